@@ -1,5 +0,0 @@
-package main
-
-type IContentHandler interface {
-	HandleContent(url string, html string) error
-}
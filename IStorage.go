@@ -1,7 +0,0 @@
-package main
-
-type IStorage interface {
-	Write(data []byte) error
-	Open(filename string) error
-	Close()
-}
@@ -0,0 +1,576 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"goCrawler/config"
+	"goCrawler/db"
+	"goCrawler/diff"
+	"goCrawler/storage"
+	"goCrawler/warc"
+)
+
+// Command is a single goCrawler subcommand, e.g. "goCrawler history <url>".
+type Command struct {
+	Name        string
+	Usage       string
+	Description string
+	Run         func(args []string, out io.Writer) error
+}
+
+var errUsage = errors.New("invalid usage")
+
+func Commands() []Command {
+	return []Command{
+		{"crawl", "crawl [options] [<url1> <url2> ...]", "Crawl the seed URLs and record new page versions", runCrawlCommand},
+		{"history", "history [options] <url>", "List the stored versions of a URL", runHistoryCommand},
+		{"show", "show [options] <url> [-version N]", "Print a stored version of a URL, the latest one by default", runShowCommand},
+		{"diff", "diff [options] <url> <v1> <v2> [-format html|text|dom|json]", "Print the differences between two stored versions of a URL", runDiffCommand},
+		{"export", "export [options] [-format json|tar] [-out file]", "Export all URLs with their versions", runExportCommand},
+		{"runs", "runs [options] [show <id> | compare <id1> <id2>]", "List past crawl runs, show one of them or compare two", runRunsCommand},
+		{"migrate-storage", "migrate-storage [options] -to <dir> [-deleteOriginals]", "Copy the stored files into a content-addressed storage", runMigrateStorageCommand},
+		{"migrate-database", "migrate-database [options] -to <file>", "Copy all keys of the remote database into a bolt database file", runMigrateDatabaseCommand},
+		{"duplicates", "duplicates [options] [-threshold 0.9]", "Group URLs whose latest versions are near-duplicates", runDuplicatesCommand},
+		{"prune", "prune [options] [-dryRun]", "Delete the versions the retention policies don't keep", runPruneCommand},
+		{"import-warc", "import-warc [options] <file.warc.gz> ...", "Build version history from the HTML responses in WARC files", runImportWarcCommand},
+	}
+}
+
+func FindCommand(name string) (Command, bool) {
+	for _, command := range Commands() {
+		if command.Name == name {
+			return command, true
+		}
+	}
+	return Command{}, false
+}
+
+// commonFlags are the flags every subcommand understands; they override values from the config file.
+type commonFlags struct {
+	configPath string
+	outputDir  string
+	database   string
+}
+
+func (c *commonFlags) register(flags *flag.FlagSet) {
+	flags.StringVar(&c.configPath, "config", "", "Path to a YAML or JSON crawl configuration file")
+	flags.StringVar(&c.outputDir, "outputDir", "", "Output directory with the crawled data (overrides storage.directory)")
+	flags.StringVar(&c.database, "database", "", "URL of the remote database (overrides database.url)")
+}
+
+func (c *commonFlags) load(flags *flag.FlagSet) (config.Config, error) {
+	cfg := config.DefaultConfig()
+	if c.configPath != "" {
+		var err error
+		if cfg, err = config.LoadConfig(c.configPath); err != nil {
+			return config.Config{}, err
+		}
+	}
+
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "outputDir":
+			cfg.Storage.Directory = c.outputDir
+		case "database":
+			cfg.Database.Type = config.DatabaseTypeRemote
+			cfg.Database.URL = c.database
+		}
+	})
+
+	// Every command opens the storage and the database, the rest of the configuration is only checked by the crawl
+	if err := cfg.ValidateStores(); err != nil {
+		return config.Config{}, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return cfg, nil
+}
+
+func newFlagSet(name string, usage string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s %s\nOptions:\n", os.Args[0], usage)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// parseInterspersed parses flags that may appear before, between or after positional arguments
+// and returns the positional ones, so "show <url> -version 2" works like "show -version 2 <url>".
+func parseInterspersed(flags *flag.FlagSet, args []string) ([]string, error) {
+	positional := make([]string, 0)
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		if flags.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+}
+
+func runCrawlCommand(args []string, out io.Writer) error {
+	flags := newFlagSet("crawl", "crawl [options] [<url1> <url2> ...]")
+	var common commonFlags
+	common.register(flags)
+	exclusionPathsArg := flags.String("exclusionPaths", "", "Comma-separated list of paths to ignore (overrides seeds[].exclusionPaths)")
+	flags.StringVar(exclusionPathsArg, "exlusionPaths", "", "Deprecated alias of -exclusionPaths")
+	maxPages := flags.Int("maxPages", 0, "Maximum number of pages crawled per seed, 0 means no limit (overrides limits.maxPages)")
+
+	urls, err := parseInterspersed(flags, args)
+	if err != nil {
+		return errUsage
+	}
+	if len(urls) == 0 && common.configPath == "" {
+		flags.Usage()
+		return errUsage
+	}
+
+	cfg, err := common.load(flags)
+	if err != nil {
+		return err
+	}
+
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "exclusionPaths", "exlusionPaths":
+			for i := range cfg.Seeds {
+				cfg.Seeds[i].ExclusionPaths = config.SplitList(*exclusionPathsArg)
+			}
+		case "maxPages":
+			cfg.Limits.MaxPages = *maxPages
+		}
+	})
+
+	if len(urls) > 0 {
+		cfg.Seeds = config.SeedsFromArgs(urls, config.SplitList(*exclusionPathsArg))
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration:\n%w", err)
+	}
+
+	return runCrawl(cfg)
+}
+
+func runHistoryCommand(args []string, out io.Writer) error {
+	flags := newFlagSet("history", "history [options] <url>")
+	var common commonFlags
+	common.register(flags)
+
+	positional, err := parseInterspersed(flags, args)
+	if err != nil || len(positional) != 1 {
+		flags.Usage()
+		return errUsage
+	}
+
+	cfg, err := common.load(flags)
+	if err != nil {
+		return err
+	}
+
+	fileStorage, err := newStorage(cfg.Storage)
+	if err != nil {
+		return err
+	}
+	database, err := newDatabase(cfg.Database)
+	if err != nil {
+		return err
+	}
+	if closer, ok := database.(io.Closer); ok {
+		defer closer.Close()
+	}
+	return diff.PrintHistory(out, database, fileStorage, positional[0])
+}
+
+func runShowCommand(args []string, out io.Writer) error {
+	flags := newFlagSet("show", "show [options] <url> [-version N]")
+	var common commonFlags
+	common.register(flags)
+	version := flags.Int("version", 0, "Version to print, 0 means the latest one")
+
+	positional, err := parseInterspersed(flags, args)
+	if err != nil || len(positional) != 1 {
+		flags.Usage()
+		return errUsage
+	}
+
+	cfg, err := common.load(flags)
+	if err != nil {
+		return err
+	}
+
+	fileStorage, err := newStorage(cfg.Storage)
+	if err != nil {
+		return err
+	}
+	database, err := newDatabase(cfg.Database)
+	if err != nil {
+		return err
+	}
+	if closer, ok := database.(io.Closer); ok {
+		defer closer.Close()
+	}
+	return diff.ShowVersion(out, database, fileStorage, positional[0], *version)
+}
+
+func runDiffCommand(args []string, out io.Writer) error {
+	flags := newFlagSet("diff", "diff [options] <url> <v1> <v2> [-format html|text|dom|json]")
+	var common commonFlags
+	common.register(flags)
+	format := flags.String("format", "html", "html: unified diff of the raw HTML, text: unified diff of the visible text, "+
+		"dom: added/removed/changed elements, json: text and DOM diff with a summary")
+
+	positional, err := parseInterspersed(flags, args)
+	if err != nil || len(positional) != 3 {
+		flags.Usage()
+		return errUsage
+	}
+
+	fromVersion, err := strconv.Atoi(positional[1])
+	if err != nil {
+		return fmt.Errorf("invalid version %q: %w", positional[1], err)
+	}
+	toVersion, err := strconv.Atoi(positional[2])
+	if err != nil {
+		return fmt.Errorf("invalid version %q: %w", positional[2], err)
+	}
+
+	cfg, err := common.load(flags)
+	if err != nil {
+		return err
+	}
+
+	database, err := newDatabase(cfg.Database)
+	if err != nil {
+		return err
+	}
+	if closer, ok := database.(io.Closer); ok {
+		defer closer.Close()
+	}
+	fileStorage, err := newStorage(cfg.Storage)
+	if err != nil {
+		return err
+	}
+	if *format == "html" {
+		return diff.DiffVersions(out, database, fileStorage, positional[0], fromVersion, toVersion)
+	}
+
+	versionDiff, err := diff.LoadVersionDiff(database, fileStorage, positional[0], fromVersion, toVersion)
+	if err != nil {
+		return err
+	}
+	return diff.PrintVersionDiff(out, versionDiff, *format)
+}
+
+func runExportCommand(args []string, out io.Writer) error {
+	flags := newFlagSet("export", "export [options] [-format json|tar] [-out file]")
+	var common commonFlags
+	common.register(flags)
+	format := flags.String("format", "json", "Export format: json (index only) or tar (gzipped tarball with index and version files)")
+	outPath := flags.String("out", "", "File to write the export to, standard output by default")
+
+	positional, err := parseInterspersed(flags, args)
+	if err != nil || len(positional) != 0 {
+		flags.Usage()
+		return errUsage
+	}
+	if *format != "json" && *format != "tar" {
+		return fmt.Errorf("unknown export format %q, expected json or tar", *format)
+	}
+
+	cfg, err := common.load(flags)
+	if err != nil {
+		return err
+	}
+
+	if *outPath != "" {
+		file, err := os.Create(*outPath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+
+	database, err := newDatabase(cfg.Database)
+	if err != nil {
+		return err
+	}
+	if closer, ok := database.(io.Closer); ok {
+		defer closer.Close()
+	}
+	if *format == "tar" {
+		fileStorage, err := newStorage(cfg.Storage)
+		if err != nil {
+			return err
+		}
+		return diff.ExportArchive(out, database, fileStorage)
+	}
+	return diff.ExportJson(out, database)
+}
+
+func runRunsCommand(args []string, out io.Writer) error {
+	flags := newFlagSet("runs", "runs [options] [show <id> | compare <id1> <id2>]")
+	var common commonFlags
+	common.register(flags)
+
+	positional, err := parseInterspersed(flags, args)
+	if err != nil {
+		return errUsage
+	}
+
+	cfg, err := common.load(flags)
+	if err != nil {
+		return err
+	}
+	database, err := newDatabase(cfg.Database)
+	if err != nil {
+		return err
+	}
+	if closer, ok := database.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	switch {
+	case len(positional) == 0 || (len(positional) == 1 && positional[0] == "list"):
+		runs, err := diff.ListCrawlRuns(database)
+		if err != nil {
+			return err
+		}
+		return diff.PrintCrawlRuns(out, runs)
+
+	case len(positional) == 2 && positional[0] == "show":
+		run, err := diff.LoadCrawlRun(database, positional[1])
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(run)
+
+	case len(positional) == 3 && positional[0] == "compare":
+		before, err := diff.LoadCrawlRun(database, positional[1])
+		if err != nil {
+			return err
+		}
+		after, err := diff.LoadCrawlRun(database, positional[2])
+		if err != nil {
+			return err
+		}
+		return diff.PrintCrawlRunComparison(out, diff.CompareCrawlRuns(before, after))
+	}
+
+	flags.Usage()
+	return errUsage
+}
+
+func runDuplicatesCommand(args []string, out io.Writer) error {
+	flags := newFlagSet("duplicates", "duplicates [options] [-threshold 0.9]")
+	var common commonFlags
+	common.register(flags)
+	threshold := flags.Float64("threshold", 0, "Minimum similarity (0-1) of near-duplicates (overrides similarity.duplicateThreshold)")
+
+	positional, err := parseInterspersed(flags, args)
+	if err != nil || len(positional) != 0 {
+		flags.Usage()
+		return errUsage
+	}
+
+	cfg, err := common.load(flags)
+	if err != nil {
+		return err
+	}
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "threshold" {
+			cfg.Similarity.DuplicateThreshold = *threshold
+		}
+	})
+	if cfg.Similarity.DuplicateThreshold <= 0 || cfg.Similarity.DuplicateThreshold > 1 {
+		return fmt.Errorf("threshold must be between 0 and 1, got %g", cfg.Similarity.DuplicateThreshold)
+	}
+
+	database, err := newDatabase(cfg.Database)
+	if err != nil {
+		return err
+	}
+	if closer, ok := database.(io.Closer); ok {
+		defer closer.Close()
+	}
+	histories, err := diff.LoadAllPageHistories(database)
+	if err != nil {
+		return err
+	}
+	diff.PrintNearDuplicates(out, diff.FindNearDuplicates(histories, cfg.Similarity.DuplicateThreshold))
+	return nil
+}
+
+func runMigrateStorageCommand(args []string, out io.Writer) error {
+	flags := newFlagSet("migrate-storage", "migrate-storage [options] -to <dir> [-deleteOriginals]")
+	var common commonFlags
+	common.register(flags)
+	target := flags.String("to", "", "Directory of the content-addressed storage to migrate to, may be the current storage directory")
+	deleteOriginals := flags.Bool("deleteOriginals", false, "Delete each file once it is migrated")
+
+	positional, err := parseInterspersed(flags, args)
+	if err != nil || len(positional) != 0 || *target == "" {
+		flags.Usage()
+		return errUsage
+	}
+
+	cfg, err := common.load(flags)
+	if err != nil {
+		return err
+	}
+	if cfg.Storage.Type != config.StorageTypeFile {
+		return fmt.Errorf("only %s storage can be migrated, storage.type is %q", config.StorageTypeFile, cfg.Storage.Type)
+	}
+
+	stats, err := storage.MigrateToContentAddressed(cfg.Storage.Directory, storage.NewContentAddressedStorage(*target), *deleteOriginals)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Migrated %d files (%d bytes) into %d new blobs (%d bytes).\n", stats.Files, stats.Bytes, stats.Blobs, stats.StoredBytes)
+	fmt.Fprintf(out, "Set storage.type to %q and storage.directory to %q to use it.\n", config.StorageTypeContentAddressed, *target)
+	return nil
+}
+
+func runMigrateDatabaseCommand(args []string, out io.Writer) error {
+	flags := newFlagSet("migrate-database", "migrate-database [options] -to <file>")
+	var common commonFlags
+	common.register(flags)
+	target := flags.String("to", "", "Path of the bolt database file to migrate to, created if missing")
+
+	positional, err := parseInterspersed(flags, args)
+	if err != nil || len(positional) != 0 || *target == "" {
+		flags.Usage()
+		return errUsage
+	}
+
+	cfg, err := common.load(flags)
+	if err != nil {
+		return err
+	}
+	if cfg.Database.Type != config.DatabaseTypeRemote {
+		return fmt.Errorf("only a %s database can be migrated, database.type is %q", config.DatabaseTypeRemote, cfg.Database.Type)
+	}
+
+	boltDatabase, err := db.NewBoltDatabase(*target)
+	if err != nil {
+		return err
+	}
+	defer boltDatabase.Close()
+
+	copied, err := db.CopyDatabase(db.NewRemoteDatabase(cfg.Database.URL), boltDatabase)
+	if err != nil {
+		return fmt.Errorf("migrated %d keys before failing: %w", copied, err)
+	}
+	fmt.Fprintf(out, "Migrated %d keys from %s.\n", copied, cfg.Database.URL)
+	fmt.Fprintf(out, "Set database.type to %q and database.path to %q to use it.\n", config.DatabaseTypeBolt, *target)
+	return nil
+}
+
+func runPruneCommand(args []string, out io.Writer) error {
+	flags := newFlagSet("prune", "prune [options] [-dryRun]")
+	var common commonFlags
+	common.register(flags)
+	dryRun := flags.Bool("dryRun", false, "Only list the versions that would be pruned")
+
+	positional, err := parseInterspersed(flags, args)
+	if err != nil || len(positional) != 0 {
+		flags.Usage()
+		return errUsage
+	}
+
+	cfg, err := common.load(flags)
+	if err != nil {
+		return err
+	}
+	fileStorage, err := newStorage(cfg.Storage)
+	if err != nil {
+		return err
+	}
+
+	database, err := newDatabase(cfg.Database)
+	if err != nil {
+		return err
+	}
+	if closer, ok := database.(io.Closer); ok {
+		defer closer.Close()
+	}
+	report, err := diff.PruneVersions(database, fileStorage, retentionRules(cfg), *dryRun, time.Now())
+	if err != nil {
+		return err
+	}
+	diff.PrintPruneReport(out, report)
+	return nil
+}
+
+func retentionRules(cfg config.Config) diff.RetentionRules {
+	rules := diff.RetentionRules{Default: diff.RetentionPolicy(cfg.Retention)}
+	for _, seed := range cfg.Seeds {
+		if seed.Retention != nil {
+			rules.Seeds = append(rules.Seeds, diff.SeedRetention{Seed: seed.URL, Policy: diff.RetentionPolicy(*seed.Retention)})
+		}
+	}
+	return rules
+}
+
+// runImportWarcCommand replays WARC files in name order, which for files written by goCrawler is the order
+// they were written in, as one crawl run. Pages missing from the files aren't marked gone.
+func runImportWarcCommand(args []string, out io.Writer) error {
+	flags := newFlagSet("import-warc", "import-warc [options] <file.warc.gz> ...")
+	var common commonFlags
+	common.register(flags)
+
+	positional, err := parseInterspersed(flags, args)
+	if err != nil || len(positional) == 0 {
+		flags.Usage()
+		return errUsage
+	}
+
+	cfg, err := common.load(flags)
+	if err != nil {
+		return err
+	}
+	fileStorage, err := newStorage(cfg.Storage)
+	if err != nil {
+		return err
+	}
+	database, err := newDatabase(cfg.Database)
+	if err != nil {
+		return err
+	}
+	if closer, ok := database.(io.Closer); ok {
+		defer closer.Close()
+	}
+	diffTracker, err := newDifferenceTracker(cfg, database, fileStorage)
+	if err != nil {
+		return err
+	}
+	diffTracker.SetDetectUnseenPages(false)
+
+	paths := append([]string{}, positional...)
+	sort.Strings(paths)
+	diffTracker.StartCrawlRun(nil, nil)
+	stats, importErr := warc.Import(paths, diffTracker)
+	run, err := diffTracker.FinishCrawlRun()
+	if importErr != nil {
+		return importErr
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Imported %d pages from %d records in %d files, skipped %d other responses.\n", stats.Pages, stats.Records, stats.Files, stats.Skipped)
+	fmt.Fprintf(out, "Crawl run %s: new %d, changed %d, unchanged %d\n", run.ID, run.NewPages, run.ChangedPages, run.UnchangedPages)
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"goCrawler/config"
+)
+
+func TestParseInterspersed(t *testing.T) {
+	flags := flag.NewFlagSet("show", flag.ContinueOnError)
+	version := flags.Int("version", 0, "")
+
+	positional, err := parseInterspersed(flags, []string{"https://www.google.com", "-version", "2"})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"https://www.google.com"}, positional)
+	assert.Equal(t, 2, *version)
+}
+
+func TestCommands_RejectS3StorageWithoutS3Section(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "crawl.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte("storage:\n  type: s3\n"), 0o644))
+
+	tests := []struct {
+		command string
+		args    []string
+	}{
+		{"history", []string{"https://www.google.com"}},
+		{"show", []string{"https://www.google.com"}},
+		{"diff", []string{"https://www.google.com", "1", "2"}},
+		{"export", nil},
+		{"prune", []string{"-dryRun"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			command, found := FindCommand(tt.command)
+			require.True(t, found)
+
+			err := command.Run(append([]string{"-config", configPath}, tt.args...), io.Discard)
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "storage.s3: required")
+		})
+	}
+}
+
+func TestNewStorage_RequiresS3Section(t *testing.T) {
+	_, err := newStorage(config.StorageConfig{Type: config.StorageTypeS3})
+	assert.ErrorContains(t, err, "storage.s3")
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"goCrawler/config"
+	"goCrawler/crawler"
+	"goCrawler/db"
+	"goCrawler/diff"
+	"goCrawler/fetch"
+	"goCrawler/notify"
+	"goCrawler/storage"
+	"goCrawler/warc"
+)
+
+func main() {
+	args := os.Args[1:]
+
+	if len(args) > 0 && (args[0] == "help" || args[0] == "-help" || args[0] == "--help" || args[0] == "-h") {
+		printUsage()
+		os.Exit(0)
+	}
+
+	// Without a known subcommand the arguments are treated as a crawl, like before subcommands existed.
+	command, found := Command{}, false
+	if len(args) > 0 {
+		command, found = FindCommand(args[0])
+	}
+	if found {
+		args = args[1:]
+	} else {
+		if len(args) == 0 {
+			printUsage()
+			os.Exit(0)
+		}
+		command, _ = FindCommand("crawl")
+	}
+
+	if err := command.Run(args, os.Stdout); err != nil {
+		if err != errUsage {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		os.Exit(1)
+	}
+}
+
+func printUsage() {
+	fmt.Printf("Usage: %s <command> [options] [arguments]\n\nCommands:\n", os.Args[0])
+	for _, command := range Commands() {
+		fmt.Printf("  %-50s %s\n", command.Usage, command.Description)
+	}
+	fmt.Printf("\nRun '%s <command> -help' for the options of a command.\n", os.Args[0])
+}
+
+func runCrawl(cfg config.Config) error {
+	database, err := newDatabase(cfg.Database)
+	if err != nil {
+		return err
+	}
+	if closer, ok := database.(io.Closer); ok {
+		defer closer.Close()
+	}
+	fileStorage, err := newStorage(cfg.Storage)
+	if err != nil {
+		return err
+	}
+	if cfg.Storage.Type != config.StorageTypeS3 {
+		removeStaleTempFiles(cfg.Storage.Directory)
+	}
+
+	seeds := make([]string, 0, len(cfg.Seeds))
+	for _, seed := range cfg.Seeds {
+		seeds = append(seeds, seed.URL)
+	}
+	configSnapshot, err := json.Marshal(cfg.Redacted())
+	if err != nil {
+		return err
+	}
+
+	deadLetterLog := newDeadLetterLog(cfg.Notifications)
+	webhookNotifier := newWebhookNotifier(cfg.Notifications, deadLetterLog)
+	digestNotifier, err := newDigestNotifier(cfg.Notifications, deadLetterLog)
+	if err != nil {
+		return err
+	}
+
+	handlers := make([]crawler.IContentHandler, 0, len(cfg.Handlers))
+	var diffTracker *diff.DifferenceTracker
+	var warcWriter *warc.WarcWriter
+	for _, handlerConfig := range cfg.Handlers {
+		switch handlerConfig.Type {
+		case config.HandlerTypeDifferenceTracker:
+			diffTracker, err = newDifferenceTracker(cfg, database, fileStorage)
+			if err != nil {
+				return err
+			}
+			// A crawl stopped by the page limit doesn't reach every page, so unseen pages aren't necessarily gone
+			diffTracker.SetDetectUnseenPages(cfg.Limits.MaxPages == 0)
+			if webhookNotifier != nil {
+				diffTracker.AddNotifier(webhookNotifier)
+			}
+			if digestNotifier != nil {
+				diffTracker.AddNotifier(digestNotifier)
+			}
+			diffTracker.StartCrawlRun(seeds, configSnapshot)
+			handlers = append(handlers, diffTracker)
+		case config.HandlerTypeWarc:
+			warcWriter, err = warc.NewWarcWriter(handlerConfig.Directory, handlerConfig.Prefix, handlerConfig.MaxFileSize)
+			if err != nil {
+				return err
+			}
+			handlers = append(handlers, warcWriter)
+		}
+	}
+	contentHandler := crawler.NewMultiContentHandler(handlers...)
+
+	limits := crawler.CrawlLimits{
+		MaxPages: cfg.Limits.MaxPages,
+		MinDelay: cfg.Limits.MinDelay,
+		MaxDelay: cfg.Limits.MaxDelay,
+	}
+
+	var wg sync.WaitGroup
+	for _, seed := range cfg.Seeds {
+		wg.Add(1)
+		go func(seed config.SeedConfig) {
+			defer wg.Done()
+
+			fmt.Printf("Crawling: %s\n", seed.URL)
+			webPage := fetch.NewWebPage(fetch.NewHTTPFetcher(cfg.Fetcher.Timeout, cfg.Fetcher.UserAgent))
+			seedCrawler := crawler.NewCrawler(webPage, contentHandler)
+			seedCrawler.SetLimits(limits)
+			seedCrawler.Crawl(seed.URL, seed.ExclusionPaths)
+		}(seed)
+	}
+
+	wg.Wait()
+	fmt.Println("Completed all crawls.")
+
+	// Finishing the run detects gone pages, which notifies, so the notifiers are closed afterwards
+	var finishErr error
+	if diffTracker != nil {
+		var run *diff.CrawlRun
+		if run, finishErr = diffTracker.FinishCrawlRun(); finishErr == nil {
+			fmt.Printf("Crawl run %s: fetched %d, new %d, changed %d, unchanged %d, failed %d, gone %d, returned %d\n",
+				run.ID, run.PagesFetched, run.NewPages, run.ChangedPages, run.UnchangedPages, run.FailedPages,
+				run.GonePages, run.ReturnedPages)
+		}
+	}
+
+	if webhookNotifier != nil {
+		webhookNotifier.Close()
+	}
+	if warcWriter != nil {
+		if err := warcWriter.Close(); err != nil {
+			log.Printf("ERROR: Finishing WARC file failed: %v", err)
+		}
+	}
+	return finishErr
+}
+
+// newDifferenceTracker creates a DifferenceTracker with the change detection settings of cfg.
+func newDifferenceTracker(cfg config.Config, database db.IDatabase, fileStorage storage.IStorage) (*diff.DifferenceTracker, error) {
+	normalizer, err := diff.NewNormalizer(diff.NormalizationOptions(cfg.Normalization))
+	if err != nil {
+		return nil, err
+	}
+
+	watchRules := make([]diff.WatchRule, 0, len(cfg.Watch))
+	for _, watch := range cfg.Watch {
+		watchRules = append(watchRules, diff.WatchRule(watch))
+	}
+	compiledWatchRules, err := diff.NewWatchRules(watchRules)
+	if err != nil {
+		return nil, err
+	}
+
+	diffTracker := diff.NewDifferenceTracker(database, fileStorage)
+	if cfg.Normalization.Enabled() {
+		diffTracker.SetNormalizer(normalizer)
+	}
+	diffTracker.SetWatchRules(compiledWatchRules)
+	diffTracker.SetSimilarityThreshold(cfg.Similarity.IgnoreAbove)
+	return diffTracker, nil
+}
+
+func newDeadLetterLog(cfg config.NotificationsConfig) *notify.DeadLetterLog {
+	if cfg.DeadLetterLog == "" {
+		return nil
+	}
+	return notify.NewDeadLetterLog(cfg.DeadLetterLog)
+}
+
+// newWebhookNotifier returns nil if no webhooks are configured.
+func newWebhookNotifier(cfg config.NotificationsConfig, deadLetterLog *notify.DeadLetterLog) *notify.WebhookNotifier {
+	if len(cfg.Webhooks) == 0 {
+		return nil
+	}
+
+	endpoints := make([]notify.WebhookEndpoint, 0, len(cfg.Webhooks))
+	for _, webhook := range cfg.Webhooks {
+		endpoints = append(endpoints, notify.WebhookEndpoint(webhook))
+	}
+	return notify.NewWebhookNotifier(endpoints, deadLetterLog)
+}
+
+// newDigestNotifier returns nil if neither email nor chat digests are configured.
+func newDigestNotifier(cfg config.NotificationsConfig, deadLetterLog *notify.DeadLetterLog) (*notify.DigestNotifier, error) {
+	sinks := make([]notify.IDigestSink, 0)
+	if cfg.Email != nil {
+		emailSink, err := notify.NewEmailSink(notify.EmailOptions(*cfg.Email))
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, emailSink)
+	}
+	for _, chat := range cfg.Chat {
+		chatSink, err := notify.NewChatSink(notify.ChatOptions(chat))
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, chatSink)
+	}
+
+	if len(sinks) == 0 {
+		return nil, nil
+	}
+	return notify.NewDigestNotifier(sinks, deadLetterLog), nil
+}
+
+// staleTempFileAge is how long a temporary file of an atomic write has to be untouched before a crawl removes it.
+// Writes take seconds at most, so older files were left by a crash rather than by a crawl still running.
+const staleTempFileAge = 24 * time.Hour
+
+// removeStaleTempFiles cleans up after crashed crawls. Only the crawl does it, other commands just read.
+func removeStaleTempFiles(directory string) {
+	removed, err := storage.RemoveStaleTempFiles(directory, staleTempFileAge)
+	if err != nil {
+		log.Printf("ERROR: Removing stale temporary files from %s failed: %v", directory, err)
+	}
+	if removed > 0 {
+		log.Printf("Removed %d stale temporary files from %s", removed, directory)
+	}
+}
+
+func newDatabase(cfg config.DatabaseConfig) (db.IDatabase, error) {
+	switch cfg.Type {
+	case config.DatabaseTypeMemory:
+		return db.NewInMemoryDatabase(), nil
+	case config.DatabaseTypeBolt:
+		return db.NewBoltDatabase(cfg.Path)
+	default:
+		return db.NewRemoteDatabase(cfg.URL), nil
+	}
+}
+
+// newStorage always decodes compressed and delta encoded files, even if compression was turned off since they were written.
+func newStorage(cfg config.StorageConfig) (storage.IStorage, error) {
+	var backend storage.IStorage
+	switch cfg.Type {
+	case config.StorageTypeContentAddressed:
+		backend = storage.NewContentAddressedStorage(cfg.Directory)
+	case config.StorageTypeS3:
+		if cfg.S3 == nil {
+			return nil, errors.New("storage.s3: required for s3 storage")
+		}
+		s3, err := storage.NewS3Storage(storage.S3Options(*cfg.S3))
+		if err != nil {
+			return nil, err
+		}
+		backend = s3
+	default:
+		backend = storage.NewFileStorage(cfg.Directory)
+	}
+
+	compression := storage.Compression(cfg.Compression)
+	if compression == "" {
+		compression = storage.CompressionNone
+	}
+	compressedStorage, err := storage.NewCompressedStorage(backend, compression)
+	if err != nil {
+		return nil, err
+	}
+	compressedStorage.SetDeltas(cfg.Deltas, cfg.KeyframeInterval)
+	return compressedStorage, nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"goCrawler/config"
+	"goCrawler/diff"
+)
+
+func TestRunCrawl_NotifiesWebhookOfGonePage(t *testing.T) {
+	var linksOldPage atomic.Bool
+	linksOldPage.Store(true)
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch {
+		case r.URL.Path == "/" && linksOldPage.Load():
+			w.Write([]byte(`<html><body><a href="/old">old</a></body></html>`))
+		case r.URL.Path == "/":
+			w.Write([]byte(`<html><body>no links</body></html>`))
+		case r.URL.Path == "/old":
+			w.Write([]byte(`<html><body>old page</body></html>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer site.Close()
+
+	var mu sync.Mutex
+	events := make([]diff.ChangeEvent, 0)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event diff.ChangeEvent
+		if json.NewDecoder(r.Body).Decode(&event) == nil {
+			mu.Lock()
+			events = append(events, event)
+			mu.Unlock()
+		}
+	}))
+	defer webhook.Close()
+
+	directory := t.TempDir()
+	cfg := config.DefaultConfig()
+	cfg.Seeds = []config.SeedConfig{{URL: site.URL}}
+	cfg.Storage.Directory = filepath.Join(directory, "pages")
+	cfg.Database = config.DatabaseConfig{Type: config.DatabaseTypeBolt, Path: filepath.Join(directory, "versions.db")}
+	cfg.Notifications.Webhooks = []config.WebhookConfig{{URL: webhook.URL}}
+	require.NoError(t, cfg.Validate())
+
+	require.NoError(t, runCrawl(cfg))
+	linksOldPage.Store(false)
+	require.NoError(t, runCrawl(cfg))
+
+	mu.Lock()
+	defer mu.Unlock()
+	gone := make([]string, 0)
+	for _, event := range events {
+		if event.Type == diff.PageGoneEvent {
+			gone = append(gone, event.URL)
+		}
+	}
+	assert.Equal(t, []string{site.URL + "/old"}, gone)
+}
@@ -0,0 +1,544 @@
+// Package config loads and validates crawl configuration files.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"goCrawler/storage"
+	"goCrawler/urlutil"
+
+	"github.com/andybalholm/cascadia"
+	"gopkg.in/yaml.v3"
+)
+
+// Config describes a whole crawl: what to crawl, how to fetch it and where to keep the results.
+// It is loaded from a YAML or JSON file (JSON is a subset of YAML, so both go through the same decoder).
+type Config struct {
+	Seeds    []SeedConfig    `yaml:"seeds"`
+	Limits   LimitsConfig    `yaml:"limits"`
+	Fetcher  FetcherConfig   `yaml:"fetcher"`
+	Storage  StorageConfig   `yaml:"storage"`
+	Database DatabaseConfig  `yaml:"database"`
+	Handlers []HandlerConfig `yaml:"handlers"`
+
+	Normalization NormalizationConfig `yaml:"normalization"`
+	Watch         []WatchConfig       `yaml:"watch"`
+	Notifications NotificationsConfig `yaml:"notifications"`
+	Similarity    SimilarityConfig    `yaml:"similarity"`
+	Retention     RetentionConfig     `yaml:"retention"`
+}
+
+// SeedConfig is a single starting URL together with the filters applied while crawling from it.
+// Retention overrides the top-level retention policy for the URLs of the seed's domain.
+type SeedConfig struct {
+	URL            string           `yaml:"url"`
+	ExclusionPaths []string         `yaml:"exclusionPaths"`
+	Retention      *RetentionConfig `yaml:"retention"`
+}
+
+// LimitsConfig bounds a single seed crawl. Zero MaxPages means no limit.
+type LimitsConfig struct {
+	MaxPages int           `yaml:"maxPages"`
+	MinDelay time.Duration `yaml:"minDelay"`
+	MaxDelay time.Duration `yaml:"maxDelay"`
+}
+
+type FetcherConfig struct {
+	Timeout   time.Duration `yaml:"timeout"`
+	UserAgent string        `yaml:"userAgent"`
+}
+
+// StorageConfig selects where page versions are kept. Compression is none, gzip or zstd; with Deltas,
+// versions are stored as the differences to their predecessor, every KeyframeInterval-th one in full.
+type StorageConfig struct {
+	Type             string `yaml:"type"`
+	Directory        string `yaml:"directory"`
+	Compression      string `yaml:"compression"`
+	Deltas           bool   `yaml:"deltas"`
+	KeyframeInterval int    `yaml:"keyframeInterval"`
+	// S3 configures the bucket of s3 storage.
+	S3 *S3Config `yaml:"s3"`
+}
+
+// S3Config locates a bucket of an S3 compatible object storage. Empty credentials are read from the
+// AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables. Files larger than PartSize are
+// uploaded in parts of that size.
+type S3Config struct {
+	Endpoint        string `yaml:"endpoint"`
+	Region          string `yaml:"region"`
+	Bucket          string `yaml:"bucket"`
+	Prefix          string `yaml:"prefix"`
+	AccessKeyID     string `yaml:"accessKeyId"`
+	SecretAccessKey string `yaml:"secretAccessKey"`
+	PartSize        int    `yaml:"partSize"`
+}
+
+// DatabaseConfig selects the version database: a remote jkdb at URL, an in-memory one,
+// or a bolt file at Path.
+type DatabaseConfig struct {
+	Type string `yaml:"type"`
+	URL  string `yaml:"url"`
+	Path string `yaml:"path"`
+}
+
+// NormalizationConfig selects what is removed from pages before they are hashed for change detection.
+type NormalizationConfig struct {
+	StripScripts       bool     `yaml:"stripScripts"`
+	StripStyles        bool     `yaml:"stripStyles"`
+	StripComments      bool     `yaml:"stripComments"`
+	CollapseWhitespace bool     `yaml:"collapseWhitespace"`
+	StripQueryStrings  bool     `yaml:"stripQueryStrings"`
+	RemoveSelectors    []string `yaml:"removeSelectors"`
+	RemovePatterns     []string `yaml:"removePatterns"`
+}
+
+// Enabled reports whether any normalization is configured; without it pages are hashed as they are.
+func (n NormalizationConfig) Enabled() bool {
+	return n.StripScripts || n.StripStyles || n.StripComments || n.CollapseWhitespace || n.StripQueryStrings ||
+		len(n.RemoveSelectors) > 0 || len(n.RemovePatterns) > 0
+}
+
+// WatchConfig limits change detection of the matching URLs to the regions selected by CSS selectors.
+// Exactly one of URL (exact match) and Pattern (regular expression) is set; the first matching rule wins.
+type WatchConfig struct {
+	URL       string   `yaml:"url"`
+	Pattern   string   `yaml:"pattern"`
+	Selectors []string `yaml:"selectors"`
+}
+
+// NotificationsConfig selects where detected changes are reported to.
+// Webhooks get every change as it is found, Email and Chat get one digest per seed when a crawl run finishes.
+// Deliveries that fail even after retrying are appended to DeadLetterLog, if set.
+type NotificationsConfig struct {
+	Webhooks      []WebhookConfig `yaml:"webhooks"`
+	Email         *EmailConfig    `yaml:"email"`
+	Chat          []ChatConfig    `yaml:"chat"`
+	DeadLetterLog string          `yaml:"deadLetterLog"`
+}
+
+// WebhookConfig is an endpoint change events are POSTed to as JSON.
+// With a Secret, requests are signed with HMAC-SHA256 in the X-GoCrawler-Signature header.
+type WebhookConfig struct {
+	URL        string        `yaml:"url"`
+	Secret     string        `yaml:"secret"`
+	MaxRetries int           `yaml:"maxRetries"`
+	RetryDelay time.Duration `yaml:"retryDelay"`
+	Timeout    time.Duration `yaml:"timeout"`
+}
+
+// EmailConfig sends digests over SMTP. SeedRecipients maps seed URLs to their own recipient lists,
+// digests of other seeds go to To. Templates are text/template, empty ones use the defaults.
+type EmailConfig struct {
+	Host            string              `yaml:"host"`
+	Port            int                 `yaml:"port"`
+	Username        string              `yaml:"username"`
+	Password        string              `yaml:"password"`
+	From            string              `yaml:"from"`
+	To              []string            `yaml:"to"`
+	SeedRecipients  map[string][]string `yaml:"seedRecipients"`
+	SubjectTemplate string              `yaml:"subjectTemplate"`
+	BodyTemplate    string              `yaml:"bodyTemplate"`
+}
+
+// ChatConfig posts digests to a Slack or Mattermost incoming webhook, optionally only those of some Seeds.
+type ChatConfig struct {
+	URL      string   `yaml:"url"`
+	Template string   `yaml:"template"`
+	Seeds    []string `yaml:"seeds"`
+}
+
+// SimilarityConfig sets the SimHash similarity thresholds, from 0 (unrelated) to 1 (identical text).
+// Changes at least IgnoreAbove similar to the latest version are ignored, 0 records every change.
+// DuplicateThreshold is the default similarity of near-duplicates reported by the duplicates command.
+type SimilarityConfig struct {
+	IgnoreAbove        float64 `yaml:"ignoreAbove"`
+	DuplicateThreshold float64 `yaml:"duplicateThreshold"`
+}
+
+// RetentionConfig selects the versions kept by the prune command; a version is kept if any rule keeps it,
+// and the latest version is always kept. A policy without rules keeps everything.
+type RetentionConfig struct {
+	KeepLast   int           `yaml:"keepLast"`
+	KeepWithin time.Duration `yaml:"keepWithin"`
+	KeepDaily  int           `yaml:"keepDaily"`
+	KeepWeekly int           `yaml:"keepWeekly"`
+}
+
+// HandlerConfig selects a content handler. Directory, Prefix and MaxFileSize configure the warc handler:
+// where WARC files are written, how their names start and the size in bytes after which a new one is started.
+type HandlerConfig struct {
+	Type        string `yaml:"type"`
+	Directory   string `yaml:"directory"`
+	Prefix      string `yaml:"prefix"`
+	MaxFileSize int64  `yaml:"maxFileSize"`
+}
+
+const (
+	StorageTypeFile = "file"
+	// StorageTypeContentAddressed stores each distinct content once, see storage.ContentAddressedStorage.
+	StorageTypeContentAddressed = "contentAddressed"
+	// StorageTypeS3 stores files as objects of an S3 bucket, see storage.S3Storage.
+	StorageTypeS3 = "s3"
+
+	DatabaseTypeRemote = "remote"
+	DatabaseTypeMemory = "memory"
+	// DatabaseTypeBolt keeps the database in a local file, see db.BoltDatabase.
+	DatabaseTypeBolt = "bolt"
+
+	HandlerTypeDifferenceTracker = "differenceTracker"
+	// HandlerTypeWarc archives every fetch in WARC files, see warc.WarcWriter.
+	HandlerTypeWarc = "warc"
+)
+
+// DefaultConfig returns the configuration the crawler used before config files existed.
+func DefaultConfig() Config {
+	return Config{
+		Limits: LimitsConfig{
+			MinDelay: 10 * time.Millisecond,
+			MaxDelay: 1010 * time.Millisecond,
+		},
+		Fetcher: FetcherConfig{
+			Timeout:   30 * time.Second,
+			UserAgent: "goCrawler",
+		},
+		Storage: StorageConfig{
+			Type:      StorageTypeFile,
+			Directory: ".",
+		},
+		Database: DatabaseConfig{
+			Type: DatabaseTypeRemote,
+			URL:  "http://localhost:8080",
+		},
+		Handlers: []HandlerConfig{{Type: HandlerTypeDifferenceTracker}},
+		Similarity: SimilarityConfig{
+			DuplicateThreshold: 0.9,
+		},
+	}
+}
+
+// LoadConfig reads the configuration file at path on top of DefaultConfig.
+// Unknown fields are rejected so typos don't silently fall back to defaults.
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("reading config file %s: %w", path, err)
+	}
+
+	return ParseConfig(data)
+}
+
+func ParseConfig(data []byte) (Config, error) {
+	config := DefaultConfig()
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil {
+		return Config{}, fmt.Errorf("parsing config: %w", err)
+	}
+
+	return config, nil
+}
+
+// Validate checks the whole configuration and reports every problem found, not just the first one.
+func (c *Config) Validate() error {
+	var errs []error
+
+	if len(c.Seeds) == 0 {
+		errs = append(errs, errors.New("seeds: at least one seed URL is required"))
+	}
+	for i, seed := range c.Seeds {
+		if err := validateSeedURL(seed.URL); err != nil {
+			errs = append(errs, fmt.Errorf("seeds[%d].url: %w", i, err))
+		}
+	}
+
+	if c.Limits.MaxPages < 0 {
+		errs = append(errs, fmt.Errorf("limits.maxPages: must not be negative, got %d", c.Limits.MaxPages))
+	}
+	if c.Limits.MinDelay < 0 {
+		errs = append(errs, fmt.Errorf("limits.minDelay: must not be negative, got %s", c.Limits.MinDelay))
+	}
+	if c.Limits.MaxDelay < c.Limits.MinDelay {
+		errs = append(errs, fmt.Errorf("limits.maxDelay: must not be lower than minDelay (%s), got %s", c.Limits.MinDelay, c.Limits.MaxDelay))
+	}
+
+	if c.Fetcher.Timeout < 0 {
+		errs = append(errs, fmt.Errorf("fetcher.timeout: must not be negative, got %s", c.Fetcher.Timeout))
+	}
+
+	errs = append(errs, c.validateStores()...)
+
+	if len(c.Handlers) == 0 {
+		errs = append(errs, errors.New("handlers: at least one content handler is required"))
+	}
+	seenHandlers := make(map[string]bool)
+	for i, handler := range c.Handlers {
+		if handler.Type != HandlerTypeDifferenceTracker && handler.Type != HandlerTypeWarc {
+			errs = append(errs, fmt.Errorf("handlers[%d].type: unknown handler %q, expected %q or %q", i, handler.Type, HandlerTypeDifferenceTracker, HandlerTypeWarc))
+		} else if seenHandlers[handler.Type] {
+			errs = append(errs, fmt.Errorf("handlers[%d].type: handler %q is configured more than once", i, handler.Type))
+		}
+		seenHandlers[handler.Type] = true
+
+		if handler.Type == HandlerTypeWarc && handler.Directory == "" {
+			errs = append(errs, fmt.Errorf("handlers[%d].directory: required for the %q handler", i, HandlerTypeWarc))
+		}
+		if handler.MaxFileSize < 0 {
+			errs = append(errs, fmt.Errorf("handlers[%d].maxFileSize: must not be negative, got %d", i, handler.MaxFileSize))
+		}
+	}
+
+	for i, selector := range c.Normalization.RemoveSelectors {
+		if _, err := cascadia.Compile(selector); err != nil {
+			errs = append(errs, fmt.Errorf("normalization.removeSelectors[%d]: invalid CSS selector %q: %w", i, selector, err))
+		}
+	}
+	for i, pattern := range c.Normalization.RemovePatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			errs = append(errs, fmt.Errorf("normalization.removePatterns[%d]: %w", i, err))
+		}
+	}
+
+	for i, watch := range c.Watch {
+		if (watch.URL == "") == (watch.Pattern == "") {
+			errs = append(errs, fmt.Errorf("watch[%d]: exactly one of url and pattern is required", i))
+		}
+		if watch.Pattern != "" {
+			if _, err := regexp.Compile(watch.Pattern); err != nil {
+				errs = append(errs, fmt.Errorf("watch[%d].pattern: %w", i, err))
+			}
+		}
+		if len(watch.Selectors) == 0 {
+			errs = append(errs, fmt.Errorf("watch[%d].selectors: at least one CSS selector is required", i))
+		}
+		for j, selector := range watch.Selectors {
+			if _, err := cascadia.Compile(selector); err != nil {
+				errs = append(errs, fmt.Errorf("watch[%d].selectors[%d]: invalid CSS selector %q: %w", i, j, selector, err))
+			}
+		}
+	}
+
+	for i, webhook := range c.Notifications.Webhooks {
+		if err := validateSeedURL(webhook.URL); err != nil {
+			errs = append(errs, fmt.Errorf("notifications.webhooks[%d].url: %w", i, err))
+		}
+		if webhook.MaxRetries < 0 {
+			errs = append(errs, fmt.Errorf("notifications.webhooks[%d].maxRetries: must not be negative, got %d", i, webhook.MaxRetries))
+		}
+		if webhook.RetryDelay < 0 {
+			errs = append(errs, fmt.Errorf("notifications.webhooks[%d].retryDelay: must not be negative, got %s", i, webhook.RetryDelay))
+		}
+		if webhook.Timeout < 0 {
+			errs = append(errs, fmt.Errorf("notifications.webhooks[%d].timeout: must not be negative, got %s", i, webhook.Timeout))
+		}
+	}
+
+	if email := c.Notifications.Email; email != nil {
+		if email.Host == "" {
+			errs = append(errs, errors.New("notifications.email.host: required"))
+		}
+		if email.Port < 0 || email.Port > 65535 {
+			errs = append(errs, fmt.Errorf("notifications.email.port: must be a valid port, got %d", email.Port))
+		}
+		if _, err := mail.ParseAddress(email.From); err != nil {
+			errs = append(errs, fmt.Errorf("notifications.email.from: %w", err))
+		}
+		if len(email.To) == 0 && len(email.SeedRecipients) == 0 {
+			errs = append(errs, errors.New("notifications.email.to: at least one recipient is required"))
+		}
+		for i, recipient := range email.To {
+			if _, err := mail.ParseAddress(recipient); err != nil {
+				errs = append(errs, fmt.Errorf("notifications.email.to[%d]: %w", i, err))
+			}
+		}
+		for seed, recipients := range email.SeedRecipients {
+			for i, recipient := range recipients {
+				if _, err := mail.ParseAddress(recipient); err != nil {
+					errs = append(errs, fmt.Errorf("notifications.email.seedRecipients[%s][%d]: %w", seed, i, err))
+				}
+			}
+		}
+		if _, err := template.New("").Parse(email.SubjectTemplate); err != nil {
+			errs = append(errs, fmt.Errorf("notifications.email.subjectTemplate: %w", err))
+		}
+		if _, err := template.New("").Parse(email.BodyTemplate); err != nil {
+			errs = append(errs, fmt.Errorf("notifications.email.bodyTemplate: %w", err))
+		}
+	}
+	for i, chat := range c.Notifications.Chat {
+		if err := validateSeedURL(chat.URL); err != nil {
+			errs = append(errs, fmt.Errorf("notifications.chat[%d].url: %w", i, err))
+		}
+		if _, err := template.New("").Parse(chat.Template); err != nil {
+			errs = append(errs, fmt.Errorf("notifications.chat[%d].template: %w", i, err))
+		}
+	}
+
+	if c.Similarity.IgnoreAbove < 0 || c.Similarity.IgnoreAbove > 1 {
+		errs = append(errs, fmt.Errorf("similarity.ignoreAbove: must be between 0 and 1, got %g", c.Similarity.IgnoreAbove))
+	}
+	if c.Similarity.DuplicateThreshold <= 0 || c.Similarity.DuplicateThreshold > 1 {
+		errs = append(errs, fmt.Errorf("similarity.duplicateThreshold: must be above 0 and at most 1, got %g", c.Similarity.DuplicateThreshold))
+	}
+
+	errs = append(errs, c.Retention.validate("retention")...)
+	for i, seed := range c.Seeds {
+		if seed.Retention != nil {
+			errs = append(errs, seed.Retention.validate(fmt.Sprintf("seeds[%d].retention", i))...)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// ValidateStores checks only the storage and database sections, which every command needs, not just the crawl.
+func (c *Config) ValidateStores() error {
+	return errors.Join(c.validateStores()...)
+}
+
+func (c *Config) validateStores() []error {
+	var errs []error
+
+	switch c.Storage.Type {
+	case StorageTypeFile, StorageTypeContentAddressed:
+		if c.Storage.Directory == "" {
+			errs = append(errs, fmt.Errorf("storage.directory: required for %s storage", c.Storage.Type))
+		}
+	case StorageTypeS3:
+		errs = append(errs, c.Storage.S3.validate()...)
+	default:
+		errs = append(errs, fmt.Errorf("storage.type: unknown storage %q, expected %q, %q or %q",
+			c.Storage.Type, StorageTypeFile, StorageTypeContentAddressed, StorageTypeS3))
+	}
+	switch c.Storage.Compression {
+	case "", "none", "gzip", "zstd":
+	default:
+		errs = append(errs, fmt.Errorf("storage.compression: unknown compression %q, expected none, gzip or zstd", c.Storage.Compression))
+	}
+	if c.Storage.KeyframeInterval < 0 {
+		errs = append(errs, fmt.Errorf("storage.keyframeInterval: must not be negative, got %d", c.Storage.KeyframeInterval))
+	}
+
+	switch c.Database.Type {
+	case DatabaseTypeRemote:
+		if err := validateSeedURL(c.Database.URL); err != nil {
+			errs = append(errs, fmt.Errorf("database.url: %w", err))
+		}
+	case DatabaseTypeBolt:
+		if c.Database.Path == "" {
+			errs = append(errs, errors.New("database.path: required for bolt database"))
+		}
+	case DatabaseTypeMemory:
+	default:
+		errs = append(errs, fmt.Errorf("database.type: unknown database %q, expected %q, %q or %q",
+			c.Database.Type, DatabaseTypeRemote, DatabaseTypeMemory, DatabaseTypeBolt))
+	}
+	return errs
+}
+
+func (r RetentionConfig) validate(field string) []error {
+	var errs []error
+	if r.KeepLast < 0 {
+		errs = append(errs, fmt.Errorf("%s.keepLast: must not be negative, got %d", field, r.KeepLast))
+	}
+	if r.KeepWithin < 0 {
+		errs = append(errs, fmt.Errorf("%s.keepWithin: must not be negative, got %s", field, r.KeepWithin))
+	}
+	if r.KeepDaily < 0 {
+		errs = append(errs, fmt.Errorf("%s.keepDaily: must not be negative, got %d", field, r.KeepDaily))
+	}
+	if r.KeepWeekly < 0 {
+		errs = append(errs, fmt.Errorf("%s.keepWeekly: must not be negative, got %d", field, r.KeepWeekly))
+	}
+	return errs
+}
+
+func (s *S3Config) validate() []error {
+	if s == nil {
+		return []error{errors.New("storage.s3: required for s3 storage")}
+	}
+	var errs []error
+	for _, err := range storage.S3Options(*s).Validate() {
+		errs = append(errs, fmt.Errorf("storage.s3.%w", err))
+	}
+	return errs
+}
+
+// Redacted returns a copy without secrets, safe to store alongside crawl runs.
+func (c Config) Redacted() Config {
+	redacted := c
+	redacted.Notifications.Webhooks = make([]WebhookConfig, len(c.Notifications.Webhooks))
+	for i, webhook := range c.Notifications.Webhooks {
+		if webhook.Secret != "" {
+			webhook.Secret = "REDACTED"
+		}
+		webhook.URL = urlutil.RedactURL(webhook.URL, false)
+		redacted.Notifications.Webhooks[i] = webhook
+	}
+	// The token of Slack and Mattermost incoming webhooks is part of the path
+	redacted.Notifications.Chat = make([]ChatConfig, len(c.Notifications.Chat))
+	for i, chat := range c.Notifications.Chat {
+		chat.URL = urlutil.RedactURL(chat.URL, true)
+		redacted.Notifications.Chat[i] = chat
+	}
+	if c.Notifications.Email != nil && c.Notifications.Email.Password != "" {
+		email := *c.Notifications.Email
+		email.Password = "REDACTED"
+		redacted.Notifications.Email = &email
+	}
+	if c.Storage.S3 != nil && c.Storage.S3.SecretAccessKey != "" {
+		s3 := *c.Storage.S3
+		s3.SecretAccessKey = "REDACTED"
+		redacted.Storage.S3 = &s3
+	}
+	return redacted
+}
+
+func validateSeedURL(rawUrl string) error {
+	if rawUrl == "" {
+		return errors.New("must not be empty")
+	}
+
+	parsedUrl, err := url.Parse(rawUrl)
+	if err != nil {
+		return err
+	}
+
+	if parsedUrl.Scheme != "http" && parsedUrl.Scheme != "https" {
+		return fmt.Errorf("%q must be an absolute http or https URL", rawUrl)
+	}
+	if parsedUrl.Host == "" {
+		return fmt.Errorf("%q has no host", rawUrl)
+	}
+
+	return nil
+}
+
+// SeedsFromArgs builds seeds from positional command line URLs sharing the same exclusion paths.
+func SeedsFromArgs(urls []string, exclusionPaths []string) []SeedConfig {
+	seeds := make([]SeedConfig, 0, len(urls))
+	for _, u := range urls {
+		seeds = append(seeds, SeedConfig{URL: u, ExclusionPaths: exclusionPaths})
+	}
+	return seeds
+}
+
+// SplitList splits a comma-separated flag value, dropping empty entries.
+func SplitList(value string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,201 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseConfig_YamlOverridesDefaults(t *testing.T) {
+	config, err := ParseConfig([]byte(`
+seeds:
+  - url: https://example.com
+    exclusionPaths: [admin, private]
+limits:
+  maxPages: 10
+  maxDelay: 2s
+storage:
+  directory: /tmp/out
+`))
+	require.NoError(t, err)
+
+	assert.Equal(t, []SeedConfig{{URL: "https://example.com", ExclusionPaths: []string{"admin", "private"}}}, config.Seeds)
+	assert.Equal(t, 10, config.Limits.MaxPages)
+	assert.Equal(t, 10*time.Millisecond, config.Limits.MinDelay)
+	assert.Equal(t, 2*time.Second, config.Limits.MaxDelay)
+	assert.Equal(t, "/tmp/out", config.Storage.Directory)
+	assert.Equal(t, DatabaseTypeRemote, config.Database.Type)
+	assert.NoError(t, config.Validate())
+}
+
+func TestParseConfig_Json(t *testing.T) {
+	config, err := ParseConfig([]byte(`{"seeds": [{"url": "https://example.com"}], "database": {"type": "memory"}}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, "https://example.com", config.Seeds[0].URL)
+	assert.Equal(t, DatabaseTypeMemory, config.Database.Type)
+	assert.NoError(t, config.Validate())
+}
+
+func TestParseConfig_RejectsUnknownFields(t *testing.T) {
+	_, err := ParseConfig([]byte("seeds:\n  - url: https://example.com\n    exlusionPaths: [admin]\n"))
+	assert.ErrorContains(t, err, "exlusionPaths")
+}
+
+func TestLoadConfig_MissingFile(t *testing.T) {
+	_, err := LoadConfig(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestValidateConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(c *Config)
+		expected string
+	}{
+		{"No seeds", func(c *Config) { c.Seeds = nil }, "seeds: at least one seed URL is required"},
+		{"Relative seed", func(c *Config) { c.Seeds[0].URL = "/page" }, "seeds[0].url"},
+		{"Non http seed", func(c *Config) { c.Seeds[0].URL = "ftp://example.com" }, "seeds[0].url"},
+		{"Negative max pages", func(c *Config) { c.Limits.MaxPages = -1 }, "limits.maxPages"},
+		{"Max delay below min delay", func(c *Config) { c.Limits.MaxDelay = time.Millisecond }, "limits.maxDelay"},
+		{"Unknown storage", func(c *Config) { c.Storage.Type = "tape" }, "storage.type"},
+		{"Unknown compression", func(c *Config) { c.Storage.Compression = "lzma" }, "storage.compression"},
+		{"Negative keyframe interval", func(c *Config) { c.Storage.KeyframeInterval = -1 }, "storage.keyframeInterval"},
+		{"Empty storage directory", func(c *Config) { c.Storage.Directory = "" }, "storage.directory"},
+		{"S3 storage without s3", func(c *Config) { c.Storage.Type = StorageTypeS3 }, "storage.s3: required"},
+		{"S3 storage without bucket", func(c *Config) {
+			c.Storage = StorageConfig{Type: StorageTypeS3, S3: &S3Config{Endpoint: "http://localhost:9000"}}
+		}, "storage.s3.bucket"},
+		{"S3 storage with relative endpoint", func(c *Config) {
+			c.Storage = StorageConfig{Type: StorageTypeS3, S3: &S3Config{Endpoint: "localhost:9000", Bucket: "pages"}}
+		}, "storage.s3.endpoint"},
+		{"S3 part size below minimum", func(c *Config) {
+			c.Storage = StorageConfig{Type: StorageTypeS3, S3: &S3Config{Endpoint: "http://localhost:9000", Bucket: "pages", PartSize: 1024}}
+		}, "storage.s3.partSize"},
+		{"Unknown database", func(c *Config) { c.Database.Type = "mongo" }, "database.type"},
+		{"Bolt database without path", func(c *Config) { c.Database.Type = DatabaseTypeBolt }, "database.path"},
+		{"Remote database without url", func(c *Config) { c.Database.URL = "" }, "database.url"},
+		{"No handlers", func(c *Config) { c.Handlers = nil }, "handlers: at least one"},
+		{"Unknown handler", func(c *Config) { c.Handlers[0].Type = "printer" }, "handlers[0].type"},
+		{"WARC handler without directory", func(c *Config) { c.Handlers = append(c.Handlers, HandlerConfig{Type: HandlerTypeWarc}) }, "handlers[1].directory"},
+		{"Negative WARC file size", func(c *Config) {
+			c.Handlers = append(c.Handlers, HandlerConfig{Type: HandlerTypeWarc, Directory: "warc", MaxFileSize: -1})
+		}, "handlers[1].maxFileSize"},
+		{"Invalid remove selector", func(c *Config) { c.Normalization.RemoveSelectors = []string{"div["} }, "normalization.removeSelectors[0]"},
+		{"Invalid remove pattern", func(c *Config) { c.Normalization.RemovePatterns = []string{"csrf=(["} }, "normalization.removePatterns[0]"},
+		{"Watch without url and pattern", func(c *Config) { c.Watch = []WatchConfig{{Selectors: []string{"table"}}} }, "watch[0]: exactly one of url and pattern"},
+		{"Watch with url and pattern", func(c *Config) {
+			c.Watch = []WatchConfig{{URL: "https://example.com", Pattern: ".*", Selectors: []string{"table"}}}
+		}, "watch[0]: exactly one of url and pattern"},
+		{"Watch without selectors", func(c *Config) { c.Watch = []WatchConfig{{Pattern: ".*"}} }, "watch[0].selectors"},
+		{"Watch with invalid selector", func(c *Config) { c.Watch = []WatchConfig{{Pattern: ".*", Selectors: []string{"div["}}} }, "watch[0].selectors[0]"},
+		{"Relative webhook url", func(c *Config) { c.Notifications.Webhooks = []WebhookConfig{{URL: "/hook"}} }, "notifications.webhooks[0].url"},
+		{"Negative webhook retries", func(c *Config) {
+			c.Notifications.Webhooks = []WebhookConfig{{URL: "https://example.com/hook", MaxRetries: -1}}
+		}, "notifications.webhooks[0].maxRetries"},
+		{"Email without host", func(c *Config) {
+			c.Notifications.Email = &EmailConfig{From: "crawler@example.com", To: []string{"team@example.com"}}
+		}, "notifications.email.host"},
+		{"Email without recipients", func(c *Config) {
+			c.Notifications.Email = &EmailConfig{Host: "localhost", From: "crawler@example.com"}
+		}, "notifications.email.to"},
+		{"Email with invalid template", func(c *Config) {
+			c.Notifications.Email = &EmailConfig{Host: "localhost", From: "crawler@example.com", To: []string{"team@example.com"}, BodyTemplate: "{{.Run"}
+		}, "notifications.email.bodyTemplate"},
+		{"Chat with invalid url", func(c *Config) { c.Notifications.Chat = []ChatConfig{{URL: "hooks"}} }, "notifications.chat[0].url"},
+		{"Similarity threshold above one", func(c *Config) { c.Similarity.IgnoreAbove = 1.5 }, "similarity.ignoreAbove"},
+		{"Zero duplicate threshold", func(c *Config) { c.Similarity.DuplicateThreshold = 0 }, "similarity.duplicateThreshold"},
+		{"Negative keep last", func(c *Config) { c.Retention.KeepLast = -1 }, "retention.keepLast"},
+		{"Negative seed keep within", func(c *Config) { c.Seeds[0].Retention = &RetentionConfig{KeepWithin: -time.Hour} }, "seeds[0].retention.keepWithin"},
+		{"Duplicated handler", func(c *Config) { c.Handlers = append(c.Handlers, c.Handlers[0]) }, "handlers[1].type: handler \"differenceTracker\" is configured more than once"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.Seeds = []SeedConfig{{URL: "https://example.com"}}
+			tc.modify(&config)
+
+			assert.ErrorContains(t, config.Validate(), tc.expected)
+		})
+	}
+}
+
+func TestValidateConfig_ReportsAllErrors(t *testing.T) {
+	config := DefaultConfig()
+	config.Storage.Type = "tape"
+	config.Database.Type = "mongo"
+
+	err := config.Validate()
+	assert.ErrorContains(t, err, "seeds")
+	assert.ErrorContains(t, err, "storage.type")
+	assert.ErrorContains(t, err, "database.type")
+}
+
+func TestValidateStores_ChecksOnlyStorageAndDatabase(t *testing.T) {
+	config := DefaultConfig()
+	assert.NoError(t, config.ValidateStores(), "seeds aren't needed to read the stored data")
+
+	config.Storage.Type = StorageTypeS3
+	config.Database.Type = "mongo"
+	err := config.ValidateStores()
+	assert.ErrorContains(t, err, "storage.s3: required")
+	assert.ErrorContains(t, err, "database.type")
+}
+
+func TestRedacted_HidesWebhookSecrets(t *testing.T) {
+	config := DefaultConfig()
+	config.Notifications.Webhooks = []WebhookConfig{{URL: "https://example.com/hook", Secret: "s3cret"}}
+
+	redacted := config.Redacted()
+
+	assert.Equal(t, "REDACTED", redacted.Notifications.Webhooks[0].Secret)
+	assert.Equal(t, "s3cret", config.Notifications.Webhooks[0].Secret)
+}
+
+func TestRedacted_HidesCredentialsInUrls(t *testing.T) {
+	config := DefaultConfig()
+	config.Notifications.Webhooks = []WebhookConfig{{URL: "https://example.com/hook?token=abc"}}
+	config.Notifications.Chat = []ChatConfig{{URL: "https://hooks.slack.com/services/T000/B000/XXXX"}}
+
+	redacted := config.Redacted()
+
+	assert.Equal(t, "https://example.com/hook?token=REDACTED", redacted.Notifications.Webhooks[0].URL)
+	assert.Equal(t, "https://hooks.slack.com/REDACTED", redacted.Notifications.Chat[0].URL)
+	assert.Equal(t, "https://hooks.slack.com/services/T000/B000/XXXX", config.Notifications.Chat[0].URL)
+}
+
+func TestRedacted_HidesEmailPassword(t *testing.T) {
+	config := DefaultConfig()
+	config.Notifications.Email = &EmailConfig{Host: "localhost", Password: "s3cret"}
+
+	redacted := config.Redacted()
+
+	assert.Equal(t, "REDACTED", redacted.Notifications.Email.Password)
+	assert.Equal(t, "s3cret", config.Notifications.Email.Password)
+}
+
+func TestRedacted_HidesS3SecretAccessKey(t *testing.T) {
+	config := DefaultConfig()
+	config.Storage = StorageConfig{Type: StorageTypeS3, S3: &S3Config{Bucket: "pages", SecretAccessKey: "s3cret"}}
+
+	redacted := config.Redacted()
+
+	assert.Equal(t, "REDACTED", redacted.Storage.S3.SecretAccessKey)
+	assert.Equal(t, "s3cret", config.Storage.S3.SecretAccessKey)
+}
+
+func TestSplitList(t *testing.T) {
+	assert.Equal(t, []string{}, SplitList(""))
+	assert.Equal(t, []string{"admin", "private"}, SplitList("admin, private,"))
+}
+
+func TestExampleConfigIsValid(t *testing.T) {
+	config, err := LoadConfig("../crawl.example.yaml")
+	require.NoError(t, err)
+	assert.NoError(t, config.Validate())
+}
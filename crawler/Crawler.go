@@ -0,0 +1,132 @@
+// Package crawler walks a website from a seed URL and passes every page it finds to an IContentHandler.
+package crawler
+
+import (
+	"fmt"
+	"time"
+
+	"goCrawler/fetch"
+	"goCrawler/filter"
+	"goCrawler/urlutil"
+)
+
+// CrawlLimits bounds a crawl. Zero MaxPages means no limit.
+type CrawlLimits struct {
+	MaxPages int
+	MinDelay time.Duration
+	MaxDelay time.Duration
+}
+
+var DefaultCrawlLimits = CrawlLimits{
+	MinDelay: 10 * time.Millisecond,
+	MaxDelay: 1010 * time.Millisecond,
+}
+
+type Crawler struct {
+	webPage        fetch.IWebPage
+	contentHandler IContentHandler
+	crawledLinks   map[string]bool
+	linksToCrawl   []string
+	linkFilters    []filter.LinkFilter
+	domain         string
+	limits         CrawlLimits
+}
+
+func NewCrawler(webPage fetch.IWebPage, contentHandler IContentHandler) *Crawler {
+
+	return &Crawler{
+		webPage:        webPage,
+		contentHandler: contentHandler,
+		crawledLinks:   make(map[string]bool),
+		linksToCrawl:   make([]string, 0),
+		linkFilters:    make([]filter.LinkFilter, 0),
+		limits:         DefaultCrawlLimits,
+	}
+}
+
+func (c *Crawler) SetLimits(limits CrawlLimits) {
+	c.limits = limits
+}
+
+func (c *Crawler) Crawl(url string, ignorePaths []string) {
+	c.domain = url
+
+	c.linkFilters = append(c.linkFilters, filter.NewPathExclusionFilter(ignorePaths))
+	c.linkFilters = append(c.linkFilters, filter.NewDomainRestrictedLinkFilter(url))
+	c.linkFilters = append(c.linkFilters, &filter.LinkToFileFilter{})
+
+	c.linksToCrawl = append(c.linksToCrawl, url)
+
+	c.crawlImpl(c.domain)
+}
+
+func (c *Crawler) crawlImpl(url string) {
+	fmt.Printf("Crawling: %s, Links to crawl: %d, Crawled: %d\n", url, len(c.linksToCrawl), len(c.crawledLinks))
+
+	c.linksToCrawl = c.linksToCrawl[:len(c.linksToCrawl)-1]
+	c.crawledLinks[url] = true
+
+	htmlContent := c.webPage.Load(url)
+
+	handleWith(c.contentHandler, url, htmlContent, c.webPage.LastResult())
+
+	links := c.webPage.GetAllLinks()
+
+	c.processLinks(links)
+
+	if c.limits.MaxPages > 0 && len(c.crawledLinks) >= c.limits.MaxPages {
+		fmt.Printf("Reached the limit of %d pages, stopping crawl of %s\n", c.limits.MaxPages, c.domain)
+		return
+	}
+
+	if len(c.linksToCrawl) > 0 {
+		// TODO: Move this to separate class so I can mock it in tests
+		time.Sleep(c.nextDelay())
+		c.crawlImpl(c.linksToCrawl[len(c.linksToCrawl)-1])
+	}
+}
+
+func (c *Crawler) nextDelay() time.Duration {
+	spread := c.limits.MaxDelay - c.limits.MinDelay
+	if spread <= 0 {
+		return c.limits.MinDelay
+	}
+	return c.limits.MinDelay + time.Duration(time.Now().UnixNano()%int64(spread))
+}
+
+func (c *Crawler) processLinks(links map[string]string) {
+	for link := range links {
+		shouldCrawl := true
+		for _, linkFilter := range c.linkFilters {
+
+			if linkFilter.FilterLink(link) {
+				// fmt.Printf("Link %s filtered out by %T\n", link, linkFilter)
+
+				shouldCrawl = false
+				break
+			}
+		}
+
+		if !shouldCrawl {
+			continue
+		}
+
+		fixedLink := urlutil.FixupLink(c.domain, link)
+
+		if _, ok := c.crawledLinks[fixedLink]; !ok {
+			found := false
+			for _, l := range c.linksToCrawl {
+				if l == fixedLink {
+					found = true
+					break
+				}
+			}
+
+			if !found {
+				fmt.Printf("Adding link to crawl: %s\n", fixedLink)
+				c.linksToCrawl = append(c.linksToCrawl, fixedLink)
+			}
+		}
+
+	}
+}
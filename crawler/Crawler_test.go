@@ -1,9 +1,11 @@
-package main
+package crawler
 
 import (
 	"testing"
 
 	"github.com/stretchr/testify/mock"
+
+	"goCrawler/fetch"
 )
 
 type MockIWebPage struct {
@@ -20,6 +22,12 @@ func (m *MockIWebPage) GetAllLinks() map[string]string {
 	return args.Get(0).(map[string]string)
 }
 
+func (m *MockIWebPage) LastResult() *fetch.FetchResult {
+	return nil
+}
+
+var defaultHtmlContent = "<html><body><a href=\"https://www.google.com\">Google</a></body></html>"
+
 type MockIContentHandler struct {
 	mock.Mock
 }
@@ -0,0 +1,13 @@
+package crawler
+
+import "goCrawler/fetch"
+
+type IContentHandler interface {
+	HandleContent(url string, html string) error
+}
+
+// IPageHandler is implemented by content handlers that also want the fetch metadata
+// (status code, content type, timing) of a page. The crawler prefers it over HandleContent when available.
+type IPageHandler interface {
+	HandlePage(page *fetch.FetchResult) error
+}
@@ -0,0 +1,44 @@
+package crawler
+
+import (
+	"errors"
+
+	"goCrawler/fetch"
+)
+
+// MultiContentHandler passes every page to all of its handlers, so one crawl can feed several consumers.
+type MultiContentHandler struct {
+	handlers []IContentHandler
+}
+
+func NewMultiContentHandler(handlers ...IContentHandler) *MultiContentHandler {
+	return &MultiContentHandler{handlers: handlers}
+}
+
+func (m *MultiContentHandler) HandleContent(url string, html string) error {
+	var errs []error
+	for _, handler := range m.handlers {
+		if err := handler.HandleContent(url, html); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m *MultiContentHandler) HandlePage(page *fetch.FetchResult) error {
+	var errs []error
+	for _, handler := range m.handlers {
+		if err := handleWith(handler, page.URL, page.HTML, page); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// handleWith calls HandlePage when both the handler supports it and the metadata is known, HandleContent otherwise.
+func handleWith(handler IContentHandler, url string, html string, page *fetch.FetchResult) error {
+	if pageHandler, ok := handler.(IPageHandler); ok && page != nil {
+		return pageHandler.HandlePage(page)
+	}
+	return handler.HandleContent(url, html)
+}
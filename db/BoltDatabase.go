@@ -0,0 +1,127 @@
+package db
+
+import (
+	"errors"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var boltBucket = []byte("keys")
+
+// BoltDatabase keeps all keys in a single bbolt file, so the version history survives restarts without
+// running jkdb. Every Store and Delete is a committed transaction. Only one process may open the file at a time.
+type BoltDatabase struct {
+	bolt *bolt.DB
+}
+
+func NewBoltDatabase(path string) (*BoltDatabase, error) {
+	database, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = database.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltBucket)
+		return err
+	})
+	if err != nil {
+		database.Close()
+		return nil, err
+	}
+	return &BoltDatabase{bolt: database}, nil
+}
+
+func (db *BoltDatabase) Store(key string, value []byte) error {
+	return db.bolt.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).Put([]byte(key), value)
+	})
+}
+
+func (db *BoltDatabase) Read(key string) ([]byte, error) {
+	var value []byte
+	err := db.bolt.View(func(tx *bolt.Tx) error {
+		stored := tx.Bucket(boltBucket).Get([]byte(key))
+		if stored == nil {
+			return errors.New("key not found")
+		}
+		// Values are only valid during the transaction
+		value = append([]byte{}, stored...)
+		return nil
+	})
+	return value, err
+}
+
+func (db *BoltDatabase) Delete(key string) error {
+	return db.bolt.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltBucket)
+		if bucket.Get([]byte(key)) == nil {
+			return errors.New("key not found")
+		}
+		return bucket.Delete([]byte(key))
+	})
+}
+
+func (db *BoltDatabase) Exists(key string) (bool, error) {
+	exists := false
+	err := db.bolt.View(func(tx *bolt.Tx) error {
+		exists = tx.Bucket(boltBucket).Get([]byte(key)) != nil
+		return nil
+	})
+	return exists, err
+}
+
+// ListKeys returns the keys in byte order.
+func (db *BoltDatabase) ListKeys() ([]string, error) {
+	keys := make([]string, 0)
+	err := db.bolt.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).ForEach(func(key, _ []byte) error {
+			keys = append(keys, string(key))
+			return nil
+		})
+	})
+	return keys, err
+}
+
+func (db *BoltDatabase) Count() (int, error) {
+	count := 0
+	err := db.bolt.View(func(tx *bolt.Tx) error {
+		count = tx.Bucket(boltBucket).Stats().KeyN
+		return nil
+	})
+	return count, err
+}
+
+func (db *BoltDatabase) Close() error {
+	return db.bolt.Close()
+}
+
+func (db *BoltDatabase) Scan(prefix string, cursor string, limit int) (ScanResult, error) {
+	return db.ScanRange(prefix, PrefixEnd(prefix), cursor, limit)
+}
+
+func (db *BoltDatabase) ScanRange(start string, end string, cursor string, limit int) (ScanResult, error) {
+	if limit <= 0 {
+		limit = DefaultScanLimit
+	}
+	result := ScanResult{Keys: make([]string, 0)}
+	err := db.bolt.View(func(tx *bolt.Tx) error {
+		boltCursor := tx.Bucket(boltBucket).Cursor()
+		key, _ := boltCursor.Seek([]byte(start))
+		if cursor >= start {
+			key, _ = boltCursor.Seek([]byte(cursor))
+			if key != nil && string(key) == cursor {
+				key, _ = boltCursor.Next()
+			}
+		}
+
+		for ; key != nil && (end == "" || string(key) < end); key, _ = boltCursor.Next() {
+			if len(result.Keys) == limit {
+				result.NextCursor = result.Keys[limit-1]
+				break
+			}
+			result.Keys = append(result.Keys, string(key))
+		}
+		return nil
+	})
+	return result, err
+}
@@ -0,0 +1,158 @@
+package db
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBoltDatabase_KeepsKeysAcrossReopening(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "versions.db")
+	database, err := NewBoltDatabase(path)
+	require.NoError(t, err)
+	require.NoError(t, database.Store("https://example.com/?a=1&b=2", []byte(`{"versions":[]}`)))
+	require.NoError(t, database.Store("crawlrun:1", []byte("run")))
+	require.NoError(t, database.Close())
+
+	sut, err := NewBoltDatabase(path)
+	require.NoError(t, err)
+	defer sut.Close()
+
+	value, err := sut.Read("https://example.com/?a=1&b=2")
+	require.NoError(t, err)
+	assert.Equal(t, `{"versions":[]}`, string(value))
+	keys, err := sut.ListKeys()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"crawlrun:1", "https://example.com/?a=1&b=2"}, keys)
+	count, err := sut.Count()
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestBoltDatabase_MissingKeys(t *testing.T) {
+	sut, err := NewBoltDatabase(filepath.Join(t.TempDir(), "versions.db"))
+	require.NoError(t, err)
+	defer sut.Close()
+	require.NoError(t, sut.Store("a", []byte("1")))
+
+	exists, err := sut.Exists("a")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	require.NoError(t, sut.Delete("a"))
+
+	exists, err = sut.Exists("a")
+	require.NoError(t, err)
+	assert.False(t, exists)
+	_, err = sut.Read("a")
+	assert.Error(t, err)
+	assert.Error(t, sut.Delete("a"))
+}
+
+func TestCopyDatabase(t *testing.T) {
+	source := NewInMemoryDatabase()
+	source.Store("https://example.com", []byte("history"))
+	source.Store("lifecycle:https://example.com", []byte("lifecycle"))
+	target, err := NewBoltDatabase(filepath.Join(t.TempDir(), "versions.db"))
+	require.NoError(t, err)
+	defer target.Close()
+	require.NoError(t, target.Store("https://example.com", []byte("outdated")))
+
+	copied, err := CopyDatabase(source, target)
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, copied)
+	value, err := target.Read("https://example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "history", string(value))
+	count, err := target.Count()
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+// scanOnlyDatabase fails ListKeys, so tests can check that large databases are only scanned.
+type scanOnlyDatabase struct {
+	IDatabase
+}
+
+func (db *scanOnlyDatabase) ListKeys() ([]string, error) {
+	return nil, errors.New("ListKeys must not be used")
+}
+
+func TestCopyDatabase_ScansKeysInPages(t *testing.T) {
+	source := NewInMemoryDatabase()
+	for i := 0; i < 2*DefaultScanLimit+1; i++ {
+		require.NoError(t, source.Store(fmt.Sprintf("https://example.com/%d", i), []byte("history")))
+	}
+	target := NewInMemoryDatabase()
+
+	copied, err := CopyDatabase(&scanOnlyDatabase{source}, target)
+
+	require.NoError(t, err)
+	assert.Equal(t, 2*DefaultScanLimit+1, copied)
+	count, err := target.Count()
+	require.NoError(t, err)
+	assert.Equal(t, copied, count)
+}
+
+// newBaselineServer answers like jkdb did before scans: keys are listed at once, in no particular order,
+// and values are base64 strings.
+func newBaselineServer(t *testing.T, data map[string]string) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/db/keys", func(w http.ResponseWriter, r *http.Request) {
+		keys := make([]string, 0, len(data))
+		for key := range data {
+			keys = append(keys, key)
+		}
+		json.NewEncoder(w).Encode(struct {
+			Keys []string `json:"keys"`
+		}{Keys: keys})
+	})
+	mux.HandleFunc("/db/read", func(w http.ResponseWriter, r *http.Request) {
+		key := r.URL.Query().Get("key")
+		value, ok := data[key]
+		if !ok {
+			http.Error(w, "Key not found", http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(struct {
+			Key   string `json:"key"`
+			Value string `json:"value"`
+		}{Key: key, Value: base64.StdEncoding.EncodeToString([]byte(value))})
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCopyDatabase_FromServerWithoutScans(t *testing.T) {
+	data := map[string]string{"https://b.com/": "b", "https://a.com/?x=1&y=2": "a", "crawlrun:1": "run"}
+	source := NewRemoteDatabase(newBaselineServer(t, data).URL)
+	target := NewInMemoryDatabase()
+
+	copied, err := CopyDatabase(source, target)
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, copied)
+	for key, value := range data {
+		stored, err := target.Read(key)
+		require.NoError(t, err, key)
+		assert.Equal(t, value, string(stored), key)
+	}
+
+	_, err = source.Scan("", "", 0)
+	assert.ErrorIs(t, err, ErrScanNotSupported)
+	keys := make([]string, 0)
+	require.NoError(t, ForEachKey(source, "https://", func(key string) error {
+		keys = append(keys, key)
+		return nil
+	}))
+	assert.Equal(t, []string{"https://a.com/?x=1&y=2", "https://b.com/"}, keys)
+}
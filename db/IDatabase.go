@@ -0,0 +1,175 @@
+// Package db is the key-value store holding the version history of crawled URLs.
+package db
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultScanLimit is the page size of a Scan without a positive limit.
+const DefaultScanLimit = 1000
+
+type IDatabase interface {
+	Store(key string, value []byte) error
+	Read(key string) ([]byte, error)
+	Delete(key string) error
+	Exists(key string) (bool, error)
+	ListKeys() ([]string, error)
+	Count() (int, error)
+	// Scan returns up to limit keys starting with prefix, in byte order, that come after cursor.
+	// Pass the NextCursor of a result to get the following page; it is empty after the last one.
+	Scan(prefix string, cursor string, limit int) (ScanResult, error)
+	// ScanRange is Scan over the keys from start up to, not including, end; an empty end has no bound.
+	ScanRange(start string, end string, cursor string, limit int) (ScanResult, error)
+}
+
+type ScanResult struct {
+	Keys       []string
+	NextCursor string
+}
+
+// ForEachKey calls fn for every key starting with prefix, in byte order, fetching the keys page by page.
+// It stops at the first error of fn and returns it. Servers that don't support scans yet have their keys
+// listed at once instead.
+func ForEachKey(database IDatabase, prefix string, fn func(key string) error) error {
+	cursor := ""
+	for {
+		result, err := database.Scan(prefix, cursor, DefaultScanLimit)
+		if cursor == "" && errors.Is(err, ErrScanNotSupported) {
+			return forEachListedKey(database, prefix, fn)
+		}
+		if err != nil {
+			return err
+		}
+		for _, key := range result.Keys {
+			if err := fn(key); err != nil {
+				return err
+			}
+		}
+		if result.NextCursor == "" {
+			return nil
+		}
+		cursor = result.NextCursor
+	}
+}
+
+func forEachListedKey(database IDatabase, prefix string, fn func(key string) error) error {
+	keys, err := database.ListKeys()
+	if err != nil {
+		return err
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if err := fn(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PrefixEnd is the end of the range of keys starting with prefix, empty if the range has no end.
+func PrefixEnd(prefix string) string {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return string(end[:i+1])
+		}
+	}
+	return ""
+}
+
+type InMemoryDatabase struct {
+	data map[string][]byte
+	// keys indexes data's keys for scans
+	keys SortedKeys
+	mu   sync.RWMutex
+}
+
+func NewInMemoryDatabase() *InMemoryDatabase {
+	return &InMemoryDatabase{
+		data: make(map[string][]byte),
+	}
+}
+
+func (db *InMemoryDatabase) Store(key string, value []byte) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, exists := db.data[key]; !exists {
+		db.keys.Add(key)
+	}
+	db.data[key] = value
+	return nil
+}
+
+func (db *InMemoryDatabase) Read(key string) ([]byte, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	value, exists := db.data[key]
+	if !exists {
+		return nil, errors.New("key not found")
+	}
+	return value, nil
+}
+
+func (db *InMemoryDatabase) Compare(key string, value []byte) (bool, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	storedValue, exists := db.data[key]
+	if !exists {
+		return false, errors.New("key not found")
+	}
+	return string(storedValue) == string(value), nil
+}
+
+func (db *InMemoryDatabase) Delete(key string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, exists := db.data[key]; !exists {
+		return errors.New("key not found")
+	}
+	delete(db.data, key)
+	db.keys.Remove(key)
+	return nil
+}
+
+func (db *InMemoryDatabase) Exists(key string) (bool, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	_, exists := db.data[key]
+	return exists, nil
+}
+
+func (db *InMemoryDatabase) ListKeys() ([]string, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	keys := make([]string, 0, len(db.data))
+	for key := range db.data {
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func (db *InMemoryDatabase) Count() (int, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return len(db.data), nil
+}
+
+// Scan takes the write lock, as it merges the keys stored since the last scan into the index.
+func (db *InMemoryDatabase) Scan(prefix string, cursor string, limit int) (ScanResult, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.keys.Scan(prefix, cursor, limit), nil
+}
+
+func (db *InMemoryDatabase) ScanRange(start string, end string, cursor string, limit int) (ScanResult, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.keys.ScanRange(start, end, cursor, limit), nil
+}
@@ -0,0 +1,125 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func scanDatabases(t *testing.T) map[string]IDatabase {
+	bolt, err := NewBoltDatabase(filepath.Join(t.TempDir(), "versions.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { bolt.Close() })
+	return map[string]IDatabase{"InMemoryDatabase": NewInMemoryDatabase(), "BoltDatabase": bolt}
+}
+
+func TestScan(t *testing.T) {
+	keys := []string{"https://b.com/", "https://a.com/x", "crawlrun:2", "https://a.com/", "https://a.com/y", "crawlrun:1"}
+	tests := []struct {
+		name     string
+		prefix   string
+		cursor   string
+		limit    int
+		expected ScanResult
+	}{
+		{"Everything", "", "", 0, ScanResult{Keys: []string{"crawlrun:1", "crawlrun:2", "https://a.com/", "https://a.com/x", "https://a.com/y", "https://b.com/"}}},
+		{"One domain", "https://a.com/", "", 10, ScanResult{Keys: []string{"https://a.com/", "https://a.com/x", "https://a.com/y"}}},
+		{"First page", "https://a.com/", "", 2, ScanResult{Keys: []string{"https://a.com/", "https://a.com/x"}, NextCursor: "https://a.com/x"}},
+		{"Last page", "https://a.com/", "https://a.com/x", 2, ScanResult{Keys: []string{"https://a.com/y"}}},
+		{"Exactly full last page", "crawlrun:", "", 2, ScanResult{Keys: []string{"crawlrun:1", "crawlrun:2"}}},
+		{"Cursor before prefix", "https://", "crawlrun:9", 1, ScanResult{Keys: []string{"https://a.com/"}, NextCursor: "https://a.com/"}},
+		{"Cursor of a deleted key", "", "https://a.com/w", 1, ScanResult{Keys: []string{"https://a.com/x"}, NextCursor: "https://a.com/x"}},
+		{"No match", "ftp://", "", 0, ScanResult{Keys: []string{}}},
+	}
+
+	for name, sut := range scanDatabases(t) {
+		for _, key := range append(keys, "https://a.com/deleted") {
+			require.NoError(t, sut.Store(key, []byte(key)))
+		}
+		require.NoError(t, sut.Delete("https://a.com/deleted"))
+
+		for _, tt := range tests {
+			t.Run(name+"/"+tt.name, func(t *testing.T) {
+				result, err := sut.Scan(tt.prefix, tt.cursor, tt.limit)
+				require.NoError(t, err)
+				assert.Equal(t, tt.expected, result)
+			})
+		}
+	}
+}
+
+func TestScanRange(t *testing.T) {
+	keys := []string{"https://a.com/", "https://a.com/x", "https://b.com/", "https://c.com/", "crawlrun:1"}
+	tests := []struct {
+		name     string
+		start    string
+		end      string
+		cursor   string
+		limit    int
+		expected ScanResult
+	}{
+		{"Bounded", "https://a.com/x", "https://c.com/", "", 0, ScanResult{Keys: []string{"https://a.com/x", "https://b.com/"}}},
+		{"Without end", "https://b", "", "", 0, ScanResult{Keys: []string{"https://b.com/", "https://c.com/"}}},
+		{"Without start", "", "https://", "", 0, ScanResult{Keys: []string{"crawlrun:1"}}},
+		{"Paged", "https://", "https://c", "", 2, ScanResult{Keys: []string{"https://a.com/", "https://a.com/x"}, NextCursor: "https://a.com/x"}},
+		{"Last page", "https://", "https://c", "https://a.com/x", 2, ScanResult{Keys: []string{"https://b.com/"}}},
+		{"Empty range", "https://b", "https://a", "", 0, ScanResult{Keys: []string{}}},
+	}
+
+	for name, sut := range scanDatabases(t) {
+		for _, key := range keys {
+			require.NoError(t, sut.Store(key, []byte(key)))
+		}
+
+		for _, tt := range tests {
+			t.Run(name+"/"+tt.name, func(t *testing.T) {
+				result, err := sut.ScanRange(tt.start, tt.end, tt.cursor, tt.limit)
+				require.NoError(t, err)
+				assert.Equal(t, tt.expected, result)
+			})
+		}
+	}
+}
+
+func TestPrefixEnd(t *testing.T) {
+	tests := []struct {
+		prefix   string
+		expected string
+	}{
+		{"https://a.com/", "https://a.com0"},
+		{"a\xff", "b"},
+		{"\xff\xff", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, PrefixEnd(tt.prefix), tt.prefix)
+	}
+}
+
+func TestForEachKey(t *testing.T) {
+	database := NewInMemoryDatabase()
+	for i := 0; i < DefaultScanLimit+5; i++ {
+		database.Store(fmt.Sprintf("page/%04d", i), nil)
+	}
+	database.Store("other", nil)
+
+	visited := 0
+	previous := ""
+	err := ForEachKey(database, "page/", func(key string) error {
+		assert.Greater(t, key, previous)
+		previous = key
+		visited++
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, DefaultScanLimit+5, visited)
+
+	stop := errors.New("stop")
+	assert.ErrorIs(t, ForEachKey(database, "", func(string) error { return stop }), stop)
+}
@@ -0,0 +1,21 @@
+package db
+
+import "fmt"
+
+// CopyDatabase stores every key of source with its value in target, overwriting keys target already has,
+// and returns the number of keys copied. The keys of source are scanned page by page, never listed at once.
+func CopyDatabase(source IDatabase, target IDatabase) (int, error) {
+	copied := 0
+	err := ForEachKey(source, "", func(key string) error {
+		value, err := source.Read(key)
+		if err != nil {
+			return fmt.Errorf("reading %q: %w", key, err)
+		}
+		if err := target.Store(key, value); err != nil {
+			return fmt.Errorf("storing %q: %w", key, err)
+		}
+		copied++
+		return nil
+	})
+	return copied, err
+}
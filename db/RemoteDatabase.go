@@ -0,0 +1,194 @@
+package db
+
+// an implementation of IDatabase interface, which stores data in a remote database
+// and uses REST API to interact with it.
+// The API is defined in the protocol package.
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"goCrawler/db/protocol"
+)
+
+// ErrScanNotSupported is returned by the scans of a RemoteDatabase whose server predates them.
+var ErrScanNotSupported = errors.New("the database server doesn't support scans")
+
+type RemoteDatabase struct {
+	url string
+	mu  sync.RWMutex
+}
+
+func NewRemoteDatabase(url string) *RemoteDatabase {
+	return &RemoteDatabase{
+		url: url,
+	}
+}
+
+// do sends a request with the protocol version and fails on any status but 200, except 404 with allowNotFound.
+func (db *RemoteDatabase) do(method string, url string, body []byte, allowNotFound bool) (*http.Response, error) {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(protocol.VersionHeader, protocol.Version)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusOK || (allowNotFound && resp.StatusCode == http.StatusNotFound) {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return nil, fmt.Errorf("%s %s: %s: %s", method, url, resp.Status, bytes.TrimSpace(message))
+}
+
+func (db *RemoteDatabase) Store(key string, value []byte) error {
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	fmt.Println("Storing key: ", key, " value: ", value)
+
+	reqData, err := json.Marshal(protocol.StoreRequest{Key: key, Value: value})
+	if err != nil {
+		return err
+	}
+
+	resp, err := db.do(http.MethodPost, db.url+protocol.StorePath, reqData, false)
+	if err != nil {
+		fmt.Println("Error storing value: ", err)
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (db *RemoteDatabase) Read(key string) ([]byte, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	resp, err := db.do(http.MethodGet, protocol.KeyURL(db.url, protocol.ReadPath, key), nil, true)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errors.New("key not found")
+	}
+
+	var respData protocol.ReadResponse
+	if err := json.NewDecoder(resp.Body).Decode(&respData); err != nil {
+		return nil, err
+	}
+	return respData.Value, nil
+}
+
+func (db *RemoteDatabase) Delete(key string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	resp, err := db.do(http.MethodDelete, protocol.KeyURL(db.url, protocol.DeletePath, key), nil, false)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (db *RemoteDatabase) Exists(key string) (bool, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	fmt.Println("Checking existence of key: ", key)
+	resp, err := db.do(http.MethodGet, protocol.KeyURL(db.url, protocol.ExistsPath, key), nil, true)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+
+	var respData protocol.ExistsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&respData); err != nil {
+		return false, err
+	}
+
+	fmt.Println("Key exists: ", respData.Exists)
+
+	return respData.Exists, nil
+}
+
+func (db *RemoteDatabase) ListKeys() ([]string, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	resp, err := db.do(http.MethodGet, db.url+protocol.KeysPath, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var respData protocol.KeysResponse
+	if err := json.NewDecoder(resp.Body).Decode(&respData); err != nil {
+		return nil, err
+	}
+	return respData.Keys, nil
+}
+
+func (db *RemoteDatabase) Count() (int, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	resp, err := db.do(http.MethodGet, db.url+protocol.CountPath, nil, false)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	var respData protocol.CountResponse
+	if err := json.NewDecoder(resp.Body).Decode(&respData); err != nil {
+		return 0, err
+	}
+	return respData.Count, nil
+}
+
+func (db *RemoteDatabase) Scan(prefix string, cursor string, limit int) (ScanResult, error) {
+	return db.scan(protocol.ScanURL(db.url, prefix, cursor, limit))
+}
+
+func (db *RemoteDatabase) ScanRange(start string, end string, cursor string, limit int) (ScanResult, error) {
+	return db.scan(protocol.RangeURL(db.url, start, end, cursor, limit))
+}
+
+// scan requests one page of keys from a scan or range URL. Servers without scans answer 404.
+func (db *RemoteDatabase) scan(url string) (ScanResult, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	resp, err := db.do(http.MethodGet, url, nil, true)
+	if err != nil {
+		return ScanResult{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ScanResult{}, fmt.Errorf("GET %s: %w", url, ErrScanNotSupported)
+	}
+
+	var respData protocol.ScanResponse
+	if err := json.NewDecoder(resp.Body).Decode(&respData); err != nil {
+		return ScanResult{}, err
+	}
+	return ScanResult{Keys: respData.Keys, NextCursor: respData.NextCursor}, nil
+}
@@ -0,0 +1,85 @@
+package db
+
+import "sort"
+
+// SortedKeys indexes keys in byte order for scans. Added keys are collected and merged into the index by
+// the next scan, so loading n keys costs a sort rather than n insertions into the middle of a slice.
+// It isn't safe for concurrent use, and since Scan merges, callers need their write lock for it too.
+type SortedKeys struct {
+	sorted  []string
+	pending []string
+}
+
+// Add adds a key that isn't in the index yet.
+func (s *SortedKeys) Add(key string) {
+	s.pending = append(s.pending, key)
+}
+
+// Remove removes key from the index if it's there.
+func (s *SortedKeys) Remove(key string) {
+	if i := sort.SearchStrings(s.sorted, key); i < len(s.sorted) && s.sorted[i] == key {
+		s.sorted = append(s.sorted[:i], s.sorted[i+1:]...)
+		return
+	}
+	for i, pending := range s.pending {
+		if pending == key {
+			s.pending[i] = s.pending[len(s.pending)-1]
+			s.pending = s.pending[:len(s.pending)-1]
+			return
+		}
+	}
+}
+
+// Scan is IDatabase.Scan over the indexed keys.
+func (s *SortedKeys) Scan(prefix string, cursor string, limit int) ScanResult {
+	return s.ScanRange(prefix, PrefixEnd(prefix), cursor, limit)
+}
+
+// ScanRange is IDatabase.ScanRange over the indexed keys.
+func (s *SortedKeys) ScanRange(start string, end string, cursor string, limit int) ScanResult {
+	s.merge()
+	if limit <= 0 {
+		limit = DefaultScanLimit
+	}
+	keys := s.sorted
+	first := sort.SearchStrings(keys, start)
+	if cursor >= start {
+		first = sort.Search(len(keys), func(i int) bool { return keys[i] > cursor })
+	}
+
+	result := ScanResult{Keys: make([]string, 0)}
+	for i := first; i < len(keys) && (end == "" || keys[i] < end); i++ {
+		if len(result.Keys) == limit {
+			result.NextCursor = result.Keys[limit-1]
+			break
+		}
+		result.Keys = append(result.Keys, keys[i])
+	}
+	return result
+}
+
+// merge sorts the pending keys into the index, in O(n + p log p) for n indexed and p pending keys.
+func (s *SortedKeys) merge() {
+	if len(s.pending) == 0 {
+		return
+	}
+	sort.Strings(s.pending)
+	if len(s.sorted) == 0 {
+		s.sorted, s.pending = s.pending, nil
+		return
+	}
+
+	merged := make([]string, 0, len(s.sorted)+len(s.pending))
+	i, j := 0, 0
+	for i < len(s.sorted) && j < len(s.pending) {
+		if s.sorted[i] < s.pending[j] {
+			merged = append(merged, s.sorted[i])
+			i++
+		} else {
+			merged = append(merged, s.pending[j])
+			j++
+		}
+	}
+	merged = append(merged, s.sorted[i:]...)
+	s.sorted, s.pending = append(merged, s.pending[j:]...), nil
+}
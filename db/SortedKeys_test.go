@@ -0,0 +1,38 @@
+package db
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSortedKeys_StaysSortedAcrossAddsRemovesAndScans(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	var sut SortedKeys
+	expected := make(map[string]bool)
+
+	for i := 0; i < 5000; i++ {
+		key := fmt.Sprintf("https://example.com/%d", random.Intn(2000))
+		switch {
+		case random.Intn(4) == 0:
+			sut.Remove(key)
+			delete(expected, key)
+		case !expected[key]:
+			sut.Add(key)
+			expected[key] = true
+		}
+		if random.Intn(100) == 0 {
+			sut.Scan("", "", 1)
+		}
+	}
+
+	keys := make([]string, 0, len(expected))
+	for key := range expected {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	assert.Equal(t, ScanResult{Keys: keys}, sut.Scan("", "", len(keys)+1))
+}
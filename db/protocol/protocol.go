@@ -0,0 +1,103 @@
+// Package protocol defines the HTTP API between jkdb and db.RemoteDatabase, so both sides share
+// the paths, parameters and message types.
+//
+// Every request and response carries the protocol version in the VersionHeader. The server rejects
+// requests for another version; requests without the header are served for older clients.
+// Values are []byte fields, which JSON encodes as standard base64.
+package protocol
+
+import (
+	"net/url"
+	"strconv"
+)
+
+const (
+	Version       = "1"
+	VersionHeader = "X-Jkdb-Protocol-Version"
+
+	// StorePath takes a POST of a StoreRequest.
+	StorePath = "/db/store"
+	// ReadPath answers a GET with a ReadResponse, or 404 if the key is missing.
+	ReadPath = "/db/read"
+	// DeletePath takes a DELETE.
+	DeletePath = "/db/delete"
+	// ExistsPath answers a GET with an ExistsResponse.
+	ExistsPath = "/db/exists"
+	// KeysPath answers a GET with a KeysResponse.
+	KeysPath = "/db/keys"
+	// CountPath answers a GET with a CountResponse.
+	CountPath = "/db/count"
+	// ScanPath answers a GET with a ScanResponse, for the PrefixParameter, CursorParameter and LimitParameter.
+	ScanPath = "/db/scan"
+	// RangePath answers a GET with a ScanResponse, for the StartParameter, EndParameter, CursorParameter and
+	// LimitParameter.
+	RangePath = "/db/range"
+
+	// KeyParameter is the query parameter naming the key of read, delete and exists requests.
+	KeyParameter    = "key"
+	PrefixParameter = "prefix"
+	CursorParameter = "cursor"
+	LimitParameter  = "limit"
+	StartParameter  = "start"
+	EndParameter    = "end"
+)
+
+type StoreRequest struct {
+	Key   string `json:"key"`
+	Value []byte `json:"value"`
+}
+
+type ReadResponse struct {
+	Key   string `json:"key"`
+	Value []byte `json:"value"`
+}
+
+type ExistsResponse struct {
+	Exists bool `json:"exists"`
+}
+
+type KeysResponse struct {
+	Keys []string `json:"keys"`
+}
+
+type CountResponse struct {
+	Count int `json:"count"`
+}
+
+// ScanResponse is one page of a scan; NextCursor is empty after the last page.
+type ScanResponse struct {
+	Keys       []string `json:"keys"`
+	NextCursor string   `json:"nextCursor,omitempty"`
+}
+
+// KeyURL is the URL of a request for key below baseURL; the key is query-escaped, so keys may contain
+// any character, including '&', '?' and '#'.
+func KeyURL(baseURL string, path string, key string) string {
+	return baseURL + path + "?" + url.Values{KeyParameter: {key}}.Encode()
+}
+
+// ScanURL is the URL of a scan request below baseURL.
+func ScanURL(baseURL string, prefix string, cursor string, limit int) string {
+	query := url.Values{PrefixParameter: {prefix}, LimitParameter: {strconv.Itoa(limit)}}
+	if cursor != "" {
+		query.Set(CursorParameter, cursor)
+	}
+	return baseURL + ScanPath + "?" + query.Encode()
+}
+
+// RangeURL is the URL of a range scan request below baseURL.
+func RangeURL(baseURL string, start string, end string, cursor string, limit int) string {
+	query := url.Values{StartParameter: {start}, LimitParameter: {strconv.Itoa(limit)}}
+	if end != "" {
+		query.Set(EndParameter, end)
+	}
+	if cursor != "" {
+		query.Set(CursorParameter, cursor)
+	}
+	return baseURL + RangePath + "?" + query.Encode()
+}
+
+// SupportedVersion reports whether a request with the version header value can be served.
+func SupportedVersion(version string) bool {
+	return version == "" || version == Version
+}
@@ -0,0 +1,68 @@
+package diff
+
+import "time"
+
+type ChangeEventType string
+
+const (
+	// NewPageEvent is emitted when a URL is versioned for the first time.
+	NewPageEvent ChangeEventType = "new_page"
+	// NewVersionEvent is emitted when the content of a known URL changes.
+	NewVersionEvent ChangeEventType = "new_version"
+	// PageGoneEvent is emitted when a known URL answers 404/410 or isn't reached by a crawl of its seed anymore.
+	PageGoneEvent ChangeEventType = "page_gone"
+	// PageReturnedEvent is emitted when a gone URL is fetched successfully again.
+	PageReturnedEvent ChangeEventType = "page_returned"
+)
+
+// ChangeEvent describes a change detected by the DifferenceTracker. OldVersion and OldHash are empty for new pages,
+// gone pages only have the Old fields set to their last version. Reason and StatusCode explain why a page is gone.
+type ChangeEvent struct {
+	Type           ChangeEventType `json:"event"`
+	URL            string          `json:"url"`
+	OldVersion     int             `json:"oldVersion,omitempty"`
+	NewVersion     int             `json:"newVersion"`
+	OldHash        string          `json:"oldHash,omitempty"`
+	NewHash        string          `json:"newHash"`
+	DiffSummary    *DiffSummary    `json:"diffSummary,omitempty"`
+	ChangedRegions []string        `json:"changedRegions,omitempty"`
+	Similarity     float64         `json:"similarity,omitempty"`
+	Reason         string          `json:"reason,omitempty"`
+	StatusCode     int             `json:"statusCode,omitempty"`
+	CrawlRunID     string          `json:"crawlRunId,omitempty"`
+	DetectedAt     time.Time       `json:"detectedAt"`
+}
+
+// INotifier is told about every change the DifferenceTracker records.
+type INotifier interface {
+	Notify(event ChangeEvent) error
+}
+
+// IRunNotifier is implemented by notifiers that batch events per crawl run instead of reporting each one.
+// FinishCrawlRun calls RunFinished once all pages of the run were handled.
+type IRunNotifier interface {
+	RunFinished(run *CrawlRun) error
+}
+
+func newChangeEvent(url string, pageVersions []PageVersion) ChangeEvent {
+	latest := pageVersions[len(pageVersions)-1]
+	event := ChangeEvent{
+		Type:           NewPageEvent,
+		URL:            url,
+		NewVersion:     latest.Version,
+		NewHash:        latest.Hash,
+		DiffSummary:    latest.DiffSummary,
+		ChangedRegions: latest.ChangedRegions,
+		Similarity:     latest.Similarity,
+		CrawlRunID:     latest.CrawlRunID,
+		DetectedAt:     latest.FirstSeen,
+	}
+
+	if len(pageVersions) > 1 {
+		previous := pageVersions[len(pageVersions)-2]
+		event.Type = NewVersionEvent
+		event.OldVersion = previous.Version
+		event.OldHash = previous.Hash
+	}
+	return event
+}
@@ -0,0 +1,304 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"goCrawler/db"
+	"goCrawler/urlutil"
+)
+
+// crawlRunKeyPrefix keeps crawl runs apart from page keys, which are always absolute URLs.
+const crawlRunKeyPrefix = "crawlrun:"
+
+// CrawlRun records a single invocation of the crawler: what it was asked to crawl and what it found.
+type CrawlRun struct {
+	ID         string
+	Seeds      []string
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Config     json.RawMessage `json:",omitempty"`
+
+	PagesFetched   int
+	NewPages       int
+	ChangedPages   int
+	UnchangedPages int
+	FailedPages    int
+	GonePages      int `json:",omitempty"`
+	ReturnedPages  int `json:",omitempty"`
+
+	NewURLs      []string
+	ChangedURLs  []string
+	FailedURLs   []string
+	GoneURLs     []string `json:",omitempty"`
+	ReturnedURLs []string `json:",omitempty"`
+}
+
+// crawlRunRecorder accumulates the outcome of every handled page into a CrawlRun.
+// Crawls of several seeds share one tracker, so it has to be safe for concurrent use.
+type crawlRunRecorder struct {
+	mu   sync.Mutex
+	run  *CrawlRun
+	seen map[string]bool
+	// failed holds the URLs whose fetch failed, fetchedDomains the domains with at least one successful fetch
+	failed         map[string]bool
+	fetchedDomains map[string]bool
+}
+
+func (r *crawlRunRecorder) start(run *CrawlRun) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.run = run
+	r.seen = make(map[string]bool)
+	r.failed = make(map[string]bool)
+	r.fetchedDomains = make(map[string]bool)
+}
+
+type pageOutcome int
+
+const (
+	pageNew pageOutcome = iota
+	pageChanged
+	pageUnchanged
+	pageFailed
+)
+
+func (r *crawlRunRecorder) record(url string, outcome pageOutcome) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.run == nil {
+		return
+	}
+
+	r.run.PagesFetched++
+	r.seen[url] = true
+	if outcome == pageFailed {
+		r.failed[url] = true
+	} else {
+		r.fetchedDomains[urlutil.NormalizeDomain(url)] = true
+	}
+	switch outcome {
+	case pageNew:
+		r.run.NewPages++
+		r.run.NewURLs = append(r.run.NewURLs, url)
+	case pageChanged:
+		r.run.ChangedPages++
+		r.run.ChangedURLs = append(r.run.ChangedURLs, url)
+	case pageUnchanged:
+		r.run.UnchangedPages++
+	case pageFailed:
+		r.run.FailedPages++
+		r.run.FailedURLs = append(r.run.FailedURLs, url)
+	}
+}
+
+func (r *crawlRunRecorder) recordLifecycle(url string, eventType ChangeEventType) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.run == nil {
+		return
+	}
+
+	switch eventType {
+	case PageGoneEvent:
+		r.run.GonePages++
+		r.run.GoneURLs = append(r.run.GoneURLs, url)
+	case PageReturnedEvent:
+		r.run.ReturnedPages++
+		r.run.ReturnedURLs = append(r.run.ReturnedURLs, url)
+	}
+}
+
+func (r *crawlRunRecorder) wasSeen(url string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.seen[url]
+}
+
+// reachedSeeds returns the seeds of the run whose crawl got anywhere: the seed itself didn't fail and some page
+// of its domain was fetched. A seed that couldn't be fetched discovers no links, so its pages aren't gone.
+func (r *crawlRunRecorder) reachedSeeds() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	seeds := make([]string, 0, len(r.run.Seeds))
+	for _, seed := range r.run.Seeds {
+		if !r.failed[seed] && r.fetchedDomains[urlutil.NormalizeDomain(seed)] {
+			seeds = append(seeds, seed)
+		}
+	}
+	return seeds
+}
+
+func NewCrawlRunID(startedAt time.Time) string {
+	return startedAt.UTC().Format("20060102T150405.000Z")
+}
+
+func IsCrawlRunKey(key string) bool {
+	return strings.HasPrefix(key, crawlRunKeyPrefix)
+}
+
+func StoreCrawlRun(database db.IDatabase, run *CrawlRun) error {
+	bytes, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	return database.Store(crawlRunKeyPrefix+run.ID, bytes)
+}
+
+func LoadCrawlRun(database db.IDatabase, id string) (*CrawlRun, error) {
+	exists, err := database.Exists(crawlRunKeyPrefix + id)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("crawl run %s not found", id)
+	}
+
+	bytes, err := database.Read(crawlRunKeyPrefix + id)
+	if err != nil {
+		return nil, err
+	}
+
+	var run CrawlRun
+	if err := json.Unmarshal(bytes, &run); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// ListCrawlRuns returns all recorded crawl runs, oldest first.
+func ListCrawlRuns(database db.IDatabase) ([]*CrawlRun, error) {
+	runs := make([]*CrawlRun, 0)
+	err := db.ForEachKey(database, crawlRunKeyPrefix, func(key string) error {
+		run, err := LoadCrawlRun(database, strings.TrimPrefix(key, crawlRunKeyPrefix))
+		if err != nil {
+			return err
+		}
+		runs = append(runs, run)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(runs, func(i, j int) bool { return runs[i].StartedAt.Before(runs[j].StartedAt) })
+	return runs, nil
+}
+
+// CrawlRunComparison describes how a later crawl run differs from an earlier one.
+type CrawlRunComparison struct {
+	Before *CrawlRun
+	After  *CrawlRun
+
+	// URLs that changed (or appeared) only in one of the runs.
+	ChangedOnlyBefore []string
+	ChangedOnlyAfter  []string
+	// URLs failing in After but not in Before, and the other way round.
+	NewlyFailing []string
+	Recovered    []string
+}
+
+func CompareCrawlRuns(before, after *CrawlRun) CrawlRunComparison {
+	changedBefore := append(append([]string{}, before.NewURLs...), before.ChangedURLs...)
+	changedAfter := append(append([]string{}, after.NewURLs...), after.ChangedURLs...)
+
+	return CrawlRunComparison{
+		Before:            before,
+		After:             after,
+		ChangedOnlyBefore: difference(changedBefore, changedAfter),
+		ChangedOnlyAfter:  difference(changedAfter, changedBefore),
+		NewlyFailing:      difference(after.FailedURLs, before.FailedURLs),
+		Recovered:         difference(before.FailedURLs, after.FailedURLs),
+	}
+}
+
+// difference returns the sorted elements of a that are not in b.
+func difference(a, b []string) []string {
+	inB := make(map[string]bool, len(b))
+	for _, item := range b {
+		inB[item] = true
+	}
+
+	result := make([]string, 0)
+	for _, item := range a {
+		if !inB[item] {
+			result = append(result, item)
+			inB[item] = true
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+func PrintCrawlRuns(out io.Writer, runs []*CrawlRun) error {
+	writer := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "ID\tSTARTED\tDURATION\tFETCHED\tNEW\tCHANGED\tUNCHANGED\tFAILED\tGONE\tRETURNED\tSEEDS")
+	for _, run := range runs {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%s\n", run.ID, formatTimestamp(run.StartedAt),
+			runDuration(run), run.PagesFetched, run.NewPages, run.ChangedPages, run.UnchangedPages, run.FailedPages,
+			run.GonePages, run.ReturnedPages, strings.Join(run.Seeds, ","))
+	}
+	return writer.Flush()
+}
+
+func PrintCrawlRunComparison(out io.Writer, comparison CrawlRunComparison) error {
+	before, after := comparison.Before, comparison.After
+
+	writer := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(writer, "\t%s\t%s\tDELTA\n", before.ID, after.ID)
+	counts := []struct {
+		name          string
+		before, after int
+	}{
+		{"fetched", before.PagesFetched, after.PagesFetched},
+		{"new", before.NewPages, after.NewPages},
+		{"changed", before.ChangedPages, after.ChangedPages},
+		{"unchanged", before.UnchangedPages, after.UnchangedPages},
+		{"failed", before.FailedPages, after.FailedPages},
+		{"gone", before.GonePages, after.GonePages},
+		{"returned", before.ReturnedPages, after.ReturnedPages},
+	}
+	for _, count := range counts {
+		fmt.Fprintf(writer, "%s\t%d\t%d\t%+d\n", count.name, count.before, count.after, count.after-count.before)
+	}
+	fmt.Fprintf(writer, "duration\t%s\t%s\t\n", runDuration(before), runDuration(after))
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+
+	printUrlList(out, "Changed only in "+before.ID, comparison.ChangedOnlyBefore)
+	printUrlList(out, "Changed only in "+after.ID, comparison.ChangedOnlyAfter)
+	printUrlList(out, "Newly failing", comparison.NewlyFailing)
+	printUrlList(out, "Recovered", comparison.Recovered)
+	printUrlList(out, "Gone in "+after.ID, after.GoneURLs)
+	printUrlList(out, "Returned in "+after.ID, after.ReturnedURLs)
+	return nil
+}
+
+func printUrlList(out io.Writer, title string, urls []string) {
+	if len(urls) == 0 {
+		return
+	}
+	fmt.Fprintf(out, "\n%s (%d):\n", title, len(urls))
+	for _, url := range urls {
+		fmt.Fprintf(out, "  %s\n", url)
+	}
+}
+
+func runDuration(run *CrawlRun) string {
+	if run.FinishedAt.IsZero() {
+		return "-"
+	}
+	return run.FinishedAt.Sub(run.StartedAt).Round(time.Second).String()
+}
@@ -0,0 +1,115 @@
+package diff
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"goCrawler/db"
+	"goCrawler/fetch"
+	"goCrawler/storage"
+)
+
+func TestCrawlRun_RecordsPageOutcomes(t *testing.T) {
+	database := db.NewInMemoryDatabase()
+	sut := NewDifferenceTracker(database, storage.NewFileStorage(t.TempDir()))
+
+	require.NoError(t, sut.HandleContent("https://www.google.com", defaultHtmlContent))
+	require.NoError(t, sut.HandleContent("https://www.google.com/kontakty", defaultHtmlContent))
+
+	startedAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	sut.now = func() time.Time { return startedAt }
+	sut.StartCrawlRun([]string{"https://www.google.com"}, []byte(`{"Limits":{"MaxPages":10}}`))
+
+	require.NoError(t, sut.HandleContent("https://www.google.com", changedHtmlContent))
+	require.NoError(t, sut.HandleContent("https://www.google.com/kontakty", defaultHtmlContent))
+	require.NoError(t, sut.HandleContent("https://www.google.com/pomoc", defaultHtmlContent))
+	require.NoError(t, sut.HandlePage(&fetch.FetchResult{URL: "https://www.google.com/broken", Err: errors.New("timeout")}))
+	require.NoError(t, sut.HandlePage(&fetch.FetchResult{URL: "https://www.google.com/missing", HTML: "not found", StatusCode: 404}))
+
+	sut.now = func() time.Time { return startedAt.Add(time.Minute) }
+	run, err := sut.FinishCrawlRun()
+	require.NoError(t, err)
+
+	stored, err := LoadCrawlRun(database, run.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://www.google.com"}, stored.Seeds)
+	assert.Equal(t, startedAt, stored.StartedAt)
+	assert.Equal(t, startedAt.Add(time.Minute), stored.FinishedAt)
+	assert.JSONEq(t, `{"Limits":{"MaxPages":10}}`, string(stored.Config))
+	assert.Equal(t, 5, stored.PagesFetched)
+	assert.Equal(t, 1, stored.NewPages)
+	assert.Equal(t, 1, stored.ChangedPages)
+	assert.Equal(t, 1, stored.UnchangedPages)
+	assert.Equal(t, 2, stored.FailedPages)
+	assert.Equal(t, []string{"https://www.google.com/pomoc"}, stored.NewURLs)
+	assert.Equal(t, []string{"https://www.google.com"}, stored.ChangedURLs)
+	assert.Equal(t, []string{"https://www.google.com/broken", "https://www.google.com/missing"}, stored.FailedURLs)
+
+	pageVersions, err := LoadPageVersions(database, "https://www.google.com")
+	require.NoError(t, err)
+	assert.Equal(t, run.ID, pageVersions[1].CrawlRunID)
+	assert.Equal(t, "", pageVersions[0].CrawlRunID)
+
+	_, err = LoadPageVersions(database, "https://www.google.com/broken")
+	assert.Error(t, err, "pages that failed to download must not be versioned")
+}
+
+func TestFinishCrawlRun_WithoutStart(t *testing.T) {
+	sut := NewDifferenceTracker(db.NewInMemoryDatabase(), storage.NewFileStorage(t.TempDir()))
+
+	_, err := sut.FinishCrawlRun()
+	assert.Error(t, err)
+}
+
+func TestListCrawlRuns_SkipsPagesAndSortsByStart(t *testing.T) {
+	database := db.NewInMemoryDatabase()
+	require.NoError(t, database.Store("https://www.google.com", []byte(`[]`)))
+
+	later := &CrawlRun{ID: "later", StartedAt: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)}
+	earlier := &CrawlRun{ID: "earlier", StartedAt: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)}
+	require.NoError(t, StoreCrawlRun(database, later))
+	require.NoError(t, StoreCrawlRun(database, earlier))
+
+	runs, err := ListCrawlRuns(database)
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	assert.Equal(t, "earlier", runs[0].ID)
+	assert.Equal(t, "later", runs[1].ID)
+
+	histories, err := LoadAllPageHistories(database)
+	require.NoError(t, err)
+	assert.Len(t, histories, 1)
+}
+
+func TestCompareCrawlRuns(t *testing.T) {
+	before := &CrawlRun{
+		ID:           "before",
+		PagesFetched: 3,
+		NewURLs:      []string{"https://a.com/new"},
+		ChangedURLs:  []string{"https://a.com/both", "https://a.com/before"},
+		FailedURLs:   []string{"https://a.com/fixed"},
+	}
+	after := &CrawlRun{
+		ID:           "after",
+		PagesFetched: 4,
+		ChangedURLs:  []string{"https://a.com/both", "https://a.com/after"},
+		FailedURLs:   []string{"https://a.com/broken"},
+	}
+
+	comparison := CompareCrawlRuns(before, after)
+
+	assert.Equal(t, []string{"https://a.com/before", "https://a.com/new"}, comparison.ChangedOnlyBefore)
+	assert.Equal(t, []string{"https://a.com/after"}, comparison.ChangedOnlyAfter)
+	assert.Equal(t, []string{"https://a.com/broken"}, comparison.NewlyFailing)
+	assert.Equal(t, []string{"https://a.com/fixed"}, comparison.Recovered)
+
+	var out bytes.Buffer
+	require.NoError(t, PrintCrawlRunComparison(&out, comparison))
+	assert.Regexp(t, `fetched\s+3\s+4\s+\+1`, out.String())
+	assert.Contains(t, out.String(), "Newly failing (1):\n  https://a.com/broken")
+}
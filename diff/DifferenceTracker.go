@@ -0,0 +1,512 @@
+// Package diff detects when crawled pages change and records their version history.
+package diff
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"path"
+	"strings"
+	"time"
+
+	"goCrawler/db"
+	"goCrawler/fetch"
+	"goCrawler/storage"
+)
+
+type DifferenceTracker struct {
+	database    db.IDatabase
+	fileStorage storage.IStorage
+	crawlRunID  string
+	runRecorder crawlRunRecorder
+	normalizer  *Normalizer
+	watchRules  *WatchRules
+	notifiers   []INotifier
+	// detectUnseenPages marks known pages the finished crawl run didn't reach as gone.
+	detectUnseenPages bool
+	// similarityThreshold ignores changes at least this similar to the latest version, 0 disables it.
+	similarityThreshold float64
+	now                 func() time.Time
+}
+
+func NewDifferenceTracker(database db.IDatabase, fileStorage storage.IStorage) *DifferenceTracker {
+	return &DifferenceTracker{
+		database:          database,
+		fileStorage:       fileStorage,
+		detectUnseenPages: true,
+		now:               time.Now,
+	}
+}
+
+// SetNormalizer makes the tracker hash the normalized form of pages instead of the raw HTML.
+func (diffTracker *DifferenceTracker) SetNormalizer(normalizer *Normalizer) {
+	diffTracker.normalizer = normalizer
+}
+
+// SetWatchRules limits change detection of matching URLs to the regions selected by their rules.
+func (diffTracker *DifferenceTracker) SetWatchRules(watchRules *WatchRules) {
+	diffTracker.watchRules = watchRules
+}
+
+// SetDetectUnseenPages selects whether FinishCrawlRun marks known pages of the run's seeds that weren't
+// reached as gone. Runs that stop early, e.g. at a page limit, should disable it.
+func (diffTracker *DifferenceTracker) SetDetectUnseenPages(enabled bool) {
+	diffTracker.detectUnseenPages = enabled
+}
+
+// SetSimilarityThreshold makes the tracker ignore changes whose SimHash similarity to the latest version
+// is at least threshold, e.g. 0.98 to ignore single changed words. Ignored changes count as unchanged,
+// so small changes only create a new version once they add up. 0 records every change.
+func (diffTracker *DifferenceTracker) SetSimilarityThreshold(threshold float64) {
+	diffTracker.similarityThreshold = threshold
+}
+
+// AddNotifier registers a notifier told about every new page, new version and gone or returned page.
+func (diffTracker *DifferenceTracker) AddNotifier(notifier INotifier) {
+	diffTracker.notifiers = append(diffTracker.notifiers, notifier)
+}
+
+// StartCrawlRun begins recording a new crawl run; every version stored until FinishCrawlRun is tagged with its ID.
+// config is an optional snapshot of the configuration the run was started with.
+func (diffTracker *DifferenceTracker) StartCrawlRun(seeds []string, config json.RawMessage) *CrawlRun {
+	startedAt := diffTracker.now().UTC()
+	run := &CrawlRun{
+		ID:        NewCrawlRunID(startedAt),
+		Seeds:     seeds,
+		StartedAt: startedAt,
+		Config:    config,
+	}
+
+	diffTracker.runRecorder.start(run)
+	diffTracker.crawlRunID = run.ID
+
+	return run
+}
+
+// FinishCrawlRun stores the run started by StartCrawlRun in the database and stops recording.
+// It must only be called once all crawls of the run are done, as pages not handled by then count as gone.
+func (diffTracker *DifferenceTracker) FinishCrawlRun() (*CrawlRun, error) {
+	if diffTracker.detectUnseenPages {
+		diffTracker.markUnseenPagesGone()
+	}
+
+	diffTracker.runRecorder.mu.Lock()
+	run := diffTracker.runRecorder.run
+	diffTracker.runRecorder.run = nil
+	diffTracker.runRecorder.mu.Unlock()
+
+	if run == nil {
+		return nil, errors.New("no crawl run in progress")
+	}
+
+	run.FinishedAt = diffTracker.now().UTC()
+	if err := StoreCrawlRun(diffTracker.database, run); err != nil {
+		handleError(err, "Error storing crawl run, id="+run.ID)
+		return run, err
+	}
+
+	for _, notifier := range diffTracker.notifiers {
+		if runNotifier, ok := notifier.(IRunNotifier); ok {
+			if err := runNotifier.RunFinished(run); err != nil {
+				handleError(err, "Error notifying about crawl run, id="+run.ID)
+			}
+		}
+	}
+	return run, nil
+}
+
+func (diffTracker *DifferenceTracker) HandleContent(url string, htmlContent string) error {
+	return diffTracker.HandlePage(&fetch.FetchResult{URL: url, HTML: htmlContent})
+}
+
+func (diffTracker *DifferenceTracker) HandlePage(page *fetch.FetchResult) error {
+	if page.Err != nil {
+		handleError(page.Err, "Error fetching page, url="+page.URL)
+		diffTracker.runRecorder.record(page.URL, pageFailed)
+		return nil
+	}
+
+	outcome, err := diffTracker.handlePage(page)
+	if err != nil || page.StatusCode >= 400 {
+		outcome = pageFailed
+	}
+	diffTracker.runRecorder.record(page.URL, outcome)
+
+	return err
+}
+
+func (diffTracker *DifferenceTracker) handlePage(page *fetch.FetchResult) (pageOutcome, error) {
+	url := page.URL
+	fingerprint := diffTracker.fingerprint(page)
+
+	urlExists, err := diffTracker.database.Exists(url)
+	if err != nil {
+		handleError(err, "Error checking if URL exists in database, url="+url)
+		return pageFailed, err
+	}
+
+	if isGoneStatus(page.StatusCode) {
+		// The error page isn't versioned; a known page is marked gone until it is fetched successfully again
+		if !urlExists {
+			return pageFailed, nil
+		}
+		pageVersions, err := diffTracker.readPageVersions(url)
+		if err != nil {
+			return pageFailed, err
+		}
+		return pageFailed, diffTracker.markGone(url, pageVersions, GoneNotFound, page.StatusCode)
+	}
+
+	if urlExists {
+		return diffTracker.updateExistingContent(page, fingerprint)
+	} else {
+		return pageNew, diffTracker.storeNewContent(page, fingerprint)
+	}
+}
+
+func (diffTracker *DifferenceTracker) updateExistingContent(page *fetch.FetchResult, fingerprint contentFingerprint) (pageOutcome, error) {
+	url := page.URL
+	pageVersions, err := diffTracker.readPageVersions(url)
+	if err != nil {
+		return pageFailed, err
+	}
+
+	latestPageVersion := &pageVersions[len(pageVersions)-1]
+	wasGone := latestPageVersion.Gone
+	latestPageVersion.Gone = false
+
+	outcome, pageVersions, err := diffTracker.compareWithLatestVersion(page, fingerprint, pageVersions)
+	if err == nil && wasGone {
+		diffTracker.recordLifecycleEvent(url, pageVersions, ChangeEvent{Type: PageReturnedEvent, DetectedAt: diffTracker.fetchTime(page)})
+	}
+	return outcome, err
+}
+
+// compareWithLatestVersion stores a new version if page differs from the latest one, or records that it was seen again.
+func (diffTracker *DifferenceTracker) compareWithLatestVersion(page *fetch.FetchResult, fingerprint contentFingerprint, pageVersions []PageVersion) (pageOutcome, []PageVersion, error) {
+	url := page.URL
+	latestPageVersion := &pageVersions[len(pageVersions)-1]
+	checkedAt := diffTracker.fetchTime(page)
+
+	if latestPageVersion.NormalizationID != fingerprint.schemeID {
+		// The latest hash was computed with different normalization options or watch rules, so comparing
+		// it would report a change on every URL. Re-baseline it by hashing the stored raw content the new way,
+		// so a real change in this crawl is still detected.
+		log.Printf("Normalization changed, re-baselining hash of url=%s", url)
+		baseline := fingerprint
+		if latestContent, err := diffTracker.fileStorage.Read(latestPageVersion.FilePath); err == nil {
+			baseline = diffTracker.fingerprint(&fetch.FetchResult{URL: url, HTML: string(latestContent)})
+		} else {
+			handleError(err, "Error reading latest version to re-baseline, assuming it is unchanged, url="+url)
+		}
+		latestPageVersion.Hash = baseline.hash
+		latestPageVersion.RegionHashes = baseline.regionHashes
+		latestPageVersion.SimHash = formatSimHash(baseline.simHash)
+		latestPageVersion.NormalizationID = baseline.schemeID
+	}
+
+	if latestPageVersion.Hash != fingerprint.hash {
+		newPageVersion := diffTracker.createPageVersion(page, latestPageVersion.Version+1, fingerprint)
+		similarity, comparable := versionSimilarity(*latestPageVersion, newPageVersion)
+		if comparable && diffTracker.similarityThreshold > 0 && similarity >= diffTracker.similarityThreshold {
+			log.Printf("Ignoring minor change of url=%s, similarity %.3f", url, similarity)
+			latestPageVersion.LastSeen = checkedAt
+			latestPageVersion.LastChecked = checkedAt
+			return pageUnchanged, pageVersions, diffTracker.storePageVersionsInDatabase(url, pageVersions, false)
+		}
+		if comparable {
+			newPageVersion.Similarity = similarity
+		}
+
+		if fingerprint.regionHashes != nil {
+			newPageVersion.ChangedRegions = changedRegions(latestPageVersion.RegionHashes, fingerprint.regionHashes)
+			log.Printf("Watched regions of url=%s changed: %s", url, strings.Join(newPageVersion.ChangedRegions, ", "))
+		}
+		if err := diffTracker.writeHtmlToFileStorage(newPageVersion, page.HTML); err != nil {
+			return pageFailed, pageVersions, err
+		}
+		if path.Dir(latestPageVersion.FilePath) != path.Dir(newPageVersion.FilePath) {
+			// The URL was stored under a path of an older encoding so far
+			diffTracker.writeUrlIndex(url)
+		}
+		diffTracker.storeVersionDiff(url, *latestPageVersion, &newPageVersion, page.HTML)
+
+		latestPageVersion.LastChecked = checkedAt
+		pageVersions = append(pageVersions, newPageVersion)
+		return pageChanged, pageVersions, diffTracker.storePageVersionsInDatabase(url, pageVersions, true)
+	}
+
+	if latestPageVersion.SimHash == "" {
+		// Versions stored before similarity fingerprints existed get one once they are seen again
+		latestPageVersion.SimHash = formatSimHash(fingerprint.simHash)
+	}
+	latestPageVersion.LastSeen = checkedAt
+	latestPageVersion.LastChecked = checkedAt
+	return pageUnchanged, pageVersions, diffTracker.storePageVersionsInDatabase(url, pageVersions, false)
+}
+
+func (diffTracker *DifferenceTracker) readPageVersions(url string) ([]PageVersion, error) {
+	versionsBytes, err := diffTracker.database.Read(url)
+	if err != nil {
+		handleError(err, "Error reading versions from database for url="+url)
+		return nil, err
+	}
+
+	pageVersions, err := PageVersionsFromJson(versionsBytes)
+	if err != nil {
+		handleError(err, "Error parsing page versions from JSON, bytes="+string(versionsBytes))
+		return nil, err
+	}
+	return pageVersions, nil
+}
+
+// markGone flags the latest version of url as gone, unless it already is.
+func (diffTracker *DifferenceTracker) markGone(url string, pageVersions []PageVersion, reason string, statusCode int) error {
+	latestPageVersion := &pageVersions[len(pageVersions)-1]
+	if latestPageVersion.Gone {
+		return nil
+	}
+
+	log.Printf("Page is gone (%s), url=%s", reason, url)
+	latestPageVersion.Gone = true
+	if err := diffTracker.storePageVersionsInDatabase(url, pageVersions, false); err != nil {
+		return err
+	}
+
+	diffTracker.recordLifecycleEvent(url, pageVersions, ChangeEvent{
+		Type:       PageGoneEvent,
+		Reason:     reason,
+		StatusCode: statusCode,
+		DetectedAt: diffTracker.now().UTC(),
+	})
+	return nil
+}
+
+// markUnseenPagesGone marks every known page within the domains of the run's seeds that the run didn't reach as gone.
+// Seeds that couldn't be fetched are skipped, a site that is down hasn't lost its pages.
+func (diffTracker *DifferenceTracker) markUnseenPagesGone() {
+	diffTracker.runRecorder.mu.Lock()
+	run := diffTracker.runRecorder.run
+	diffTracker.runRecorder.mu.Unlock()
+	if run == nil {
+		return
+	}
+	seeds := diffTracker.runRecorder.reachedSeeds()
+
+	// Only the keys of the seeds' domains are scanned; the prefixes also match longer host names, hence the filter
+	for _, prefix := range seedDomainPrefixes(seeds) {
+		err := db.ForEachKey(diffTracker.database, prefix, func(url string) error {
+			if !isPageKey(url) || !inSeedDomains(seeds, url) || diffTracker.runRecorder.wasSeen(url) {
+				return nil
+			}
+
+			pageVersions, err := diffTracker.readPageVersions(url)
+			if err != nil || len(pageVersions) == 0 {
+				return nil
+			}
+			if err := diffTracker.markGone(url, pageVersions, GoneNotSeen, 0); err != nil {
+				handleError(err, "Error marking page as gone, url="+url)
+			}
+			return nil
+		})
+		if err != nil {
+			handleError(err, "Error scanning pages to detect gone pages, prefix="+prefix)
+		}
+	}
+}
+
+// recordLifecycleEvent completes event from the latest version, appends it to the lifecycle of the URL,
+// counts it in the crawl run and notifies about it.
+func (diffTracker *DifferenceTracker) recordLifecycleEvent(url string, pageVersions []PageVersion, event ChangeEvent) {
+	latest := pageVersions[len(pageVersions)-1]
+	event.URL = url
+	event.CrawlRunID = diffTracker.crawlRunID
+	if event.Type == PageGoneEvent {
+		event.OldVersion = latest.Version
+		event.OldHash = latest.Hash
+	} else {
+		event.NewVersion = latest.Version
+		event.NewHash = latest.Hash
+	}
+
+	if err := appendLifecycleEvent(diffTracker.database, event); err != nil {
+		handleError(err, "Error storing lifecycle event, url="+event.URL)
+	}
+	diffTracker.runRecorder.recordLifecycle(event.URL, event.Type)
+	diffTracker.notify(event)
+}
+
+func (diffTracker *DifferenceTracker) storeNewContent(page *fetch.FetchResult, fingerprint contentFingerprint) error {
+	newPageVersion := diffTracker.createPageVersion(page, 1, fingerprint)
+	if err := diffTracker.writeHtmlToFileStorage(newPageVersion, page.HTML); err != nil {
+		return err
+	}
+	diffTracker.writeUrlIndex(page.URL)
+
+	pageVersions := []PageVersion{newPageVersion}
+	return diffTracker.storePageVersionsInDatabase(page.URL, pageVersions, true)
+}
+
+func (diffTracker *DifferenceTracker) createPageVersion(page *fetch.FetchResult, version int, fingerprint contentFingerprint) PageVersion {
+	fetchedAt := diffTracker.fetchTime(page)
+	return PageVersion{
+		Hash:            fingerprint.hash,
+		RegionHashes:    fingerprint.regionHashes,
+		SimHash:         formatSimHash(fingerprint.simHash),
+		FilePath:        ConstructFilePath(page.URL, version),
+		Version:         version,
+		NormalizationID: fingerprint.schemeID,
+		FirstSeen:       fetchedAt,
+		LastSeen:        fetchedAt,
+		LastChecked:     fetchedAt,
+		StatusCode:      page.StatusCode,
+		ContentLength:   int64(len(page.HTML)),
+		ContentType:     page.ContentType,
+		FetchDuration:   page.Duration,
+		CrawlRunID:      diffTracker.crawlRunID,
+	}
+}
+
+// fetchTime is when the page was downloaded, or now if the fetcher didn't say.
+func (diffTracker *DifferenceTracker) fetchTime(page *fetch.FetchResult) time.Time {
+	if page.FetchedAt.IsZero() {
+		return diffTracker.now().UTC()
+	}
+	return page.FetchedAt.UTC()
+}
+
+func (diffTracker *DifferenceTracker) writeHtmlToFileStorage(pageVersion PageVersion, htmlContent string) error {
+	return diffTracker.writeToFileStorage(pageVersion.FilePath, []byte(htmlContent))
+}
+
+// writeUrlIndex stores url next to its versions. Without it only URLs with short paths can be told from
+// their directory, so a failure is only logged.
+func (diffTracker *DifferenceTracker) writeUrlIndex(url string) {
+	diffTracker.writeToFileStorage(constructIndexPath(url), []byte(url))
+}
+
+func (diffTracker *DifferenceTracker) writeToFileStorage(path string, content []byte) error {
+	writer, err := diffTracker.fileStorage.NewWriter(path)
+	if err != nil {
+		handleError(err, "Error opening file for writing, path="+path)
+		return err
+	}
+
+	if _, err := writer.Write(content); err != nil {
+		writer.Close()
+		handleError(err, "Error writing content to file, file="+path)
+		return err
+	}
+
+	if err := writer.Close(); err != nil {
+		handleError(err, "Error storing file, path="+path)
+		return err
+	}
+	return nil
+}
+
+// storeVersionDiff computes how the new version differs from the previous one and stores the diff next to it.
+// A failure only loses the stored diff, it can still be computed later from both versions.
+func (diffTracker *DifferenceTracker) storeVersionDiff(url string, previous PageVersion, newPageVersion *PageVersion, htmlContent string) {
+	previousContent, err := diffTracker.fileStorage.Read(previous.FilePath)
+	if err != nil {
+		handleError(err, "Error reading previous version for diff, path="+previous.FilePath)
+		return
+	}
+
+	versionDiff, err := ComputeVersionDiff(url, previous.Version, string(previousContent), newPageVersion.Version, htmlContent)
+	if err != nil {
+		handleError(err, "Error computing diff of url="+url)
+		return
+	}
+
+	bytes, err := VersionDiffToJson(versionDiff)
+	if err != nil {
+		handleError(err, "Error serializing diff of url="+url)
+		return
+	}
+
+	diffPath := ConstructDiffFilePath(url, newPageVersion.Version)
+	if err := diffTracker.writeToFileStorage(diffPath, bytes); err != nil {
+		return
+	}
+
+	newPageVersion.DiffPath = diffPath
+	newPageVersion.DiffSummary = &versionDiff.Summary
+}
+
+// storePageVersionsInDatabase saves the version list of url; newVersion tells whether its last version was just created.
+func (diffTracker *DifferenceTracker) storePageVersionsInDatabase(url string, pageVersions []PageVersion, newVersion bool) error {
+	bytes, err := PageVersionsToJson(pageVersions)
+	if err != nil {
+		handleError(err, "Error serializing page versions to JSON")
+		return err
+	}
+
+	if err := diffTracker.database.Store(url, bytes); err != nil {
+		handleError(err, "Error storing page versions in database")
+		return err
+	}
+
+	if newVersion {
+		diffTracker.notify(newChangeEvent(url, pageVersions))
+	}
+	return nil
+}
+
+// notify passes the event to every notifier. Failing notifications don't fail the crawl,
+// notifiers are expected to retry or record undelivered events themselves.
+func (diffTracker *DifferenceTracker) notify(event ChangeEvent) {
+	for _, notifier := range diffTracker.notifiers {
+		if err := notifier.Notify(event); err != nil {
+			handleError(err, "Error notifying about a change of url="+event.URL)
+		}
+	}
+}
+
+// contentFingerprint is what change detection compares: the page hash, the hashes of watched
+// regions if a watch rule applies, and an ID of how both were computed. The SimHash of the same content
+// tells how much it changed.
+type contentFingerprint struct {
+	hash         string
+	regionHashes map[string]string
+	simHash      uint64
+	schemeID     string
+}
+
+func (diffTracker *DifferenceTracker) fingerprint(page *fetch.FetchResult) contentFingerprint {
+	schemeID := ""
+	if diffTracker.normalizer != nil {
+		schemeID = diffTracker.normalizer.ID()
+	}
+
+	if rule := diffTracker.watchRules.match(page.URL); rule != nil {
+		regionHashes, regionsHtml, err := rule.regionHashes(page.HTML, diffTracker.normalizer)
+		if err == nil {
+			return contentFingerprint{
+				hash:         combinedRegionHash(regionHashes),
+				regionHashes: regionHashes,
+				simHash:      SimHash(ExtractText(regionsHtml)),
+				schemeID:     schemeID + "/" + rule.id,
+			}
+		}
+		handleError(err, "Error extracting watched regions, hashing the whole page, url="+page.URL)
+	}
+
+	htmlContent := page.HTML
+	if diffTracker.normalizer != nil {
+		htmlContent = diffTracker.normalizer.Normalize(htmlContent)
+	}
+	return contentFingerprint{hash: getMD5Hash(htmlContent), simHash: SimHash(ExtractText(htmlContent)), schemeID: schemeID}
+}
+
+func getMD5Hash(text string) string {
+	hash := md5.Sum([]byte(text))
+	return hex.EncodeToString(hash[:])
+}
+
+func handleError(err error, message string) {
+	log.Printf("ERROR: %s: %v", message, err)
+}
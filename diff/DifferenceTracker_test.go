@@ -0,0 +1,437 @@
+package diff
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"goCrawler/db"
+	"goCrawler/fetch"
+	"goCrawler/storage"
+	"goCrawler/urlutil"
+)
+
+type MockIStorage struct {
+	mock.Mock
+}
+
+func (m *MockIStorage) NewWriter(filename string) (io.WriteCloser, error) {
+	args := m.Called(filename)
+	if err := args.Error(0); err != nil {
+		return nil, err
+	}
+	return &mockWriter{storage: m}, nil
+}
+
+// mockWriter records its calls on the storage mock, so tests expect "Write" and "Close" there.
+type mockWriter struct {
+	storage *MockIStorage
+}
+
+func (w *mockWriter) Write(bytes []byte) (int, error) {
+	args := w.storage.MethodCalled("Write", bytes)
+	return len(bytes), args.Error(0)
+}
+
+func (w *mockWriter) Close() error {
+	args := w.storage.MethodCalled("Close")
+	return args.Error(0)
+}
+
+func (m *MockIStorage) NewReader(filename string) (io.ReadCloser, error) {
+	args := m.Called(filename)
+	reader, _ := args.Get(0).(io.ReadCloser)
+	return reader, args.Error(1)
+}
+
+func (m *MockIStorage) Read(filename string) ([]byte, error) {
+	args := m.Called(filename)
+	content, _ := args.Get(0).([]byte)
+	return content, args.Error(1)
+}
+
+func (m *MockIStorage) Stat(filename string) (storage.FileInfo, error) {
+	args := m.Called(filename)
+	return args.Get(0).(storage.FileInfo), args.Error(1)
+}
+
+func (m *MockIStorage) List(prefix string) ([]string, error) {
+	args := m.Called(prefix)
+	names, _ := args.Get(0).([]string)
+	return names, args.Error(1)
+}
+
+func (m *MockIStorage) Delete(filename string) error {
+	args := m.Called(filename)
+	return args.Error(0)
+}
+
+type MockIDatabase struct {
+	mock.Mock
+}
+
+func (m *MockIDatabase) Store(key string, value []byte) error {
+	args := m.Called(key, value)
+	return args.Error(0)
+}
+
+func (m *MockIDatabase) Read(key string) ([]byte, error) {
+	args := m.Called(key)
+	return args.Get(0).([]byte), args.Error(1)
+}
+
+func (m *MockIDatabase) Delete(key string) error {
+	args := m.Called(key)
+	return args.Error(0)
+}
+
+func (m *MockIDatabase) Exists(key string) (bool, error) {
+	args := m.Called(key)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockIDatabase) ListKeys() ([]string, error) {
+	args := m.Called()
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockIDatabase) Count() (int, error) {
+	args := m.Called()
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockIDatabase) Scan(prefix string, cursor string, limit int) (db.ScanResult, error) {
+	args := m.Called(prefix, cursor, limit)
+	return args.Get(0).(db.ScanResult), args.Error(1)
+}
+
+func (m *MockIDatabase) ScanRange(start string, end string, cursor string, limit int) (db.ScanResult, error) {
+	args := m.Called(start, end, cursor, limit)
+	return args.Get(0).(db.ScanResult), args.Error(1)
+}
+
+var defaultHtmlContent = "<html><body><a href=\"https://www.google.com\">Google</a></body></html>"
+var defaultHtmlContentMd5Hash = "d6165a2f6a47eba8aa611ca6891203a9"
+
+var changedHtmlContent = "<html><body><a href=\"https://www.google.com\">Yahoo</a></body></html>"
+var changedHtmlContentMd5Hash = "2f2180839c2f324971d4f0f98fbf46de"
+
+func fileNameMatchesPattern(version int) interface{} {
+	return func(fileName string) bool {
+		regexPattern := `^.+\/v` + fmt.Sprint(version) + `.html$`
+
+		matched, err := regexp.MatchString(regexPattern, fileName)
+		if err != nil {
+			return false
+		}
+
+		return matched
+	}
+}
+
+// versionsMatch matches serialized page versions by hash, file path and version, ignoring timestamps.
+func versionsMatch(expected ...PageVersion) interface{} {
+	return func(bytes []byte) bool {
+		pageVersions, err := PageVersionsFromJson(bytes)
+		if err != nil || len(pageVersions) != len(expected) {
+			return false
+		}
+
+		for i, pageVersion := range pageVersions {
+			if pageVersion.Hash != expected[i].Hash || pageVersion.FilePath != expected[i].FilePath || pageVersion.Version != expected[i].Version {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// expectUrlIndex expects url to be stored next to the first version of it.
+func expectUrlIndex(storageMock *MockIStorage, url string) {
+	storageMock.On("NewWriter", urlutil.EncodeFilePath(url)+"/"+urlIndexFilename).Return(nil).Once()
+	storageMock.On("Write", []byte(url)).Return(nil).Once()
+	storageMock.On("Close").Return(nil).Once()
+}
+
+var defaultVersion = PageVersion{Hash: defaultHtmlContentMd5Hash, FilePath: "www.google.com/v1.html", Version: 1}
+var changedVersion = PageVersion{Hash: changedHtmlContentMd5Hash, FilePath: "www.google.com/v2.html", Version: 2}
+
+func Test_ShouldStoreUrlVersionsInSeparateDirectories(t *testing.T) {
+	storageMock := new(MockIStorage)
+	databaseMock := new(MockIDatabase)
+
+	sut := NewDifferenceTracker(databaseMock, storageMock)
+
+	storageMock.On("NewWriter", mock.MatchedBy(fileNameMatchesPattern(1))).Return(nil).Once()
+	storageMock.On("Write", []byte(defaultHtmlContent)).Return(nil).Once()
+	storageMock.On("Close").Return(nil).Once()
+	expectUrlIndex(storageMock, "https://www.google.com")
+
+	databaseMock.On("Exists", "https://www.google.com").Return(false, nil).Once()
+	jsonWithSingleVersion := []byte(`[{"Hash":"d6165a2f6a47eba8aa611ca6891203a9","FilePath":"www.google.com/v1.html","Version":1}]`)
+
+	databaseMock.On("Store", "https://www.google.com", mock.MatchedBy(versionsMatch(defaultVersion))).Return(nil).Once()
+
+	sut.HandleContent("https://www.google.com", defaultHtmlContent)
+
+	storageMock.On("NewWriter", mock.MatchedBy(fileNameMatchesPattern(2))).Return(nil)
+	storageMock.On("Write", []byte(changedHtmlContent)).Return(nil)
+	storageMock.On("Close").Return(nil).Once()
+	// Without the previous version no diff is stored next to the new one
+	storageMock.On("Read", "www.google.com/v1.html").Return(nil, fs.ErrNotExist).Once()
+
+	databaseMock.On("Exists", "https://www.google.com").Return(true, nil)
+	databaseMock.On("Read", "https://www.google.com").Return(jsonWithSingleVersion, nil)
+
+	databaseMock.On("Store", "https://www.google.com", mock.MatchedBy(versionsMatch(defaultVersion, changedVersion))).Return(nil)
+
+	sut.HandleContent("https://www.google.com", changedHtmlContent)
+
+	storageMock.AssertNumberOfCalls(t, "Close", 3)
+	storageMock.AssertNumberOfCalls(t, "NewWriter", 3)
+	storageMock.AssertNumberOfCalls(t, "Write", 3)
+	databaseMock.AssertNumberOfCalls(t, "Exists", 2)
+	databaseMock.AssertNumberOfCalls(t, "Read", 1)
+	databaseMock.AssertNumberOfCalls(t, "Store", 2)
+}
+
+func Test_ShouldNotStoreSameVersionTwice(t *testing.T) {
+	storageMock := new(MockIStorage)
+	databaseMock := new(MockIDatabase)
+
+	sut := NewDifferenceTracker(databaseMock, storageMock)
+
+	storageMock.On("NewWriter", mock.MatchedBy(fileNameMatchesPattern(1))).Return(nil).Once()
+	storageMock.On("Write", []byte(defaultHtmlContent)).Return(nil).Once()
+	storageMock.On("Close").Return(nil).Once()
+	expectUrlIndex(storageMock, "https://www.google.com")
+
+	databaseMock.On("Exists", "https://www.google.com").Return(false, nil).Once()
+	jsonWithSingleVersion := []byte(`[{"Hash":"d6165a2f6a47eba8aa611ca6891203a9","FilePath":"www.google.com/v1.html","Version":1}]`)
+
+	databaseMock.On("Store", "https://www.google.com", mock.MatchedBy(versionsMatch(defaultVersion))).Return(nil).Once()
+
+	sut.HandleContent("https://www.google.com", defaultHtmlContent)
+
+	storageMock.On("NewWriter", mock.MatchedBy(fileNameMatchesPattern(2))).Return(nil)
+	storageMock.On("Write", []byte(defaultHtmlContent)).Return(nil)
+	storageMock.On("Close").Return(nil).Once()
+
+	databaseMock.On("Exists", "https://www.google.com").Return(true, nil)
+	databaseMock.On("Read", "https://www.google.com").Return(jsonWithSingleVersion, nil)
+	// Only the last seen timestamps of the existing version are updated
+	databaseMock.On("Store", "https://www.google.com", mock.MatchedBy(versionsMatch(defaultVersion))).Return(nil).Once()
+
+	sut.HandleContent("https://www.google.com", defaultHtmlContent)
+
+	storageMock.AssertNumberOfCalls(t, "Close", 2)
+	storageMock.AssertNumberOfCalls(t, "NewWriter", 2)
+	storageMock.AssertNumberOfCalls(t, "Write", 2)
+	databaseMock.AssertNumberOfCalls(t, "Exists", 2)
+	databaseMock.AssertNumberOfCalls(t, "Read", 1)
+	databaseMock.AssertNumberOfCalls(t, "Store", 2)
+}
+
+func Test_ShouldPanicIfErrorOccursWhenCreatingWriter(t *testing.T) {
+	storageMock := new(MockIStorage)
+	databaseMock := new(MockIDatabase)
+
+	sut := NewDifferenceTracker(databaseMock, storageMock)
+
+	storageMock.On("NewWriter", mock.MatchedBy(fileNameMatchesPattern(1))).Return(fmt.Errorf("error")).Once()
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("The code did not panic")
+		}
+	}()
+
+	sut.HandleContent("https://www.google.com", defaultHtmlContent)
+}
+
+func Test_ShouldStoreSeparateDomains(t *testing.T) {
+	storageMock := new(MockIStorage)
+	databaseMock := new(MockIDatabase)
+
+	sut := NewDifferenceTracker(databaseMock, storageMock)
+
+	storageMock.On("NewWriter", mock.MatchedBy(fileNameMatchesPattern(1))).Return(nil).Once()
+	storageMock.On("Write", []byte(defaultHtmlContent)).Return(nil).Once()
+	storageMock.On("Close").Return(nil).Once()
+	expectUrlIndex(storageMock, "https://www.google.com")
+
+	databaseMock.On("Exists", "https://www.google.com").Return(false, nil).Once()
+	databaseMock.On("Store", "https://www.google.com", mock.MatchedBy(versionsMatch(defaultVersion))).Return(nil).Once()
+
+	sut.HandleContent("https://www.google.com", defaultHtmlContent)
+
+	storageMock.On("NewWriter", mock.MatchedBy(fileNameMatchesPattern(1))).Return(nil)
+	storageMock.On("Write", []byte(changedHtmlContent)).Return(nil)
+	storageMock.On("Close").Return(nil).Once()
+
+	databaseMock.On("Exists", "https://www.google2.com").Return(false, nil)
+	expectUrlIndex(storageMock, "https://www.google2.com")
+
+	google2Version := PageVersion{Hash: changedHtmlContentMd5Hash, FilePath: "www.google2.com/v1.html", Version: 1}
+	databaseMock.On("Store", "https://www.google2.com", mock.MatchedBy(versionsMatch(google2Version))).Return(nil)
+
+	sut.HandleContent("https://www.google2.com", changedHtmlContent)
+
+	storageMock.AssertNumberOfCalls(t, "Close", 4)
+	storageMock.AssertNumberOfCalls(t, "NewWriter", 4)
+	storageMock.AssertNumberOfCalls(t, "Write", 4)
+	databaseMock.AssertNumberOfCalls(t, "Exists", 2)
+	databaseMock.AssertNumberOfCalls(t, "Read", 0)
+	databaseMock.AssertNumberOfCalls(t, "Store", 2)
+}
+
+func Test_ShouldRecordTimestampsAndFetchMetadata(t *testing.T) {
+	database := db.NewInMemoryDatabase()
+	storageMock := new(MockIStorage)
+	storageMock.On("NewWriter", mock.Anything).Return(nil)
+	storageMock.On("Write", mock.Anything).Return(nil)
+	storageMock.On("Close").Return(nil)
+
+	sut := NewDifferenceTracker(database, storageMock)
+	run := sut.StartCrawlRun([]string{"https://www.google.com"}, nil)
+
+	firstFetch := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	page := &fetch.FetchResult{
+		URL:         "https://www.google.com",
+		HTML:        defaultHtmlContent,
+		StatusCode:  200,
+		ContentType: "text/html",
+		FetchedAt:   firstFetch,
+		Duration:    150 * time.Millisecond,
+	}
+	require.NoError(t, sut.HandlePage(page))
+
+	secondFetch := firstFetch.Add(time.Hour)
+	sut.now = func() time.Time { return secondFetch }
+	require.NoError(t, sut.HandleContent("https://www.google.com", defaultHtmlContent))
+
+	pageVersions, err := LoadPageVersions(database, "https://www.google.com")
+	require.NoError(t, err)
+	require.Len(t, pageVersions, 1)
+
+	version := pageVersions[0]
+	assert.Equal(t, firstFetch, version.FirstSeen)
+	assert.Equal(t, secondFetch, version.LastSeen)
+	assert.Equal(t, secondFetch, version.LastChecked)
+	assert.Equal(t, 200, version.StatusCode)
+	assert.Equal(t, "text/html", version.ContentType)
+	assert.Equal(t, int64(len(defaultHtmlContent)), version.ContentLength)
+	assert.Equal(t, 150*time.Millisecond, version.FetchDuration)
+	assert.Equal(t, run.ID, version.CrawlRunID)
+}
+
+type recordingNotifier struct {
+	events       []ChangeEvent
+	finishedRuns []*CrawlRun
+}
+
+func (r *recordingNotifier) RunFinished(run *CrawlRun) error {
+	r.finishedRuns = append(r.finishedRuns, run)
+	return nil
+}
+
+func (r *recordingNotifier) Notify(event ChangeEvent) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+func Test_ShouldNotifyAboutNewPagesAndVersions(t *testing.T) {
+	database := db.NewInMemoryDatabase()
+	storageMock := new(MockIStorage)
+	storageMock.On("NewWriter", mock.Anything).Return(nil)
+	storageMock.On("Write", mock.Anything).Return(nil)
+	storageMock.On("Close").Return(nil)
+	storageMock.On("Read", mock.Anything).Return(nil, fs.ErrNotExist)
+
+	notifier := &recordingNotifier{}
+	sut := NewDifferenceTracker(database, storageMock)
+	sut.AddNotifier(notifier)
+
+	require.NoError(t, sut.HandleContent("https://www.google.com", defaultHtmlContent))
+	require.NoError(t, sut.HandleContent("https://www.google.com", defaultHtmlContent))
+	require.NoError(t, sut.HandleContent("https://www.google.com", changedHtmlContent))
+
+	require.Len(t, notifier.events, 2)
+	assert.Equal(t, NewPageEvent, notifier.events[0].Type)
+	assert.Equal(t, 1, notifier.events[0].NewVersion)
+	assert.Equal(t, defaultHtmlContentMd5Hash, notifier.events[0].NewHash)
+
+	assert.Equal(t, NewVersionEvent, notifier.events[1].Type)
+	assert.Equal(t, "https://www.google.com", notifier.events[1].URL)
+	assert.Equal(t, 1, notifier.events[1].OldVersion)
+	assert.Equal(t, 2, notifier.events[1].NewVersion)
+	assert.Equal(t, defaultHtmlContentMd5Hash, notifier.events[1].OldHash)
+	assert.Equal(t, changedHtmlContentMd5Hash, notifier.events[1].NewHash)
+}
+
+func Test_ShouldTellRunNotifiersWhenCrawlRunFinishes(t *testing.T) {
+	notifier := &recordingNotifier{}
+	sut := NewDifferenceTracker(db.NewInMemoryDatabase(), new(MockIStorage))
+	sut.AddNotifier(notifier)
+
+	sut.StartCrawlRun([]string{"https://www.google.com"}, nil)
+	assert.Empty(t, notifier.finishedRuns)
+
+	run, err := sut.FinishCrawlRun()
+	require.NoError(t, err)
+	assert.Equal(t, []*CrawlRun{run}, notifier.finishedRuns)
+}
+
+func Test_ShouldReadVersionsWithoutTimestamps(t *testing.T) {
+	pageVersions, err := PageVersionsFromJson([]byte(`[{"Hash":"d6165a2f6a47eba8aa611ca6891203a9","FilePath":"www.google.com/v1.html","Version":1}]`))
+	require.NoError(t, err)
+
+	assert.Equal(t, []PageVersion{defaultVersion}, pageVersions)
+}
+
+func Test_ShouldStoreUrlIndexNextToVersions(t *testing.T) {
+	database := db.NewInMemoryDatabase()
+	fileStorage := storage.NewFileStorage(t.TempDir())
+	sut := NewDifferenceTracker(database, fileStorage)
+
+	longUrl := "https://www.google.com/search?q=" + strings.Repeat("long+query+", 40)
+	require.NoError(t, sut.HandleContent(longUrl, defaultHtmlContent))
+
+	pageVersions, err := LoadPageVersions(database, longUrl)
+	require.NoError(t, err)
+	require.Len(t, pageVersions, 1)
+	url, err := UrlOfFile(fileStorage, pageVersions[0].FilePath)
+	require.NoError(t, err)
+	assert.Equal(t, longUrl, url)
+}
+
+func Test_ShouldStoreUrlIndexWhenMovingFromOlderPathEncoding(t *testing.T) {
+	database := db.NewInMemoryDatabase()
+	fileStorage := storage.NewFileStorage(t.TempDir())
+	sut := NewDifferenceTracker(database, fileStorage)
+
+	writer, err := fileStorage.NewWriter("google.com/v1.html")
+	require.NoError(t, err)
+	_, err = writer.Write([]byte(defaultHtmlContent))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	require.NoError(t, database.Store("https://www.google.com", []byte(`[{"Hash":"d6165a2f6a47eba8aa611ca6891203a9","FilePath":"google.com/v1.html","Version":1}]`)))
+
+	require.NoError(t, sut.HandleContent("https://www.google.com", changedHtmlContent))
+
+	pageVersions, err := LoadPageVersions(database, "https://www.google.com")
+	require.NoError(t, err)
+	require.Len(t, pageVersions, 2)
+	assert.Equal(t, "www.google.com/v2.html", pageVersions[1].FilePath)
+	index, err := fileStorage.Read("www.google.com/" + urlIndexFilename)
+	require.NoError(t, err)
+	assert.Equal(t, "https://www.google.com", string(index))
+}
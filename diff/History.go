@@ -0,0 +1,275 @@
+package diff
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pmezard/go-difflib/difflib"
+
+	"goCrawler/db"
+	"goCrawler/storage"
+)
+
+// PageHistory is the exported form of everything known about a single URL.
+type PageHistory struct {
+	URL       string        `json:"url"`
+	Versions  []PageVersion `json:"versions"`
+	Lifecycle []ChangeEvent `json:"lifecycle,omitempty"`
+}
+
+func LoadPageVersions(database db.IDatabase, url string) ([]PageVersion, error) {
+	exists, err := database.Exists(url)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("no versions stored for url=%s", url)
+	}
+
+	versionsBytes, err := database.Read(url)
+	if err != nil {
+		return nil, err
+	}
+
+	return PageVersionsFromJson(versionsBytes)
+}
+
+func FindPageVersion(pageVersions []PageVersion, version int) (PageVersion, error) {
+	for _, pageVersion := range pageVersions {
+		if pageVersion.Version == version {
+			return pageVersion, nil
+		}
+	}
+	return PageVersion{}, fmt.Errorf("version %d not found", version)
+}
+
+// PrintHistory lists all stored versions of url, oldest first, followed by when it was gone and returned.
+func PrintHistory(out io.Writer, database db.IDatabase, fileStorage storage.IStorage, url string) error {
+	pageVersions, err := LoadPageVersions(database, url)
+	if err != nil {
+		return err
+	}
+
+	writer := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "VERSION\tHASH\tFIRST SEEN\tLAST SEEN\tSTATUS\tSIMILARITY\tPATH\tCHANGED REGIONS")
+	for _, pageVersion := range pageVersions {
+		firstSeen := pageVersion.FirstSeen
+		if firstSeen.IsZero() {
+			// Versions stored before timestamps were recorded only have the file modification time
+			if info, err := fileStorage.Stat(pageVersion.FilePath); err == nil {
+				firstSeen = info.ModTime
+			}
+		}
+
+		status := "-"
+		if pageVersion.StatusCode != 0 {
+			status = fmt.Sprint(pageVersion.StatusCode)
+		}
+
+		similarity := "-"
+		if pageVersion.Similarity != 0 {
+			similarity = fmt.Sprintf("%.2f", pageVersion.Similarity)
+		}
+
+		regions := "-"
+		if len(pageVersion.ChangedRegions) > 0 {
+			regions = strings.Join(pageVersion.ChangedRegions, ", ")
+		}
+
+		if pageVersion.Gone {
+			status += " (gone)"
+		}
+
+		fmt.Fprintf(writer, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", pageVersion.Version, pageVersion.Hash,
+			formatTimestamp(firstSeen), formatTimestamp(pageVersion.LastSeen), status, similarity, pageVersion.FilePath, regions)
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+
+	lifecycleEvents, err := LoadLifecycleEvents(database, url)
+	if err != nil {
+		return err
+	}
+	printLifecycleEvents(out, lifecycleEvents)
+	return nil
+}
+
+func formatTimestamp(timestamp time.Time) string {
+	if timestamp.IsZero() {
+		return "-"
+	}
+	return timestamp.Format(time.RFC3339)
+}
+
+// ShowVersion writes the stored content of the given version of url; version 0 means the latest one.
+func ShowVersion(out io.Writer, database db.IDatabase, fileStorage storage.IStorage, url string, version int) error {
+	pageVersions, err := LoadPageVersions(database, url)
+	if err != nil {
+		return err
+	}
+
+	pageVersion := pageVersions[len(pageVersions)-1]
+	if version != 0 {
+		if pageVersion, err = FindPageVersion(pageVersions, version); err != nil {
+			return err
+		}
+	}
+
+	content, err := fileStorage.Read(pageVersion.FilePath)
+	if err != nil {
+		return err
+	}
+
+	_, err = out.Write(content)
+	return err
+}
+
+// DiffVersions writes a unified diff between two stored versions of url.
+func DiffVersions(out io.Writer, database db.IDatabase, fileStorage storage.IStorage, url string, fromVersion, toVersion int) error {
+	pageVersions, err := LoadPageVersions(database, url)
+	if err != nil {
+		return err
+	}
+
+	from, err := FindPageVersion(pageVersions, fromVersion)
+	if err != nil {
+		return err
+	}
+	to, err := FindPageVersion(pageVersions, toVersion)
+	if err != nil {
+		return err
+	}
+
+	fromContent, err := fileStorage.Read(from.FilePath)
+	if err != nil {
+		return err
+	}
+	toContent, err := fileStorage.Read(to.FilePath)
+	if err != nil {
+		return err
+	}
+
+	return difflib.WriteUnifiedDiff(out, difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(fromContent)),
+		B:        difflib.SplitLines(string(toContent)),
+		FromFile: fmt.Sprintf("%s@v%d", url, from.Version),
+		ToFile:   fmt.Sprintf("%s@v%d", url, to.Version),
+		Context:  3,
+	})
+}
+
+// LoadAllPageHistories returns the version lists of every URL in the database, sorted by URL.
+// Other records kept in the database, like crawl runs, are skipped; lifecycle events are attached to their page.
+func LoadAllPageHistories(database db.IDatabase) ([]PageHistory, error) {
+	// Only pages that were gone have lifecycle events; knowing which saves a lookup for every other page
+	hasLifecycle := make(map[string]bool)
+	err := db.ForEachKey(database, lifecycleKeyPrefix, func(key string) error {
+		hasLifecycle[strings.TrimPrefix(key, lifecycleKeyPrefix)] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	histories := make([]PageHistory, 0)
+	// Scans return the keys sorted, so the histories are too
+	err = db.ForEachKey(database, "", func(key string) error {
+		if !isPageKey(key) {
+			return nil
+		}
+
+		pageVersions, err := LoadPageVersions(database, key)
+		if err != nil {
+			return fmt.Errorf("loading versions of %s: %w", key, err)
+		}
+		history := PageHistory{URL: key, Versions: pageVersions}
+		if hasLifecycle[key] {
+			if history.Lifecycle, err = LoadLifecycleEvents(database, key); err != nil {
+				return fmt.Errorf("loading lifecycle of %s: %w", key, err)
+			}
+		}
+		histories = append(histories, history)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return histories, nil
+}
+
+func ExportJson(out io.Writer, database db.IDatabase) error {
+	histories, err := LoadAllPageHistories(database)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(histories)
+}
+
+// ExportArchive writes a gzipped tarball with index.json describing all URLs and
+// every stored version file under versions/, using the same relative paths as the storage.
+func ExportArchive(out io.Writer, database db.IDatabase, fileStorage storage.IStorage) error {
+	histories, err := LoadAllPageHistories(database)
+	if err != nil {
+		return err
+	}
+
+	gzipWriter := gzip.NewWriter(out)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	index, err := json.MarshalIndent(histories, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeTarFile(tarWriter, "index.json", index, time.Now()); err != nil {
+		return err
+	}
+
+	for _, history := range histories {
+		for _, pageVersion := range history.Versions {
+			content, err := fileStorage.Read(pageVersion.FilePath)
+			if err != nil {
+				handleError(err, "Error reading version file, path="+pageVersion.FilePath)
+				continue
+			}
+
+			modTime := time.Now()
+			if info, err := fileStorage.Stat(pageVersion.FilePath); err == nil {
+				modTime = info.ModTime
+			}
+
+			name := "versions/" + strings.TrimPrefix(pageVersion.FilePath, "/")
+			if err := writeTarFile(tarWriter, name, content, modTime); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return err
+	}
+	return gzipWriter.Close()
+}
+
+func writeTarFile(tarWriter *tar.Writer, name string, content []byte, modTime time.Time) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(content)),
+		ModTime: modTime,
+	}
+	if err := tarWriter.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := tarWriter.Write(content)
+	return err
+}
@@ -0,0 +1,146 @@
+package diff
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"goCrawler/db"
+	"goCrawler/storage"
+)
+
+func newHistoryFixture(t *testing.T) (*db.InMemoryDatabase, *storage.FileStorage) {
+	database := db.NewInMemoryDatabase()
+	fileStorage := storage.NewFileStorage(t.TempDir())
+	diffTracker := NewDifferenceTracker(database, fileStorage)
+
+	require.NoError(t, diffTracker.HandleContent("https://www.google.com", defaultHtmlContent))
+	require.NoError(t, diffTracker.HandleContent("https://www.google.com", changedHtmlContent))
+	require.NoError(t, diffTracker.HandleContent("https://www.google.com/kontakty", defaultHtmlContent))
+
+	return database, fileStorage
+}
+
+func TestPrintHistory(t *testing.T) {
+	database, fileStorage := newHistoryFixture(t)
+
+	var out bytes.Buffer
+	require.NoError(t, PrintHistory(&out, database, fileStorage, "https://www.google.com"))
+
+	assert.Contains(t, out.String(), "VERSION")
+	assert.Contains(t, out.String(), defaultHtmlContentMd5Hash)
+	assert.Contains(t, out.String(), changedHtmlContentMd5Hash)
+	assert.Contains(t, out.String(), "google.com/v2.html")
+}
+
+func TestPrintHistory_UnknownUrl(t *testing.T) {
+	database, fileStorage := newHistoryFixture(t)
+
+	err := PrintHistory(io.Discard, database, fileStorage, "https://www.yahoo.com")
+	assert.ErrorContains(t, err, "no versions stored")
+}
+
+func TestShowVersion(t *testing.T) {
+	database, fileStorage := newHistoryFixture(t)
+
+	var latest bytes.Buffer
+	require.NoError(t, ShowVersion(&latest, database, fileStorage, "https://www.google.com", 0))
+	assert.Equal(t, changedHtmlContent, latest.String())
+
+	var first bytes.Buffer
+	require.NoError(t, ShowVersion(&first, database, fileStorage, "https://www.google.com", 1))
+	assert.Equal(t, defaultHtmlContent, first.String())
+
+	assert.ErrorContains(t, ShowVersion(io.Discard, database, fileStorage, "https://www.google.com", 3), "version 3 not found")
+}
+
+func TestDiffVersions(t *testing.T) {
+	database, fileStorage := newHistoryFixture(t)
+
+	var out bytes.Buffer
+	require.NoError(t, DiffVersions(&out, database, fileStorage, "https://www.google.com", 1, 2))
+
+	assert.Contains(t, out.String(), "--- https://www.google.com@v1")
+	assert.Contains(t, out.String(), "+++ https://www.google.com@v2")
+	assert.Contains(t, out.String(), "-"+defaultHtmlContent)
+	assert.Contains(t, out.String(), "+"+changedHtmlContent)
+}
+
+func TestExportJson(t *testing.T) {
+	database, _ := newHistoryFixture(t)
+
+	var out bytes.Buffer
+	require.NoError(t, ExportJson(&out, database))
+
+	var histories []PageHistory
+	require.NoError(t, json.Unmarshal(out.Bytes(), &histories))
+	require.Len(t, histories, 2)
+	assert.Equal(t, "https://www.google.com", histories[0].URL)
+	assert.Len(t, histories[0].Versions, 2)
+	assert.Equal(t, "https://www.google.com/kontakty", histories[1].URL)
+}
+
+// lookupCountingDatabase counts the Exists and Read calls per key.
+type lookupCountingDatabase struct {
+	db.IDatabase
+	lookups map[string]int
+}
+
+func (database *lookupCountingDatabase) Exists(key string) (bool, error) {
+	database.lookups[key]++
+	return database.IDatabase.Exists(key)
+}
+
+func (database *lookupCountingDatabase) Read(key string) ([]byte, error) {
+	database.lookups[key]++
+	return database.IDatabase.Read(key)
+}
+
+func TestLoadAllPageHistories_LooksUpOnlyExistingLifecycles(t *testing.T) {
+	inMemory, _ := newHistoryFixture(t)
+	require.NoError(t, appendLifecycleEvent(inMemory, ChangeEvent{URL: "https://www.google.com/kontakty", Type: PageGoneEvent}))
+	database := &lookupCountingDatabase{IDatabase: inMemory, lookups: map[string]int{}}
+
+	histories, err := LoadAllPageHistories(database)
+
+	require.NoError(t, err)
+	require.Len(t, histories, 2)
+	assert.Empty(t, histories[0].Lifecycle)
+	assert.Len(t, histories[1].Lifecycle, 1)
+	assert.Zero(t, database.lookups[lifecycleKeyPrefix+"https://www.google.com"])
+	assert.NotZero(t, database.lookups[lifecycleKeyPrefix+"https://www.google.com/kontakty"])
+}
+
+func TestExportArchive(t *testing.T) {
+	database, fileStorage := newHistoryFixture(t)
+
+	var out bytes.Buffer
+	require.NoError(t, ExportArchive(&out, database, fileStorage))
+
+	gzipReader, err := gzip.NewReader(&out)
+	require.NoError(t, err)
+	tarReader := tar.NewReader(gzipReader)
+
+	files := map[string]string{}
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		content, err := io.ReadAll(tarReader)
+		require.NoError(t, err)
+		files[header.Name] = string(content)
+	}
+
+	assert.Contains(t, files, "index.json")
+	assert.Equal(t, defaultHtmlContent, files["versions/www.google.com/v1.html"])
+	assert.Equal(t, changedHtmlContent, files["versions/www.google.com/v2.html"])
+	assert.Equal(t, defaultHtmlContent, files["versions/www.google.com/kontakty/v1.html"])
+}
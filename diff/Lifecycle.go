@@ -0,0 +1,129 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"goCrawler/db"
+	"goCrawler/urlutil"
+)
+
+// lifecycleKeyPrefix keeps the gone/returned history of a URL apart from its versions.
+const lifecycleKeyPrefix = "lifecycle:"
+
+// Reasons a page is considered gone.
+const (
+	// GoneNotFound means the server answered 404 Not Found or 410 Gone.
+	GoneNotFound = "not_found"
+	// GoneNotSeen means a crawl of the page's seed finished without reaching the page.
+	GoneNotSeen = "not_seen"
+)
+
+func IsLifecycleKey(key string) bool {
+	return strings.HasPrefix(key, lifecycleKeyPrefix)
+}
+
+// isPageKey tells page version lists apart from the other records kept in the database.
+func isPageKey(key string) bool {
+	return !IsCrawlRunKey(key) && !IsLifecycleKey(key)
+}
+
+func isGoneStatus(statusCode int) bool {
+	return statusCode == http.StatusNotFound || statusCode == http.StatusGone
+}
+
+// LoadLifecycleEvents returns the gone and returned events of url, oldest first.
+func LoadLifecycleEvents(database db.IDatabase, url string) ([]ChangeEvent, error) {
+	exists, err := database.Exists(lifecycleKeyPrefix + url)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return []ChangeEvent{}, nil
+	}
+
+	bytes, err := database.Read(lifecycleKeyPrefix + url)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []ChangeEvent
+	if err := json.Unmarshal(bytes, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func appendLifecycleEvent(database db.IDatabase, event ChangeEvent) error {
+	events, err := LoadLifecycleEvents(database, event.URL)
+	if err != nil {
+		return err
+	}
+
+	bytes, err := json.Marshal(append(events, event))
+	if err != nil {
+		return err
+	}
+	return database.Store(lifecycleKeyPrefix+event.URL, bytes)
+}
+
+// inSeedDomains tells whether a crawl of seeds would reach url; crawls never leave the domain of their seed.
+func inSeedDomains(seeds []string, url string) bool {
+	domain := urlutil.NormalizeDomain(url)
+	for _, seed := range seeds {
+		if urlutil.NormalizeDomain(seed) == domain {
+			return true
+		}
+	}
+	return false
+}
+
+// seedDomainPrefixes returns the key prefixes of the pages inSeedDomains accepts for seeds: both schemes, with
+// and without "www.". Prefixes covered by a shorter one are left out, so no key is matched twice.
+func seedDomainPrefixes(seeds []string) []string {
+	var prefixes []string
+	for _, seed := range seeds {
+		domain := urlutil.NormalizeDomain(seed)
+		if domain == "" {
+			continue
+		}
+		for _, scheme := range []string{"http://", "https://"} {
+			prefixes = append(prefixes, scheme+domain, scheme+"www."+domain)
+		}
+	}
+	sort.Strings(prefixes)
+
+	distinct := make([]string, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		if len(distinct) == 0 || !strings.HasPrefix(prefix, distinct[len(distinct)-1]) {
+			distinct = append(distinct, prefix)
+		}
+	}
+	return distinct
+}
+
+func printLifecycleEvents(out io.Writer, events []ChangeEvent) {
+	if len(events) == 0 {
+		return
+	}
+
+	fmt.Fprintln(out, "\nLifecycle:")
+	for _, event := range events {
+		line := fmt.Sprintf("  %s  %s", formatTimestamp(event.DetectedAt), event.Type)
+		if event.Reason != "" {
+			line += " (" + event.Reason
+			if event.StatusCode != 0 {
+				line += fmt.Sprintf(", HTTP %d", event.StatusCode)
+			}
+			line += ")"
+		}
+		if event.CrawlRunID != "" {
+			line += "  run " + event.CrawlRunID
+		}
+		fmt.Fprintln(out, line)
+	}
+}
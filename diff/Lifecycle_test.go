@@ -0,0 +1,237 @@
+package diff
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"goCrawler/db"
+	"goCrawler/fetch"
+	"goCrawler/storage"
+)
+
+func newLifecycleFixture(t *testing.T) (*db.InMemoryDatabase, *DifferenceTracker, *recordingNotifier) {
+	database := db.NewInMemoryDatabase()
+	sut := NewDifferenceTracker(database, storage.NewFileStorage(t.TempDir()))
+	notifier := &recordingNotifier{}
+	sut.AddNotifier(notifier)
+
+	require.NoError(t, sut.HandleContent("https://www.google.com", defaultHtmlContent))
+	require.NoError(t, sut.HandleContent("https://www.google.com/kontakty", defaultHtmlContent))
+	notifier.events = nil
+	return database, sut, notifier
+}
+
+func TestLifecycle_NotFoundMarksPageGone(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+	}{
+		{"Not found", 404},
+		{"Gone", 410},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database, sut, notifier := newLifecycleFixture(t)
+			sut.StartCrawlRun([]string{"https://www.google.com"}, nil)
+
+			notFound := &fetch.FetchResult{URL: "https://www.google.com/kontakty", HTML: "not found", StatusCode: tt.statusCode}
+			require.NoError(t, sut.HandlePage(notFound))
+			require.NoError(t, sut.HandlePage(notFound))
+			require.NoError(t, sut.HandleContent("https://www.google.com", defaultHtmlContent))
+			run, err := sut.FinishCrawlRun()
+			require.NoError(t, err)
+
+			pageVersions, err := LoadPageVersions(database, "https://www.google.com/kontakty")
+			require.NoError(t, err)
+			require.Len(t, pageVersions, 1, "the error page must not be versioned")
+			assert.True(t, pageVersions[0].Gone)
+
+			events, err := LoadLifecycleEvents(database, "https://www.google.com/kontakty")
+			require.NoError(t, err)
+			require.Len(t, events, 1)
+			assert.Equal(t, PageGoneEvent, events[0].Type)
+			assert.Equal(t, GoneNotFound, events[0].Reason)
+			assert.Equal(t, tt.statusCode, events[0].StatusCode)
+			assert.Equal(t, 1, events[0].OldVersion)
+			assert.Equal(t, defaultHtmlContentMd5Hash, events[0].OldHash)
+			assert.Equal(t, run.ID, events[0].CrawlRunID)
+
+			assert.Equal(t, events, notifier.events)
+			assert.Equal(t, 1, run.GonePages)
+			assert.Equal(t, []string{"https://www.google.com/kontakty"}, run.GoneURLs)
+			assert.Equal(t, 2, run.FailedPages)
+		})
+	}
+}
+
+func TestLifecycle_NotFoundOfUnknownPageIsNotRecorded(t *testing.T) {
+	database, sut, notifier := newLifecycleFixture(t)
+
+	require.NoError(t, sut.HandlePage(&fetch.FetchResult{URL: "https://www.google.com/missing", HTML: "not found", StatusCode: 404}))
+
+	exists, err := database.Exists("https://www.google.com/missing")
+	require.NoError(t, err)
+	assert.False(t, exists)
+	assert.Empty(t, notifier.events)
+}
+
+func TestLifecycle_GonePageReturns(t *testing.T) {
+	database, sut, notifier := newLifecycleFixture(t)
+	require.NoError(t, sut.HandlePage(&fetch.FetchResult{URL: "https://www.google.com/kontakty", StatusCode: 404}))
+
+	sut.StartCrawlRun([]string{"https://www.google.com"}, nil)
+	require.NoError(t, sut.HandleContent("https://www.google.com", defaultHtmlContent))
+	require.NoError(t, sut.HandleContent("https://www.google.com/kontakty", changedHtmlContent))
+	run, err := sut.FinishCrawlRun()
+	require.NoError(t, err)
+
+	pageVersions, err := LoadPageVersions(database, "https://www.google.com/kontakty")
+	require.NoError(t, err)
+	require.Len(t, pageVersions, 2)
+	assert.False(t, pageVersions[0].Gone)
+	assert.False(t, pageVersions[1].Gone)
+
+	events, err := LoadLifecycleEvents(database, "https://www.google.com/kontakty")
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, PageReturnedEvent, events[1].Type)
+	assert.Equal(t, 2, events[1].NewVersion)
+	assert.Equal(t, changedHtmlContentMd5Hash, events[1].NewHash)
+
+	require.Len(t, notifier.events, 3)
+	assert.Equal(t, PageGoneEvent, notifier.events[0].Type)
+	assert.Equal(t, NewVersionEvent, notifier.events[1].Type)
+	assert.Equal(t, PageReturnedEvent, notifier.events[2].Type)
+	assert.Equal(t, []string{"https://www.google.com/kontakty"}, run.ReturnedURLs)
+}
+
+func TestLifecycle_UnseenPagesAreGone(t *testing.T) {
+	database, sut, notifier := newLifecycleFixture(t)
+	require.NoError(t, sut.HandleContent("https://www.yahoo.com", defaultHtmlContent))
+	notifier.events = nil
+
+	sut.StartCrawlRun([]string{"https://google.com"}, nil)
+	require.NoError(t, sut.HandleContent("https://www.google.com", defaultHtmlContent))
+	run, err := sut.FinishCrawlRun()
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"https://www.google.com/kontakty"}, run.GoneURLs, "pages of other domains are not crawled by the run")
+	require.Len(t, notifier.events, 1)
+	assert.Equal(t, GoneNotSeen, notifier.events[0].Reason)
+
+	stored, err := LoadCrawlRun(database, run.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stored.GonePages)
+
+	// Staying unseen doesn't report the page again
+	sut.StartCrawlRun([]string{"https://google.com"}, nil)
+	require.NoError(t, sut.HandleContent("https://google.com/new", defaultHtmlContent))
+	run, err = sut.FinishCrawlRun()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://www.google.com"}, run.GoneURLs)
+}
+
+func TestLifecycle_PagesOfUnreachableSeedsAreNotGone(t *testing.T) {
+	_, sut, notifier := newLifecycleFixture(t)
+	require.NoError(t, sut.HandleContent("https://www.yahoo.com", defaultHtmlContent))
+	require.NoError(t, sut.HandleContent("https://www.yahoo.com/news", defaultHtmlContent))
+	notifier.events = nil
+
+	sut.StartCrawlRun([]string{"https://www.google.com", "https://www.yahoo.com"}, nil)
+	require.NoError(t, sut.HandlePage(&fetch.FetchResult{URL: "https://www.google.com", Err: errors.New("no such host")}))
+	require.NoError(t, sut.HandleContent("https://www.yahoo.com", defaultHtmlContent))
+	run, err := sut.FinishCrawlRun()
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"https://www.yahoo.com/news"}, run.GoneURLs, "the pages of the failed seed are kept")
+	require.Len(t, notifier.events, 1)
+	assert.Equal(t, "https://www.yahoo.com/news", notifier.events[0].URL)
+}
+
+// scanOnlyDatabase fails ListKeys, so tests can check that large databases are only scanned.
+type scanOnlyDatabase struct {
+	db.IDatabase
+}
+
+func (database *scanOnlyDatabase) ListKeys() ([]string, error) {
+	return nil, errors.New("ListKeys must not be used")
+}
+
+func TestLifecycle_UnseenPagesAreFoundByScanningTheSeedDomains(t *testing.T) {
+	database := &scanOnlyDatabase{db.NewInMemoryDatabase()}
+	sut := NewDifferenceTracker(database, storage.NewFileStorage(t.TempDir()))
+	for _, url := range []string{"https://www.google.com/kontakty", "http://google.com/old", "https://google.com.example.org", "https://yahoo.com"} {
+		require.NoError(t, sut.HandleContent(url, defaultHtmlContent))
+	}
+
+	sut.StartCrawlRun([]string{"https://google.com", "https://www.google.com/search"}, nil)
+	require.NoError(t, sut.HandleContent("https://www.google.com/search", defaultHtmlContent))
+	run, err := sut.FinishCrawlRun()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"http://google.com/old", "https://www.google.com/kontakty"}, run.GoneURLs)
+
+	histories, err := LoadAllPageHistories(database)
+	require.NoError(t, err)
+	urls := make([]string, 0, len(histories))
+	for _, history := range histories {
+		urls = append(urls, history.URL)
+	}
+	assert.Equal(t, []string{"http://google.com/old", "https://google.com.example.org", "https://www.google.com/kontakty",
+		"https://www.google.com/search", "https://yahoo.com"}, urls)
+	assert.Len(t, histories[0].Lifecycle, 1)
+	assert.Empty(t, histories[1].Lifecycle)
+}
+
+func TestSeedDomainPrefixes(t *testing.T) {
+	tests := []struct {
+		name     string
+		seeds    []string
+		expected []string
+	}{
+		{"Both schemes with and without www", []string{"https://www.example.com/start"},
+			[]string{"http://example.com", "http://www.example.com", "https://example.com", "https://www.example.com"}},
+		{"Covered prefixes are left out", []string{"https://example.com", "http://example.com.au"},
+			[]string{"http://example.com", "http://www.example.com", "https://example.com", "https://www.example.com"}},
+		{"No seeds", nil, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, seedDomainPrefixes(tt.seeds))
+		})
+	}
+}
+
+func TestLifecycle_UnseenDetectionCanBeDisabled(t *testing.T) {
+	_, sut, notifier := newLifecycleFixture(t)
+	sut.SetDetectUnseenPages(false)
+
+	sut.StartCrawlRun([]string{"https://www.google.com"}, nil)
+	run, err := sut.FinishCrawlRun()
+	require.NoError(t, err)
+
+	assert.Zero(t, run.GonePages)
+	assert.Empty(t, notifier.events)
+}
+
+func TestLifecycle_IsReportedInHistory(t *testing.T) {
+	database, sut, _ := newLifecycleFixture(t)
+	require.NoError(t, sut.HandlePage(&fetch.FetchResult{URL: "https://www.google.com/kontakty", StatusCode: 410}))
+
+	var out bytes.Buffer
+	require.NoError(t, PrintHistory(&out, database, storage.NewFileStorage(t.TempDir()), "https://www.google.com/kontakty"))
+	assert.Contains(t, out.String(), "(gone)")
+	assert.Regexp(t, `Lifecycle:\n  \S+  page_gone \(not_found, HTTP 410\)`, out.String())
+
+	histories, err := LoadAllPageHistories(database)
+	require.NoError(t, err)
+	require.Len(t, histories, 2, "lifecycle records are not pages")
+	assert.Empty(t, histories[0].Lifecycle)
+	require.Len(t, histories[1].Lifecycle, 1)
+	assert.Equal(t, PageGoneEvent, histories[1].Lifecycle[0].Type)
+}
@@ -0,0 +1,107 @@
+package diff
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// DuplicateCluster is a group of URLs whose latest versions are near-duplicates of each other,
+// e.g. the same article under several URLs or print views. MinSimilarity is the lowest similarity
+// between two URLs the cluster was joined by.
+type DuplicateCluster struct {
+	URLs          []string
+	MinSimilarity float64
+}
+
+// FindNearDuplicates clusters URLs whose latest versions are at least threshold similar. Similarity is
+// transitive within a cluster: if a~b and b~c, all three end up together. Gone pages and versions
+// without a SimHash are skipped. Every pair is compared, which is fine for some ten thousand URLs.
+func FindNearDuplicates(histories []PageHistory, threshold float64) []DuplicateCluster {
+	type candidate struct {
+		url     string
+		simHash uint64
+	}
+	candidates := make([]candidate, 0, len(histories))
+	for _, history := range histories {
+		if len(history.Versions) == 0 {
+			continue
+		}
+		latest := history.Versions[len(history.Versions)-1]
+		if simHash, ok := parseSimHash(latest.SimHash); ok && !latest.Gone {
+			candidates = append(candidates, candidate{history.URL, simHash})
+		}
+	}
+
+	// Union-find over the candidate indexes
+	parent := make([]int, len(candidates))
+	minSimilarity := make([]float64, len(candidates))
+	for i := range parent {
+		parent[i] = i
+		minSimilarity[i] = 1
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	for i := range candidates {
+		for j := i + 1; j < len(candidates); j++ {
+			similarity := Similarity(candidates[i].simHash, candidates[j].simHash)
+			if similarity < threshold {
+				continue
+			}
+			rootI, rootJ := find(i), find(j)
+			if rootI != rootJ {
+				parent[rootJ] = rootI
+				minSimilarity[rootI] = min(minSimilarity[rootI], minSimilarity[rootJ])
+			}
+			minSimilarity[rootI] = min(minSimilarity[rootI], similarity)
+		}
+	}
+
+	clustersByRoot := make(map[int]*DuplicateCluster)
+	for i, candidate := range candidates {
+		root := find(i)
+		if clustersByRoot[root] == nil {
+			clustersByRoot[root] = &DuplicateCluster{MinSimilarity: minSimilarity[root]}
+		}
+		clustersByRoot[root].URLs = append(clustersByRoot[root].URLs, candidate.url)
+	}
+
+	clusters := make([]DuplicateCluster, 0)
+	for _, cluster := range clustersByRoot {
+		if len(cluster.URLs) > 1 {
+			sort.Strings(cluster.URLs)
+			clusters = append(clusters, *cluster)
+		}
+	}
+	// Largest clusters first, then by URL so the output is stable
+	sort.Slice(clusters, func(i, j int) bool {
+		if len(clusters[i].URLs) != len(clusters[j].URLs) {
+			return len(clusters[i].URLs) > len(clusters[j].URLs)
+		}
+		return clusters[i].URLs[0] < clusters[j].URLs[0]
+	})
+	return clusters
+}
+
+func PrintNearDuplicates(out io.Writer, clusters []DuplicateCluster) {
+	if len(clusters) == 0 {
+		fmt.Fprintln(out, "No near-duplicates found.")
+		return
+	}
+
+	for i, cluster := range clusters {
+		if i > 0 {
+			fmt.Fprintln(out)
+		}
+		fmt.Fprintf(out, "Cluster %d: %d URLs, similarity >= %.2f\n", i+1, len(cluster.URLs), cluster.MinSimilarity)
+		for _, url := range cluster.URLs {
+			fmt.Fprintf(out, "  %s\n", url)
+		}
+	}
+}
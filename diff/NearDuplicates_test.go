@@ -0,0 +1,56 @@
+package diff
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func historyWithSimHash(url string, simHash uint64, gone bool) PageHistory {
+	return PageHistory{URL: url, Versions: []PageVersion{
+		{Version: 1, SimHash: formatSimHash(^simHash)},
+		{Version: 2, SimHash: formatSimHash(simHash), Gone: gone},
+	}}
+}
+
+func TestFindNearDuplicates(t *testing.T) {
+	histories := []PageHistory{
+		historyWithSimHash("https://a.com/article", 0x0000, false),
+		historyWithSimHash("https://a.com/article?print=1", 0x0001, false),
+		historyWithSimHash("https://b.com/copy", 0x0003, false),
+		historyWithSimHash("https://a.com/other", 0xffff_ffff, false),
+		historyWithSimHash("https://a.com/other-gone", 0xffff_fffe, true),
+		historyWithSimHash("https://a.com/unrelated", 0xffff_0000_ffff_0000, false),
+		{URL: "https://a.com/no-simhash", Versions: []PageVersion{{Version: 1}}},
+	}
+
+	clusters := FindNearDuplicates(histories, 62.0/64)
+
+	assert.Equal(t, []DuplicateCluster{
+		{URLs: []string{"https://a.com/article", "https://a.com/article?print=1", "https://b.com/copy"}, MinSimilarity: 62.0 / 64},
+	}, clusters)
+}
+
+func TestFindNearDuplicates_MinSimilarityOfMergedClusters(t *testing.T) {
+	histories := []PageHistory{
+		historyWithSimHash("https://a.com/1", 0x00, false),
+		historyWithSimHash("https://a.com/2", 0x03, false),
+		historyWithSimHash("https://a.com/3", 0x07, false),
+	}
+
+	clusters := FindNearDuplicates(histories, 61.0/64)
+
+	assert.Len(t, clusters, 1)
+	assert.Equal(t, 61.0/64, clusters[0].MinSimilarity)
+}
+
+func TestPrintNearDuplicates(t *testing.T) {
+	var out bytes.Buffer
+	PrintNearDuplicates(&out, []DuplicateCluster{{URLs: []string{"https://a.com/1", "https://a.com/2"}, MinSimilarity: 0.953}})
+	assert.Equal(t, "Cluster 1: 2 URLs, similarity >= 0.95\n  https://a.com/1\n  https://a.com/2\n", out.String())
+
+	out.Reset()
+	PrintNearDuplicates(&out, nil)
+	assert.Equal(t, "No near-duplicates found.\n", out.String())
+}
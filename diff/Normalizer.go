@@ -0,0 +1,153 @@
+package diff
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+)
+
+// NormalizationOptions selects what is removed from a page before it is hashed,
+// so that noise like CSRF tokens, timestamps or rotating ads doesn't create new versions.
+type NormalizationOptions struct {
+	StripScripts       bool
+	StripStyles        bool
+	StripComments      bool
+	CollapseWhitespace bool
+	// StripQueryStrings drops query strings from href and src attributes (cache busting like style.css?v=123).
+	StripQueryStrings bool
+	// RemoveSelectors are CSS selectors of elements removed from the document.
+	RemoveSelectors []string
+	// RemovePatterns are regular expressions whose matches are removed from the rendered document.
+	RemovePatterns []string
+}
+
+// Normalizer turns raw HTML into the canonical form used for change detection.
+// The raw HTML is still what gets stored; only the hash is computed from the normalized form.
+type Normalizer struct {
+	options   NormalizationOptions
+	selectors []cascadia.Selector
+	patterns  []*regexp.Regexp
+	id        string
+}
+
+var whitespaceRegexp = regexp.MustCompile(`\s+`)
+
+func NewNormalizer(options NormalizationOptions) (*Normalizer, error) {
+	normalizer := &Normalizer{options: options}
+
+	for _, selector := range options.RemoveSelectors {
+		compiled, err := cascadia.Compile(selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSS selector %q: %w", selector, err)
+		}
+		normalizer.selectors = append(normalizer.selectors, compiled)
+	}
+
+	for _, pattern := range options.RemovePatterns {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		normalizer.patterns = append(normalizer.patterns, compiled)
+	}
+
+	optionsJson, err := json.Marshal(options)
+	if err != nil {
+		return nil, err
+	}
+	hash := md5.Sum(optionsJson)
+	normalizer.id = hex.EncodeToString(hash[:4])
+
+	return normalizer, nil
+}
+
+// ID identifies the normalization options, so hashes computed with different options are never compared.
+func (n *Normalizer) ID() string {
+	return n.id
+}
+
+func (n *Normalizer) Normalize(htmlContent string) string {
+	normalized := htmlContent
+
+	if n.needsDocument() {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
+		if err != nil {
+			handleError(err, "Error parsing HTML for normalization, falling back to raw content")
+		} else {
+			n.normalizeDocument(doc)
+			if rendered, err := doc.Html(); err == nil {
+				normalized = rendered
+			}
+		}
+	}
+
+	for _, pattern := range n.patterns {
+		normalized = pattern.ReplaceAllString(normalized, "")
+	}
+
+	if n.options.CollapseWhitespace {
+		normalized = strings.TrimSpace(whitespaceRegexp.ReplaceAllString(normalized, " "))
+	}
+
+	return normalized
+}
+
+func (n *Normalizer) needsDocument() bool {
+	return n.options.StripScripts || n.options.StripStyles || n.options.StripComments ||
+		n.options.StripQueryStrings || len(n.selectors) > 0
+}
+
+func (n *Normalizer) normalizeDocument(doc *goquery.Document) {
+	if n.options.StripScripts {
+		doc.Find("script, noscript").Remove()
+	}
+	if n.options.StripStyles {
+		doc.Find("style").Remove()
+		doc.Find("[style]").RemoveAttr("style")
+	}
+	for _, selector := range n.selectors {
+		doc.FindMatcher(selector).Remove()
+	}
+	if n.options.StripComments {
+		removeComments(doc.Nodes[0])
+	}
+	if n.options.StripQueryStrings {
+		doc.Find("[href], [src]").Each(func(i int, s *goquery.Selection) {
+			for _, attribute := range []string{"href", "src"} {
+				if value, exists := s.Attr(attribute); exists {
+					s.SetAttr(attribute, stripQueryString(value))
+				}
+			}
+		})
+	}
+}
+
+func removeComments(node *html.Node) {
+	for child := node.FirstChild; child != nil; {
+		next := child.NextSibling
+		if child.Type == html.CommentNode {
+			node.RemoveChild(child)
+		} else {
+			removeComments(child)
+		}
+		child = next
+	}
+}
+
+func stripQueryString(link string) string {
+	parsedUrl, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+	parsedUrl.RawQuery = ""
+	parsedUrl.ForceQuery = false
+	return parsedUrl.String()
+}
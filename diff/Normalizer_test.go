@@ -0,0 +1,121 @@
+package diff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"goCrawler/db"
+	"goCrawler/storage"
+)
+
+func TestNormalizer(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  NormalizationOptions
+		first    string
+		second   string
+		expected bool // true if both pages are expected to normalize to the same content
+	}{
+		{"Raw content differs", NormalizationOptions{},
+			"<p>a</p>", "<p>b</p>", false},
+		{"Scripts", NormalizationOptions{StripScripts: true},
+			`<p>a</p><script>var csrf = "123";</script>`, `<p>a</p><script>var csrf = "456";</script>`, true},
+		{"Styles", NormalizationOptions{StripStyles: true},
+			`<style>.a{color:red}</style><p style="top:1px">a</p>`, `<style>.a{color:blue}</style><p style="top:2px">a</p>`, true},
+		{"Comments", NormalizationOptions{StripComments: true},
+			"<p>a<!-- rendered at 12:00 --></p>", "<p>a<!-- rendered at 12:01 --></p>", true},
+		{"Whitespace", NormalizationOptions{CollapseWhitespace: true},
+			"<p>a  b</p>\n", "<p>a\n\tb</p>", true},
+		{"Query strings", NormalizationOptions{StripQueryStrings: true},
+			`<link href="style.css?v=1"><img src="/a.png?t=1">`, `<link href="style.css?v=2"><img src="/a.png?t=2">`, true},
+		{"Selectors", NormalizationOptions{RemoveSelectors: []string{"input[name=csrf]", ".ad"}},
+			`<p>a</p><input name="csrf" value="1"><div class="ad">buy</div>`, `<p>a</p><input name="csrf" value="2"><div class="ad">sell</div>`, true},
+		{"Selectors keep other content", NormalizationOptions{RemoveSelectors: []string{".ad"}},
+			`<p>a</p><div class="ad">buy</div>`, `<p>b</p><div class="ad">buy</div>`, false},
+		{"Patterns", NormalizationOptions{RemovePatterns: []string{`\d{2}:\d{2}`}},
+			"<p>Updated 12:00</p>", "<p>Updated 13:45</p>", true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			normalizer, err := NewNormalizer(tc.options)
+			require.NoError(t, err)
+
+			same := normalizer.Normalize(tc.first) == normalizer.Normalize(tc.second)
+			if same != tc.expected {
+				t.Errorf("Normalize(%q) == Normalize(%q) is %v; want %v", tc.first, tc.second, same, tc.expected)
+			}
+		})
+	}
+}
+
+func TestNewNormalizer_InvalidOptions(t *testing.T) {
+	_, err := NewNormalizer(NormalizationOptions{RemoveSelectors: []string{"div["}})
+	assert.ErrorContains(t, err, "invalid CSS selector")
+
+	_, err = NewNormalizer(NormalizationOptions{RemovePatterns: []string{"(["}})
+	assert.ErrorContains(t, err, "invalid pattern")
+}
+
+func TestDifferenceTracker_HashesNormalizedContentAndStoresRaw(t *testing.T) {
+	database := db.NewInMemoryDatabase()
+	fileStorage := storage.NewFileStorage(t.TempDir())
+	normalizer, err := NewNormalizer(NormalizationOptions{StripScripts: true})
+	require.NoError(t, err)
+
+	sut := NewDifferenceTracker(database, fileStorage)
+	sut.SetNormalizer(normalizer)
+
+	first := `<html><body><p>a</p><script>token="1"</script></body></html>`
+	second := `<html><body><p>a</p><script>token="2"</script></body></html>`
+	require.NoError(t, sut.HandleContent("https://www.google.com", first))
+	require.NoError(t, sut.HandleContent("https://www.google.com", second))
+
+	pageVersions, err := LoadPageVersions(database, "https://www.google.com")
+	require.NoError(t, err)
+	require.Len(t, pageVersions, 1)
+	assert.Equal(t, normalizer.ID(), pageVersions[0].NormalizationID)
+
+	content, err := fileStorage.Read(pageVersions[0].FilePath)
+	require.NoError(t, err)
+	assert.Equal(t, first, string(content))
+}
+
+func TestDifferenceTracker_RebaselinesWhenNormalizationChanges(t *testing.T) {
+	database := db.NewInMemoryDatabase()
+	sut := NewDifferenceTracker(database, storage.NewFileStorage(t.TempDir()))
+
+	require.NoError(t, sut.HandleContent("https://www.google.com", `<p>a</p><script>1</script>`))
+
+	normalizer, err := NewNormalizer(NormalizationOptions{StripScripts: true})
+	require.NoError(t, err)
+	sut.SetNormalizer(normalizer)
+
+	require.NoError(t, sut.HandleContent("https://www.google.com", `<p>a</p><script>2</script>`))
+	require.NoError(t, sut.HandleContent("https://www.google.com", `<p>b</p><script>3</script>`))
+
+	pageVersions, err := LoadPageVersions(database, "https://www.google.com")
+	require.NoError(t, err)
+	assert.Len(t, pageVersions, 2, "only the real change after re-baselining creates a version")
+}
+
+func TestDifferenceTracker_DetectsChangeInTheCrawlThatChangesNormalization(t *testing.T) {
+	database := db.NewInMemoryDatabase()
+	sut := NewDifferenceTracker(database, storage.NewFileStorage(t.TempDir()))
+
+	require.NoError(t, sut.HandleContent("https://www.google.com", `<p>a</p><script>1</script>`))
+
+	normalizer, err := NewNormalizer(NormalizationOptions{StripScripts: true})
+	require.NoError(t, err)
+	sut.SetNormalizer(normalizer)
+
+	require.NoError(t, sut.HandleContent("https://www.google.com", `<p>b</p><script>2</script>`))
+
+	pageVersions, err := LoadPageVersions(database, "https://www.google.com")
+	require.NoError(t, err)
+	require.Len(t, pageVersions, 2, "the content change is recorded despite re-baselining")
+	assert.Equal(t, normalizer.ID(), pageVersions[0].NormalizationID)
+	assert.Equal(t, normalizer.ID(), pageVersions[1].NormalizationID)
+}
@@ -0,0 +1,82 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"time"
+
+	"goCrawler/storage"
+	"goCrawler/urlutil"
+)
+
+// PageVersion describes one distinct content of a URL. Only Hash, FilePath and Version
+// existed originally, so every other field may be zero in versions stored by older crawls.
+type PageVersion struct {
+	Hash     string
+	FilePath string
+	Version  int
+	// NormalizationID identifies how Hash was computed (normalization options and watch rule), empty for the raw HTML.
+	NormalizationID string `json:",omitempty"`
+	// RegionHashes are the hashes of the regions selected by the watch rule of the URL, keyed by CSS selector,
+	// and ChangedRegions the selectors whose region differs from the previous version.
+	RegionHashes   map[string]string `json:",omitempty"`
+	ChangedRegions []string          `json:",omitempty"`
+	// SimHash is the similarity fingerprint of the content (hex), Similarity how similar it is to the
+	// previous version, from 1 for nearly identical to around 0.5 for unrelated content.
+	SimHash    string  `json:",omitempty"`
+	Similarity float64 `json:",omitempty"`
+	// DiffPath is where the diff against the previous version is stored, DiffSummary its totals.
+	DiffPath    string       `json:",omitempty"`
+	DiffSummary *DiffSummary `json:",omitempty"`
+
+	// FirstSeen is when this content was fetched for the first time, LastSeen the last
+	// time it was fetched unchanged and LastChecked the last time the URL was fetched at all.
+	FirstSeen   time.Time
+	LastSeen    time.Time
+	LastChecked time.Time
+
+	// Metadata of the fetch that produced this version.
+	StatusCode    int           `json:",omitempty"`
+	ContentLength int64         `json:",omitempty"`
+	ContentType   string        `json:",omitempty"`
+	FetchDuration time.Duration `json:",omitempty"`
+	CrawlRunID    string        `json:",omitempty"`
+
+	// Gone is set on the latest version while the URL is gone; see LoadLifecycleEvents for when and why.
+	Gone bool `json:",omitempty"`
+}
+
+// urlIndexFilename is the file next to the versions of a URL that holds the URL itself,
+// so the URL of a directory is known even if its path was shortened by hashing.
+const urlIndexFilename = "_url"
+
+// ConstructFilePath returns where a version of url is stored, in the directory given by urlutil.EncodeFilePath.
+// Versions stored by older crawls keep the paths recorded in their FilePath.
+func ConstructFilePath(url string, version int) string {
+	return fmt.Sprintf("%s/v%d.html", urlutil.EncodeFilePath(url), version)
+}
+
+func constructIndexPath(url string) string {
+	return urlutil.EncodeFilePath(url) + "/" + urlIndexFilename
+}
+
+// UrlOfFile returns the URL a stored file belongs to, from the index next to it or by decoding its directory.
+func UrlOfFile(fileStorage storage.IStorage, filePath string) (string, error) {
+	directory := path.Dir(filePath)
+	if url, err := fileStorage.Read(directory + "/" + urlIndexFilename); err == nil {
+		return string(url), nil
+	}
+	return urlutil.DecodeFilePath(directory)
+}
+
+func PageVersionsToJson(pageVersions []PageVersion) ([]byte, error) {
+	return json.Marshal(pageVersions)
+}
+
+func PageVersionsFromJson(data []byte) ([]PageVersion, error) {
+	var pageVersions []PageVersion
+	err := json.Unmarshal(data, &pageVersions)
+
+	return pageVersions, err
+}
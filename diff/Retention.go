@@ -0,0 +1,203 @@
+package diff
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"goCrawler/db"
+	"goCrawler/storage"
+	"goCrawler/urlutil"
+)
+
+// RetentionPolicy selects which versions of a URL are kept when pruning; a version is kept if any rule keeps it.
+// The latest version is always kept, as new content is compared against it. Versions are dated by FirstSeen;
+// versions stored before timestamps were recorded are only kept by KeepLast.
+type RetentionPolicy struct {
+	// KeepLast keeps the newest N versions.
+	KeepLast int
+	// KeepWithin keeps versions first seen less than this long ago.
+	KeepWithin time.Duration
+	// KeepDaily and KeepWeekly keep the newest version of each of the last N days or ISO weeks that have versions.
+	KeepDaily  int
+	KeepWeekly int
+}
+
+// Enabled reports whether the policy has any rule; without one nothing is pruned.
+func (p RetentionPolicy) Enabled() bool {
+	return p.KeepLast > 0 || p.KeepWithin > 0 || p.KeepDaily > 0 || p.KeepWeekly > 0
+}
+
+// keep returns the version numbers of pageVersions the policy keeps at now.
+func (p RetentionPolicy) keep(pageVersions []PageVersion, now time.Time) map[int]bool {
+	kept := make(map[int]bool)
+	if len(pageVersions) == 0 {
+		return kept
+	}
+
+	newestFirst := append([]PageVersion{}, pageVersions...)
+	sort.Slice(newestFirst, func(i, j int) bool { return newestFirst[i].Version > newestFirst[j].Version })
+
+	kept[newestFirst[0].Version] = true
+	for i, pageVersion := range newestFirst {
+		if i < p.KeepLast {
+			kept[pageVersion.Version] = true
+		}
+		if p.KeepWithin > 0 && !pageVersion.FirstSeen.IsZero() && now.Sub(pageVersion.FirstSeen) < p.KeepWithin {
+			kept[pageVersion.Version] = true
+		}
+	}
+
+	keepNewestPerPeriod(newestFirst, p.KeepDaily, kept, func(t time.Time) string {
+		return t.UTC().Format("2006-01-02")
+	})
+	keepNewestPerPeriod(newestFirst, p.KeepWeekly, kept, func(t time.Time) string {
+		year, week := t.UTC().ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	})
+	return kept
+}
+
+func keepNewestPerPeriod(newestFirst []PageVersion, periods int, kept map[int]bool, period func(time.Time) string) {
+	seenPeriods := make(map[string]bool)
+	for _, pageVersion := range newestFirst {
+		if len(seenPeriods) >= periods {
+			return
+		}
+		if pageVersion.FirstSeen.IsZero() {
+			continue
+		}
+		if key := period(pageVersion.FirstSeen); !seenPeriods[key] {
+			seenPeriods[key] = true
+			kept[pageVersion.Version] = true
+		}
+	}
+}
+
+// SeedRetention is the retention policy of the URLs crawled from Seed.
+type SeedRetention struct {
+	Seed   string
+	Policy RetentionPolicy
+}
+
+// RetentionRules picks the policy of a URL: the one of the first seed with the URL's domain, or Default.
+type RetentionRules struct {
+	Default RetentionPolicy
+	Seeds   []SeedRetention
+}
+
+func (r RetentionRules) policyFor(url string) RetentionPolicy {
+	domain := urlutil.NormalizeDomain(url)
+	for _, seedRetention := range r.Seeds {
+		if urlutil.NormalizeDomain(seedRetention.Seed) == domain {
+			return seedRetention.Policy
+		}
+	}
+	return r.Default
+}
+
+// PrunedPage lists the versions of a URL removed by pruning.
+type PrunedPage struct {
+	URL      string
+	Pruned   []int
+	Kept     int
+	Failures []string `json:",omitempty"`
+}
+
+type PruneReport struct {
+	DryRun bool
+	Pages  []PrunedPage
+}
+
+func (r PruneReport) PrunedVersions() int {
+	total := 0
+	for _, page := range r.Pages {
+		total += len(page.Pruned)
+	}
+	return total
+}
+
+// PruneVersions applies rules to every URL in the database. The version list of a URL is rewritten before
+// its files are deleted, so the database never refers to deleted files; files that fail to delete are only
+// reported. Versions are deleted newest first, so delta encoded successors are rebuilt at most once.
+// With dryRun nothing is changed. It must not run while a crawl is storing versions.
+func PruneVersions(database db.IDatabase, fileStorage storage.IStorage, rules RetentionRules, dryRun bool, now time.Time) (PruneReport, error) {
+	report := PruneReport{DryRun: dryRun, Pages: make([]PrunedPage, 0)}
+
+	histories, err := LoadAllPageHistories(database)
+	if err != nil {
+		return report, err
+	}
+
+	for _, history := range histories {
+		policy := rules.policyFor(history.URL)
+		if !policy.Enabled() {
+			continue
+		}
+
+		kept := policy.keep(history.Versions, now)
+		remaining := make([]PageVersion, 0, len(kept))
+		pruned := make([]PageVersion, 0)
+		for _, pageVersion := range history.Versions {
+			if kept[pageVersion.Version] {
+				remaining = append(remaining, pageVersion)
+			} else {
+				pruned = append(pruned, pageVersion)
+			}
+		}
+		if len(pruned) == 0 {
+			continue
+		}
+
+		page := PrunedPage{URL: history.URL, Kept: len(remaining)}
+		for _, pageVersion := range pruned {
+			page.Pruned = append(page.Pruned, pageVersion.Version)
+		}
+		report.Pages = append(report.Pages, page)
+		if dryRun {
+			continue
+		}
+
+		versionsJson, err := PageVersionsToJson(remaining)
+		if err != nil {
+			return report, err
+		}
+		if err := database.Store(history.URL, versionsJson); err != nil {
+			return report, fmt.Errorf("rewriting versions of %s: %w", history.URL, err)
+		}
+
+		for i := len(pruned) - 1; i >= 0; i-- {
+			for _, path := range []string{pruned[i].FilePath, pruned[i].DiffPath} {
+				if path == "" {
+					continue
+				}
+				if err := fileStorage.Delete(path); err != nil {
+					handleError(err, "Error deleting pruned file "+path)
+					report.Pages[len(report.Pages)-1].Failures = append(report.Pages[len(report.Pages)-1].Failures, path)
+				}
+			}
+		}
+	}
+	return report, nil
+}
+
+func PrintPruneReport(out io.Writer, report PruneReport) {
+	verb := "Pruned"
+	if report.DryRun {
+		verb = "Would prune"
+	}
+
+	for _, page := range report.Pages {
+		versions := make([]string, 0, len(page.Pruned))
+		for _, version := range page.Pruned {
+			versions = append(versions, fmt.Sprintf("v%d", version))
+		}
+		fmt.Fprintf(out, "%s: %s %s, keeping %d\n", page.URL, strings.ToLower(verb), strings.Join(versions, ", "), page.Kept)
+		for _, failure := range page.Failures {
+			fmt.Fprintf(out, "  failed to delete %s\n", failure)
+		}
+	}
+	fmt.Fprintf(out, "%s %d versions of %d URLs.\n", verb, report.PrunedVersions(), len(report.Pages))
+}
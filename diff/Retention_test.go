@@ -0,0 +1,152 @@
+package diff
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"goCrawler/db"
+	"goCrawler/storage"
+)
+
+var retentionNow = time.Date(2024, 3, 20, 12, 0, 0, 0, time.UTC)
+
+// versionsSeenAt creates one version per timestamp, oldest first.
+func versionsSeenAt(firstSeen ...time.Time) []PageVersion {
+	pageVersions := make([]PageVersion, 0, len(firstSeen))
+	for i, seen := range firstSeen {
+		pageVersions = append(pageVersions, PageVersion{Version: i + 1, FirstSeen: seen})
+	}
+	return pageVersions
+}
+
+func keptVersions(kept map[int]bool) []int {
+	versions := make([]int, 0)
+	for version := 1; version <= 20; version++ {
+		if kept[version] {
+			versions = append(versions, version)
+		}
+	}
+	return versions
+}
+
+func TestRetentionPolicy_Keep(t *testing.T) {
+	day := 24 * time.Hour
+	pageVersions := versionsSeenAt(
+		time.Time{},                        // v1, stored before timestamps were recorded
+		retentionNow.Add(-30*day),          // v2
+		retentionNow.Add(-15*day),          // v3
+		retentionNow.Add(-14*day),          // v4
+		retentionNow.Add(-3*day),           // v5
+		retentionNow.Add(-2*day-time.Hour), // v6
+		retentionNow.Add(-2*day),           // v7
+		retentionNow.Add(-time.Hour),       // v8
+	)
+
+	tests := []struct {
+		name     string
+		policy   RetentionPolicy
+		expected []int
+	}{
+		{"Latest is always kept", RetentionPolicy{KeepLast: 1}, []int{8}},
+		{"Keep last", RetentionPolicy{KeepLast: 3}, []int{6, 7, 8}},
+		{"Keep within", RetentionPolicy{KeepWithin: 3*day + time.Minute}, []int{5, 6, 7, 8}},
+		{"Keep daily", RetentionPolicy{KeepDaily: 3}, []int{5, 7, 8}},
+		{"Keep weekly", RetentionPolicy{KeepWeekly: 3}, []int{4, 5, 8}},
+		{"Rules are combined", RetentionPolicy{KeepLast: 2, KeepWeekly: 2}, []int{5, 7, 8}},
+		{"Legacy versions only by keep last", RetentionPolicy{KeepLast: 8, KeepDaily: 1}, []int{1, 2, 3, 4, 5, 6, 7, 8}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, keptVersions(tt.policy.keep(pageVersions, retentionNow)))
+		})
+	}
+}
+
+func TestRetentionRules_PolicyFor(t *testing.T) {
+	rules := RetentionRules{
+		Default: RetentionPolicy{KeepLast: 10},
+		Seeds:   []SeedRetention{{Seed: "https://www.example.com", Policy: RetentionPolicy{KeepLast: 2}}},
+	}
+
+	assert.Equal(t, 2, rules.policyFor("https://example.com/page").KeepLast)
+	assert.Equal(t, 10, rules.policyFor("https://other.com/page").KeepLast)
+}
+
+func newPruneFixture(t *testing.T) (*db.InMemoryDatabase, *storage.FileStorage, string) {
+	directory := t.TempDir()
+	database := db.NewInMemoryDatabase()
+	fileStorage := storage.NewFileStorage(directory)
+	diffTracker := NewDifferenceTracker(database, fileStorage)
+
+	for i, content := range []string{defaultHtmlContent, changedHtmlContent, defaultHtmlContent} {
+		diffTracker.now = func() time.Time { return retentionNow.Add(time.Duration(i-3) * 24 * time.Hour) }
+		require.NoError(t, diffTracker.HandleContent("https://www.google.com", content))
+	}
+	require.NoError(t, diffTracker.HandleContent("https://other.com", defaultHtmlContent))
+	return database, fileStorage, directory
+}
+
+func TestPruneVersions(t *testing.T) {
+	database, fileStorage, directory := newPruneFixture(t)
+	before, err := LoadPageVersions(database, "https://www.google.com")
+	require.NoError(t, err)
+	require.Len(t, before, 3)
+
+	report, err := PruneVersions(database, fileStorage, RetentionRules{Default: RetentionPolicy{KeepLast: 1}}, false, retentionNow)
+	require.NoError(t, err)
+
+	assert.Equal(t, []PrunedPage{{URL: "https://www.google.com", Pruned: []int{1, 2}, Kept: 1}}, report.Pages)
+	after, err := LoadPageVersions(database, "https://www.google.com")
+	require.NoError(t, err)
+	assert.Equal(t, before[2:], after)
+
+	for _, pageVersion := range before[:2] {
+		for _, path := range []string{pageVersion.FilePath, pageVersion.DiffPath} {
+			if path != "" {
+				_, err := os.Stat(filepath.Join(directory, path))
+				assert.True(t, os.IsNotExist(err), path)
+			}
+		}
+	}
+	_, err = os.Stat(filepath.Join(directory, before[2].FilePath))
+	assert.NoError(t, err)
+}
+
+func TestPruneVersions_DryRun(t *testing.T) {
+	database, fileStorage, directory := newPruneFixture(t)
+	before, err := LoadPageVersions(database, "https://www.google.com")
+	require.NoError(t, err)
+
+	report, err := PruneVersions(database, fileStorage, RetentionRules{Default: RetentionPolicy{KeepLast: 2}}, true, retentionNow)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, report.PrunedVersions())
+	after, err := LoadPageVersions(database, "https://www.google.com")
+	require.NoError(t, err)
+	assert.Equal(t, before, after)
+	_, err = os.Stat(filepath.Join(directory, before[0].FilePath))
+	assert.NoError(t, err)
+
+	var out bytes.Buffer
+	PrintPruneReport(&out, report)
+	assert.Equal(t, "https://www.google.com: would prune v1, keeping 2\nWould prune 1 versions of 1 URLs.\n", out.String())
+}
+
+func TestPruneVersions_SeedPolicyWithoutRulesKeepsEverything(t *testing.T) {
+	database, fileStorage, _ := newPruneFixture(t)
+	rules := RetentionRules{
+		Default: RetentionPolicy{KeepLast: 1},
+		Seeds:   []SeedRetention{{Seed: "https://www.google.com"}},
+	}
+
+	report, err := PruneVersions(database, fileStorage, rules, false, retentionNow)
+	require.NoError(t, err)
+	assert.Empty(t, report.Pages)
+}
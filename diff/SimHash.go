@@ -0,0 +1,83 @@
+package diff
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// shingleSize is the number of consecutive words hashed together. Shingles make reordered text differ,
+// while a changed word only affects the few shingles containing it.
+const shingleSize = 3
+
+// SimHash fingerprints the text of a page so that similar texts get fingerprints differing in few bits,
+// unlike MD5 where any change flips about half of them.
+func SimHash(text string) uint64 {
+	words := strings.Fields(strings.ToLower(text))
+	if len(words) == 0 {
+		return 0
+	}
+
+	var weights [64]int
+	for _, shingle := range shingles(words) {
+		hash := fnv.New64a()
+		hash.Write([]byte(shingle))
+		shingleHash := hash.Sum64()
+		for bit := 0; bit < 64; bit++ {
+			if shingleHash&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+
+	var simHash uint64
+	for bit := 0; bit < 64; bit++ {
+		if weights[bit] > 0 {
+			simHash |= 1 << bit
+		}
+	}
+	return simHash
+}
+
+func shingles(words []string) []string {
+	if len(words) <= shingleSize {
+		return []string{strings.Join(words, " ")}
+	}
+
+	result := make([]string, 0, len(words)-shingleSize+1)
+	for i := 0; i+shingleSize <= len(words); i++ {
+		result = append(result, strings.Join(words[i:i+shingleSize], " "))
+	}
+	return result
+}
+
+// Similarity is the share of equal bits of two SimHashes, 1 for (nearly) identical texts and around 0.5 for unrelated ones.
+func Similarity(a, b uint64) float64 {
+	return 1 - float64(bits.OnesCount64(a^b))/64
+}
+
+func formatSimHash(simHash uint64) string {
+	return fmt.Sprintf("%016x", simHash)
+}
+
+func parseSimHash(simHash string) (uint64, bool) {
+	if simHash == "" {
+		return 0, false
+	}
+	parsed, err := strconv.ParseUint(simHash, 16, 64)
+	return parsed, err == nil
+}
+
+// versionSimilarity compares the SimHashes of two versions; ok is false if either was stored without one.
+func versionSimilarity(a, b PageVersion) (similarity float64, ok bool) {
+	simHashA, okA := parseSimHash(a.SimHash)
+	simHashB, okB := parseSimHash(b.SimHash)
+	if !okA || !okB {
+		return 0, false
+	}
+	return Similarity(simHashA, simHashB), true
+}
@@ -0,0 +1,97 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"goCrawler/db"
+	"goCrawler/storage"
+)
+
+var articleText = strings.Repeat("The quick brown fox jumps over the lazy dog near the river bank. ", 2) +
+	"Crawlers fetch pages, follow links and record every version they find. " +
+	"Change detection compares fingerprints of consecutive versions to spot meaningful edits. " +
+	"Small edits like a fixed typo should not look the same as a page being rewritten from scratch. " +
+	"Near duplicates are pages with almost the same text under different addresses."
+
+func articleHtml(text string) string {
+	return "<html><body><article><p>" + text + "</p></article></body></html>"
+}
+
+func TestSimilarity(t *testing.T) {
+	oneWordChanged := strings.Replace(articleText, "typo", "spelling mistake", 1)
+	unrelated := "Quarterly revenue grew by four percent while operating costs stayed flat across all regions. " +
+		"The board approved the dividend and announced a new share buyback program for the coming year."
+
+	identical := Similarity(SimHash(articleText), SimHash(articleText))
+	minorChange := Similarity(SimHash(articleText), SimHash(oneWordChanged))
+	rewritten := Similarity(SimHash(articleText), SimHash(unrelated))
+
+	assert.Equal(t, 1.0, identical)
+	assert.GreaterOrEqual(t, minorChange, 0.85)
+	assert.Less(t, rewritten, minorChange)
+	assert.Less(t, rewritten, 0.8)
+}
+
+func TestSimHash_IgnoresCaseAndWhitespace(t *testing.T) {
+	assert.Equal(t, SimHash("Hello big   World\n again"), SimHash("hello BIG world again"))
+	assert.Equal(t, uint64(0), SimHash("  "))
+}
+
+func TestVersionSimilarity_RequiresSimHashes(t *testing.T) {
+	_, ok := versionSimilarity(PageVersion{SimHash: formatSimHash(42)}, PageVersion{})
+	assert.False(t, ok)
+
+	similarity, ok := versionSimilarity(PageVersion{SimHash: formatSimHash(0)}, PageVersion{SimHash: formatSimHash(0xff)})
+	assert.True(t, ok)
+	assert.Equal(t, 1-8.0/64, similarity)
+}
+
+func TestDifferenceTracker_RecordsSimilarity(t *testing.T) {
+	tests := []struct {
+		name             string
+		threshold        float64
+		expectedVersions int
+	}{
+		{"Every change is recorded without threshold", 0, 2},
+		{"Minor change below threshold is recorded", 0.999, 2},
+		{"Minor change above threshold is ignored", 0.8, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database := db.NewInMemoryDatabase()
+			sut := NewDifferenceTracker(database, storage.NewFileStorage(t.TempDir()))
+			sut.SetSimilarityThreshold(tt.threshold)
+
+			require.NoError(t, sut.HandleContent("https://www.google.com", articleHtml(articleText)))
+			require.NoError(t, sut.HandleContent("https://www.google.com", articleHtml(strings.Replace(articleText, "typo", "spelling mistake", 1))))
+
+			pageVersions, err := LoadPageVersions(database, "https://www.google.com")
+			require.NoError(t, err)
+			require.Len(t, pageVersions, tt.expectedVersions)
+			assert.Equal(t, formatSimHash(SimHash(articleText)), pageVersions[0].SimHash)
+			assert.Zero(t, pageVersions[0].Similarity)
+			if tt.expectedVersions == 2 {
+				assert.Greater(t, pageVersions[1].Similarity, 0.8)
+				assert.Less(t, pageVersions[1].Similarity, 1.0)
+			}
+		})
+	}
+}
+
+func TestDifferenceTracker_BackfillsSimHashOfOldVersions(t *testing.T) {
+	database := db.NewInMemoryDatabase()
+	require.NoError(t, database.Store("https://www.google.com", []byte(`[{"Hash":"d6165a2f6a47eba8aa611ca6891203a9","FilePath":"google.com/v1.html","Version":1}]`)))
+	sut := NewDifferenceTracker(database, storage.NewFileStorage(t.TempDir()))
+
+	require.NoError(t, sut.HandleContent("https://www.google.com", defaultHtmlContent))
+
+	pageVersions, err := LoadPageVersions(database, "https://www.google.com")
+	require.NoError(t, err)
+	require.Len(t, pageVersions, 1)
+	assert.Equal(t, formatSimHash(SimHash("Google")), pageVersions[0].SimHash)
+}
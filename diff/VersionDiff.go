@@ -0,0 +1,415 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/pmezard/go-difflib/difflib"
+	"golang.org/x/net/html"
+
+	"goCrawler/db"
+	"goCrawler/storage"
+)
+
+// VersionDiff explains how one version of a page differs from another: a unified diff of the
+// visible text and the elements that were added, removed or changed in the DOM.
+type VersionDiff struct {
+	URL         string
+	FromVersion int
+	ToVersion   int
+	TextDiff    string
+	DomChanges  []DomChange
+	Summary     DiffSummary
+}
+
+type DiffSummary struct {
+	LinesAdded      int
+	LinesRemoved    int
+	ElementsAdded   int
+	ElementsRemoved int
+	ElementsChanged int
+}
+
+func (s DiffSummary) String() string {
+	return fmt.Sprintf("+%d -%d lines, %d added, %d removed, %d changed elements",
+		s.LinesAdded, s.LinesRemoved, s.ElementsAdded, s.ElementsRemoved, s.ElementsChanged)
+}
+
+type DomChangeType string
+
+const (
+	ElementAdded   DomChangeType = "added"
+	ElementRemoved DomChangeType = "removed"
+	ElementChanged DomChangeType = "changed"
+)
+
+// DomChange is a single element-level difference. Path locates the element, e.g. "html>body>div.prices>table>tr[2]";
+// Before and After hold the tag, attributes and own text of the element in the respective version.
+type DomChange struct {
+	Type   DomChangeType
+	Path   string
+	Before string `json:",omitempty"`
+	After  string `json:",omitempty"`
+}
+
+// ComputeVersionDiff compares two HTML documents.
+func ComputeVersionDiff(url string, fromVersion int, fromHtml string, toVersion int, toHtml string) (*VersionDiff, error) {
+	textDiff, linesAdded, linesRemoved, err := diffText(
+		ExtractText(fromHtml), ExtractText(toHtml),
+		fmt.Sprintf("%s@v%d", url, fromVersion), fmt.Sprintf("%s@v%d", url, toVersion))
+	if err != nil {
+		return nil, err
+	}
+
+	domChanges := DiffDom(fromHtml, toHtml)
+
+	versionDiff := &VersionDiff{
+		URL:         url,
+		FromVersion: fromVersion,
+		ToVersion:   toVersion,
+		TextDiff:    textDiff,
+		DomChanges:  domChanges,
+		Summary:     DiffSummary{LinesAdded: linesAdded, LinesRemoved: linesRemoved},
+	}
+	for _, change := range domChanges {
+		switch change.Type {
+		case ElementAdded:
+			versionDiff.Summary.ElementsAdded++
+		case ElementRemoved:
+			versionDiff.Summary.ElementsRemoved++
+		case ElementChanged:
+			versionDiff.Summary.ElementsChanged++
+		}
+	}
+	return versionDiff, nil
+}
+
+func diffText(fromText, toText, fromName, toName string) (string, int, int, error) {
+	fromLines := difflib.SplitLines(fromText)
+	toLines := difflib.SplitLines(toText)
+
+	added, removed := 0, 0
+	for _, opCode := range difflib.NewMatcher(fromLines, toLines).GetOpCodes() {
+		switch opCode.Tag {
+		case 'r':
+			removed += opCode.I2 - opCode.I1
+			added += opCode.J2 - opCode.J1
+		case 'd':
+			removed += opCode.I2 - opCode.I1
+		case 'i':
+			added += opCode.J2 - opCode.J1
+		}
+	}
+
+	unified, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        fromLines,
+		B:        toLines,
+		FromFile: fromName,
+		ToFile:   toName,
+		Context:  3,
+	})
+	return unified, added, removed, err
+}
+
+var blockElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "br": true, "dd": true, "div": true,
+	"dl": true, "dt": true, "fieldset": true, "figcaption": true, "figure": true, "footer": true, "form": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "header": true, "hr": true,
+	"li": true, "main": true, "nav": true, "ol": true, "p": true, "pre": true, "section": true, "table": true,
+	"td": true, "th": true, "tr": true, "ul": true, "title": true,
+}
+
+var invisibleElements = map[string]bool{"script": true, "style": true, "noscript": true, "template": true}
+
+// ExtractText returns the visible text of a document, one line per block element.
+func ExtractText(htmlContent string) string {
+	root, err := html.Parse(strings.NewReader(htmlContent))
+	if err != nil {
+		return htmlContent
+	}
+
+	var lines []string
+	var current strings.Builder
+	flush := func() {
+		if line := strings.Join(strings.Fields(current.String()), " "); line != "" {
+			lines = append(lines, line)
+		}
+		current.Reset()
+	}
+
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		switch node.Type {
+		case html.TextNode:
+			current.WriteString(node.Data)
+			current.WriteString(" ")
+			return
+		case html.ElementNode:
+			if invisibleElements[node.Data] {
+				return
+			}
+		case html.CommentNode:
+			return
+		}
+
+		isBlock := node.Type == html.ElementNode && blockElements[node.Data]
+		if isBlock {
+			flush()
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+		if isBlock {
+			flush()
+		}
+	}
+	walk(root)
+	flush()
+
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// domElement is the comparable form of an element: where it is and what it directly contains.
+type domElement struct {
+	path    string
+	content string
+}
+
+// DiffDom lists the elements added, removed or changed between two documents. Elements are compared in
+// document order; an element is "changed" when an element with the same path has different attributes or own text.
+func DiffDom(fromHtml, toHtml string) []DomChange {
+	fromElements := flattenDom(fromHtml)
+	toElements := flattenDom(toHtml)
+
+	fromKeys := make([]string, len(fromElements))
+	for i, element := range fromElements {
+		fromKeys[i] = element.path + "\n" + element.content
+	}
+	toKeys := make([]string, len(toElements))
+	for i, element := range toElements {
+		toKeys[i] = element.path + "\n" + element.content
+	}
+
+	changes := make([]DomChange, 0)
+	for _, opCode := range difflib.NewMatcher(fromKeys, toKeys).GetOpCodes() {
+		switch opCode.Tag {
+		case 'd':
+			changes = append(changes, removedElements(fromElements[opCode.I1:opCode.I2])...)
+		case 'i':
+			changes = append(changes, addedElements(toElements[opCode.J1:opCode.J2])...)
+		case 'r':
+			changes = append(changes, pairReplacedElements(fromElements[opCode.I1:opCode.I2], toElements[opCode.J1:opCode.J2])...)
+		}
+	}
+	return changes
+}
+
+// pairReplacedElements reports elements present on both sides under the same path as changed,
+// and the rest as removed or added.
+func pairReplacedElements(from, to []domElement) []DomChange {
+	toByPath := make(map[string]int, len(to))
+	for i, element := range to {
+		if _, exists := toByPath[element.path]; !exists {
+			toByPath[element.path] = i
+		}
+	}
+
+	changes := make([]DomChange, 0)
+	paired := make(map[int]bool)
+	for _, element := range from {
+		if i, exists := toByPath[element.path]; exists && !paired[i] {
+			paired[i] = true
+			changes = append(changes, DomChange{Type: ElementChanged, Path: element.path, Before: element.content, After: to[i].content})
+			continue
+		}
+		changes = append(changes, DomChange{Type: ElementRemoved, Path: element.path, Before: element.content})
+	}
+	for i, element := range to {
+		if !paired[i] {
+			changes = append(changes, DomChange{Type: ElementAdded, Path: element.path, After: element.content})
+		}
+	}
+	return changes
+}
+
+func removedElements(elements []domElement) []DomChange {
+	changes := make([]DomChange, 0, len(elements))
+	for _, element := range elements {
+		changes = append(changes, DomChange{Type: ElementRemoved, Path: element.path, Before: element.content})
+	}
+	return changes
+}
+
+func addedElements(elements []domElement) []DomChange {
+	changes := make([]DomChange, 0, len(elements))
+	for _, element := range elements {
+		changes = append(changes, DomChange{Type: ElementAdded, Path: element.path, After: element.content})
+	}
+	return changes
+}
+
+func flattenDom(htmlContent string) []domElement {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
+	if err != nil {
+		return nil
+	}
+
+	elements := make([]domElement, 0)
+	var walk func(node *html.Node, parentPath string)
+	walk = func(node *html.Node, parentPath string) {
+		siblings := make(map[string]int)
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode || invisibleElements[child.Data] {
+				continue
+			}
+
+			step := elementStep(child)
+			siblings[step]++
+			if siblings[step] > 1 {
+				step = fmt.Sprintf("%s[%d]", step, siblings[step])
+			}
+			path := step
+			if parentPath != "" {
+				path = parentPath + ">" + step
+			}
+
+			elements = append(elements, domElement{path: path, content: elementContent(child)})
+			walk(child, path)
+		}
+	}
+	walk(doc.Nodes[0], "")
+	return elements
+}
+
+// elementStep names an element within its parent by tag, id and classes.
+func elementStep(node *html.Node) string {
+	step := node.Data
+	for _, attribute := range node.Attr {
+		switch attribute.Key {
+		case "id":
+			step += "#" + attribute.Val
+		case "class":
+			for _, class := range strings.Fields(attribute.Val) {
+				step += "." + class
+			}
+		}
+	}
+	return step
+}
+
+// elementContent is the tag with its attributes and own (non-descendant) text.
+func elementContent(node *html.Node) string {
+	attributes := make([]string, 0, len(node.Attr))
+	for _, attribute := range node.Attr {
+		attributes = append(attributes, fmt.Sprintf("%s=%q", attribute.Key, attribute.Val))
+	}
+	sort.Strings(attributes)
+
+	var text strings.Builder
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.TextNode {
+			text.WriteString(child.Data)
+			text.WriteString(" ")
+		}
+	}
+
+	content := "<" + node.Data
+	if len(attributes) > 0 {
+		content += " " + strings.Join(attributes, " ")
+	}
+	content += ">"
+	if ownText := strings.Join(strings.Fields(text.String()), " "); ownText != "" {
+		content += " " + ownText
+	}
+	return content
+}
+
+func ConstructDiffFilePath(url string, version int) string {
+	return strings.TrimSuffix(ConstructFilePath(url, version), ".html") + ".diff.json"
+}
+
+func VersionDiffToJson(versionDiff *VersionDiff) ([]byte, error) {
+	return json.MarshalIndent(versionDiff, "", "  ")
+}
+
+func VersionDiffFromJson(data []byte) (*VersionDiff, error) {
+	var versionDiff VersionDiff
+	if err := json.Unmarshal(data, &versionDiff); err != nil {
+		return nil, err
+	}
+	return &versionDiff, nil
+}
+
+// PrintVersionDiff writes a diff as "text" (unified diff of the visible text), "dom" (one line per changed element) or "json".
+func PrintVersionDiff(out io.Writer, versionDiff *VersionDiff, format string) error {
+	switch format {
+	case "text":
+		_, err := io.WriteString(out, versionDiff.TextDiff)
+		return err
+	case "dom":
+		for _, change := range versionDiff.DomChanges {
+			switch change.Type {
+			case ElementAdded:
+				fmt.Fprintf(out, "+ %s %s\n", change.Path, change.After)
+			case ElementRemoved:
+				fmt.Fprintf(out, "- %s %s\n", change.Path, change.Before)
+			case ElementChanged:
+				fmt.Fprintf(out, "~ %s %s -> %s\n", change.Path, change.Before, change.After)
+			}
+		}
+		_, err := fmt.Fprintf(out, "%s\n", versionDiff.Summary)
+		return err
+	case "json":
+		bytes, err := VersionDiffToJson(versionDiff)
+		if err != nil {
+			return err
+		}
+		_, err = out.Write(append(bytes, '\n'))
+		return err
+	}
+	return fmt.Errorf("unknown diff format %q, expected html, text, dom or json", format)
+}
+
+// LoadVersionDiff returns the diff between two versions of url. The diff stored with toVersion is used
+// when it was computed against fromVersion; otherwise it is computed from the stored contents.
+func LoadVersionDiff(database db.IDatabase, fileStorage storage.IStorage, url string, fromVersion, toVersion int) (*VersionDiff, error) {
+	pageVersions, err := LoadPageVersions(database, url)
+	if err != nil {
+		return nil, err
+	}
+
+	from, err := FindPageVersion(pageVersions, fromVersion)
+	if err != nil {
+		return nil, err
+	}
+	to, err := FindPageVersion(pageVersions, toVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	if to.DiffPath != "" {
+		if data, err := fileStorage.Read(to.DiffPath); err == nil {
+			if versionDiff, err := VersionDiffFromJson(data); err == nil && versionDiff.FromVersion == from.Version {
+				return versionDiff, nil
+			}
+		}
+	}
+
+	fromContent, err := fileStorage.Read(from.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	toContent, err := fileStorage.Read(to.FilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return ComputeVersionDiff(url, from.Version, string(fromContent), to.Version, string(toContent))
+}
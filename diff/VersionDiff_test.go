@@ -0,0 +1,75 @@
+package diff
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"goCrawler/db"
+	"goCrawler/storage"
+)
+
+const jobsBefore = `<html><head><title>Jobs</title><script>var t = 1;</script></head><body>
+<h1>Open positions</h1>
+<ul class="jobs"><li>Go developer</li><li>Tester</li></ul>
+<p id="footer">Contact us</p>
+</body></html>`
+
+const jobsAfter = `<html><head><title>Jobs</title><script>var t = 2;</script></head><body>
+<h1>Open positions</h1>
+<ul class="jobs"><li>Go developer</li><li>Senior tester</li><li>Designer</li></ul>
+</body></html>`
+
+func TestExtractText(t *testing.T) {
+	assert.Equal(t, "Jobs\nOpen positions\nGo developer\nTester\nContact us\n", ExtractText(jobsBefore))
+}
+
+func TestComputeVersionDiff(t *testing.T) {
+	versionDiff, err := ComputeVersionDiff("https://a.com/jobs", 1, jobsBefore, 2, jobsAfter)
+	require.NoError(t, err)
+
+	assert.Contains(t, versionDiff.TextDiff, "--- https://a.com/jobs@v1")
+	assert.Contains(t, versionDiff.TextDiff, "-Tester\n")
+	assert.Contains(t, versionDiff.TextDiff, "+Senior tester\n")
+	assert.Contains(t, versionDiff.TextDiff, "+Designer\n")
+	assert.Contains(t, versionDiff.TextDiff, "-Contact us\n")
+	assert.NotContains(t, versionDiff.TextDiff, "var t")
+
+	assert.Contains(t, versionDiff.DomChanges, DomChange{Type: ElementChanged, Path: "html>body>ul.jobs>li[2]", Before: "<li> Tester", After: "<li> Senior tester"})
+	assert.Contains(t, versionDiff.DomChanges, DomChange{Type: ElementAdded, Path: "html>body>ul.jobs>li[3]", After: "<li> Designer"})
+	assert.Contains(t, versionDiff.DomChanges, DomChange{Type: ElementRemoved, Path: "html>body>p#footer", Before: `<p id="footer"> Contact us`})
+
+	assert.Equal(t, DiffSummary{LinesAdded: 2, LinesRemoved: 2, ElementsAdded: 1, ElementsRemoved: 1, ElementsChanged: 1}, versionDiff.Summary)
+}
+
+func TestDiffDom_IdenticalDocuments(t *testing.T) {
+	assert.Empty(t, DiffDom(jobsBefore, jobsBefore))
+}
+
+func TestDifferenceTracker_StoresDiffWithNewVersion(t *testing.T) {
+	database := db.NewInMemoryDatabase()
+	fileStorage := storage.NewFileStorage(t.TempDir())
+	sut := NewDifferenceTracker(database, fileStorage)
+
+	require.NoError(t, sut.HandleContent("https://a.com/jobs", jobsBefore))
+	require.NoError(t, sut.HandleContent("https://a.com/jobs", jobsAfter))
+
+	pageVersions, err := LoadPageVersions(database, "https://a.com/jobs")
+	require.NoError(t, err)
+	require.Len(t, pageVersions, 2)
+	assert.Empty(t, pageVersions[0].DiffPath)
+	assert.Equal(t, "a.com/jobs/v2.diff.json", pageVersions[1].DiffPath)
+	require.NotNil(t, pageVersions[1].DiffSummary)
+	assert.Equal(t, 1, pageVersions[1].DiffSummary.ElementsAdded)
+
+	stored, err := LoadVersionDiff(database, fileStorage, "https://a.com/jobs", 1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stored.ToVersion)
+
+	var out bytes.Buffer
+	require.NoError(t, PrintVersionDiff(&out, stored, "dom"))
+	assert.Contains(t, out.String(), "+ html>body>ul.jobs>li[3] <li> Designer")
+	assert.Contains(t, out.String(), "1 added, 1 removed, 1 changed elements")
+}
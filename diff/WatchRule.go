@@ -0,0 +1,137 @@
+package diff
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+)
+
+// WatchRule limits change detection of matching URLs to the regions selected by CSS selectors,
+// e.g. only the price table of a product page. Either URL (exact match) or Pattern (regular expression) is set.
+type WatchRule struct {
+	URL       string
+	Pattern   string
+	Selectors []string
+}
+
+type compiledWatchRule struct {
+	rule      WatchRule
+	pattern   *regexp.Regexp
+	selectors []cascadia.Selector
+	id        string
+}
+
+// WatchRules picks the rule for a URL; the first matching rule wins.
+type WatchRules struct {
+	rules []compiledWatchRule
+}
+
+func NewWatchRules(rules []WatchRule) (*WatchRules, error) {
+	watchRules := &WatchRules{}
+	for i, rule := range rules {
+		compiled := compiledWatchRule{rule: rule}
+
+		if (rule.URL == "") == (rule.Pattern == "") {
+			return nil, fmt.Errorf("watch rule %d: exactly one of url and pattern is required", i)
+		}
+		if rule.Pattern != "" {
+			pattern, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("watch rule %d: invalid pattern %q: %w", i, rule.Pattern, err)
+			}
+			compiled.pattern = pattern
+		}
+
+		if len(rule.Selectors) == 0 {
+			return nil, fmt.Errorf("watch rule %d: at least one selector is required", i)
+		}
+		for _, selector := range rule.Selectors {
+			compiledSelector, err := cascadia.Compile(selector)
+			if err != nil {
+				return nil, fmt.Errorf("watch rule %d: invalid CSS selector %q: %w", i, selector, err)
+			}
+			compiled.selectors = append(compiled.selectors, compiledSelector)
+		}
+
+		hash := md5.Sum([]byte(strings.Join(rule.Selectors, "\n")))
+		compiled.id = hex.EncodeToString(hash[:4])
+
+		watchRules.rules = append(watchRules.rules, compiled)
+	}
+	return watchRules, nil
+}
+
+func (w *WatchRules) match(url string) *compiledWatchRule {
+	if w == nil {
+		return nil
+	}
+	for i := range w.rules {
+		rule := &w.rules[i]
+		if rule.rule.URL == url || (rule.pattern != nil && rule.pattern.MatchString(url)) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// regionHashes hashes the outer HTML of everything each selector matches, keyed by selector.
+// A selector matching nothing still gets a hash, so a region disappearing is reported as a change.
+// regionsHtml is the HTML of all regions, in the order of the selectors.
+func (rule *compiledWatchRule) regionHashes(htmlContent string, normalizer *Normalizer) (hashes map[string]string, regionsHtml string, err error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
+	if err != nil {
+		return nil, "", err
+	}
+
+	var regions strings.Builder
+	hashes = make(map[string]string, len(rule.selectors))
+	for i, selector := range rule.selectors {
+		var region strings.Builder
+		doc.FindMatcher(selector).Each(func(_ int, s *goquery.Selection) {
+			if regionHtml, err := goquery.OuterHtml(s); err == nil {
+				region.WriteString(regionHtml)
+			}
+		})
+
+		regionContent := region.String()
+		if normalizer != nil {
+			regionContent = normalizer.Normalize(regionContent)
+		}
+		hashes[rule.rule.Selectors[i]] = getMD5Hash(regionContent)
+		regions.WriteString(regionContent)
+	}
+	return hashes, regions.String(), nil
+}
+
+// combinedRegionHash is the page hash of a watched page: it changes when any region changes.
+func combinedRegionHash(regionHashes map[string]string) string {
+	selectors := make([]string, 0, len(regionHashes))
+	for selector := range regionHashes {
+		selectors = append(selectors, selector)
+	}
+	sort.Strings(selectors)
+
+	var combined strings.Builder
+	for _, selector := range selectors {
+		combined.WriteString(selector + "=" + regionHashes[selector] + "\n")
+	}
+	return getMD5Hash(combined.String())
+}
+
+// changedRegions lists the selectors whose region hash differs between two versions.
+func changedRegions(previous, current map[string]string) []string {
+	changed := make([]string, 0)
+	for selector, hash := range current {
+		if previous[selector] != hash {
+			changed = append(changed, selector)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
@@ -0,0 +1,97 @@
+package diff
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"goCrawler/db"
+	"goCrawler/storage"
+)
+
+const pricingPage = `<html><body>
+<div class="banner">%s</div>
+<table class="prices"><tr><td>%s</td></tr></table>
+<h1>%s</h1>
+</body></html>`
+
+func pricing(banner, price, title string) string {
+	return fmt.Sprintf(pricingPage, banner, price, title)
+}
+
+func TestNewWatchRules_InvalidRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     WatchRule
+		expected string
+	}{
+		{"No url and no pattern", WatchRule{Selectors: []string{"table"}}, "exactly one of url and pattern"},
+		{"Both url and pattern", WatchRule{URL: "https://a.com", Pattern: "a", Selectors: []string{"table"}}, "exactly one of url and pattern"},
+		{"Invalid pattern", WatchRule{Pattern: "([", Selectors: []string{"table"}}, "invalid pattern"},
+		{"No selectors", WatchRule{URL: "https://a.com"}, "at least one selector"},
+		{"Invalid selector", WatchRule{URL: "https://a.com", Selectors: []string{"div["}}, "invalid CSS selector"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewWatchRules([]WatchRule{tc.rule})
+			assert.ErrorContains(t, err, tc.expected)
+		})
+	}
+}
+
+func TestWatchRules_FirstMatchingRuleWins(t *testing.T) {
+	watchRules, err := NewWatchRules([]WatchRule{
+		{URL: "https://a.com/pricing", Selectors: []string{"table"}},
+		{Pattern: `^https://a\.com/`, Selectors: []string{"h1"}},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"table"}, watchRules.match("https://a.com/pricing").rule.Selectors)
+	assert.Equal(t, []string{"h1"}, watchRules.match("https://a.com/jobs").rule.Selectors)
+	assert.Nil(t, watchRules.match("https://b.com/pricing"))
+}
+
+func TestDifferenceTracker_VersionsOnlyWatchedRegions(t *testing.T) {
+	database := db.NewInMemoryDatabase()
+	watchRules, err := NewWatchRules([]WatchRule{{Pattern: "pricing", Selectors: []string{"table.prices", "h1"}}})
+	require.NoError(t, err)
+
+	sut := NewDifferenceTracker(database, storage.NewFileStorage(t.TempDir()))
+	sut.SetWatchRules(watchRules)
+
+	url := "https://www.google.com/pricing"
+	require.NoError(t, sut.HandleContent(url, pricing("sale!", "10 EUR", "Pricing")))
+	require.NoError(t, sut.HandleContent(url, pricing("new sale!", "10 EUR", "Pricing")))
+
+	pageVersions, err := LoadPageVersions(database, url)
+	require.NoError(t, err)
+	require.Len(t, pageVersions, 1, "changes outside watched regions must not create versions")
+	assert.Len(t, pageVersions[0].RegionHashes, 2)
+
+	require.NoError(t, sut.HandleContent(url, pricing("new sale!", "12 EUR", "Pricing")))
+
+	pageVersions, err = LoadPageVersions(database, url)
+	require.NoError(t, err)
+	require.Len(t, pageVersions, 2)
+	assert.Equal(t, []string{"table.prices"}, pageVersions[1].ChangedRegions)
+}
+
+func TestDifferenceTracker_ReportsDisappearingRegion(t *testing.T) {
+	database := db.NewInMemoryDatabase()
+	watchRules, err := NewWatchRules([]WatchRule{{URL: "https://www.google.com", Selectors: []string{"ul.jobs"}}})
+	require.NoError(t, err)
+
+	sut := NewDifferenceTracker(database, storage.NewFileStorage(t.TempDir()))
+	sut.SetWatchRules(watchRules)
+
+	require.NoError(t, sut.HandleContent("https://www.google.com", `<ul class="jobs"><li>Go developer</li></ul>`))
+	require.NoError(t, sut.HandleContent("https://www.google.com", `<p>No open positions</p>`))
+
+	pageVersions, err := LoadPageVersions(database, "https://www.google.com")
+	require.NoError(t, err)
+	require.Len(t, pageVersions, 2)
+	assert.Equal(t, []string{"ul.jobs"}, pageVersions[1].ChangedRegions)
+}
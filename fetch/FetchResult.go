@@ -0,0 +1,26 @@
+package fetch
+
+import (
+	"net/http"
+	"time"
+)
+
+// FetchResult is a downloaded page together with what the server told us about it.
+type FetchResult struct {
+	URL           string
+	HTML          string
+	StatusCode    int
+	ContentType   string
+	ContentLength int64
+	FetchedAt     time.Time
+	Duration      time.Duration
+
+	// Protocol, RequestHeader and Header are the HTTP exchange as sent and received, e.g. for archiving it.
+	// Header no longer has Content-Encoding and Transfer-Encoding if the body was decoded on the way.
+	Protocol      string
+	RequestHeader http.Header
+	Header        http.Header
+
+	// Err is set when the page could not be downloaded at all; the other metadata is then zero.
+	Err error
+}
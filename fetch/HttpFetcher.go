@@ -0,0 +1,63 @@
+package fetch
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+type HTTPFetcher struct {
+	client    *http.Client
+	userAgent string
+}
+
+func NewHTTPFetcher(timeout time.Duration, userAgent string) *HTTPFetcher {
+	return &HTTPFetcher{
+		client:    &http.Client{Timeout: timeout},
+		userAgent: userAgent,
+	}
+}
+
+func (f *HTTPFetcher) Fetch(url string) (*FetchResult, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		log.Printf("Failed to create request for url='%s', err=%s", url, err)
+		return nil, err
+	}
+	if f.userAgent != "" {
+		req.Header.Set("User-Agent", f.userAgent)
+	}
+
+	client := f.client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Printf("Failed to GET from url='%s', err=%s", url, err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Printf("Failed to ReadAll response body of url='%s', err=%s", url, err)
+		return nil, err
+	}
+
+	return &FetchResult{
+		URL:           url,
+		HTML:          string(body),
+		StatusCode:    resp.StatusCode,
+		ContentType:   resp.Header.Get("Content-Type"),
+		ContentLength: int64(len(body)),
+		FetchedAt:     start,
+		Duration:      time.Since(start),
+		Protocol:      resp.Proto,
+		RequestHeader: req.Header.Clone(),
+		Header:        resp.Header.Clone(),
+	}, nil
+}
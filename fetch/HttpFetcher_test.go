@@ -0,0 +1,36 @@
+package fetch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPFetcher_RecordsResponseMetadata(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "goCrawler-test", r.Header.Get("User-Agent"))
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("ETag", `"abc"`)
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("<html>missing</html>"))
+	}))
+	defer server.Close()
+
+	fetcher := NewHTTPFetcher(time.Second, "goCrawler-test")
+	result, err := fetcher.Fetch(server.URL)
+	require.NoError(t, err)
+
+	assert.Equal(t, server.URL, result.URL)
+	assert.Equal(t, "<html>missing</html>", result.HTML)
+	assert.Equal(t, http.StatusNotFound, result.StatusCode)
+	assert.Equal(t, "text/html; charset=utf-8", result.ContentType)
+	assert.Equal(t, int64(20), result.ContentLength)
+	assert.False(t, result.FetchedAt.IsZero())
+	assert.Equal(t, "HTTP/1.1", result.Protocol)
+	assert.Equal(t, "goCrawler-test", result.RequestHeader.Get("User-Agent"))
+	assert.Equal(t, `"abc"`, result.Header.Get("ETag"))
+}
@@ -1,7 +1,8 @@
-package main
+package fetch
 
 // IWebPage defines the interface for web page operations.
 type IWebPage interface {
     Load(urlToCrawl string) string
     GetAllLinks() map[string]string
+    LastResult() *FetchResult
 }
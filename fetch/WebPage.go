@@ -1,7 +1,9 @@
-package main
+// Package fetch downloads web pages and extracts their links.
+package fetch
 
 import (
 	"strings"
+	"time"
 
 	"github.com/PuerkitoBio/goquery"
 	// Added for mocking
@@ -9,13 +11,14 @@ import (
 
 // Fetcher interface defines the behavior for fetching HTML
 type Fetcher interface {
-	FetchHTML(url string) (string, error)
+	Fetch(url string) (*FetchResult, error)
 }
 
 // WebPage implements the logic for working with web pages.
 type WebPage struct {
-	htmlCache string
-	fetcher   Fetcher // Added fetcher dependency
+	htmlCache  string
+	lastResult *FetchResult
+	fetcher    Fetcher // Added fetcher dependency
 }
 
 // NewWebPage constructor now accepts a fetcher interface
@@ -25,13 +28,21 @@ func NewWebPage(fetcher Fetcher) *WebPage {
 
 // Load fetches the HTML content of the given URL and caches it.
 func (wp *WebPage) Load(urlToCrawl string) string {
-	html, err := wp.fetcher.FetchHTML(urlToCrawl)
+	fetchedAt := time.Now()
+	result, err := wp.fetcher.Fetch(urlToCrawl)
 	if err != nil {
+		wp.lastResult = &FetchResult{URL: urlToCrawl, FetchedAt: fetchedAt, Err: err}
 		// Handle error appropriately; for simplicity, just return an error message.
 		return "Failed to load the page: " + err.Error()
 	}
-	wp.htmlCache = html
-	return html
+	wp.lastResult = result
+	wp.htmlCache = result.HTML
+	return result.HTML
+}
+
+// LastResult returns the metadata of the last Load, with Err set if it failed.
+func (wp *WebPage) LastResult() *FetchResult {
+	return wp.lastResult
 }
 
 // GetAllLinks parses the cached HTML and returns all links as a map.
@@ -1,4 +1,4 @@
-package main
+package fetch
 
 import (
 	"fmt"
@@ -17,14 +17,14 @@ type mockFetcher struct {
 	mock.Mock
 }
 
-func (m *mockFetcher) FetchHTML(url string) (string, error) {
+func (m *mockFetcher) Fetch(url string) (*FetchResult, error) {
 	args := m.Called(url)
-	return args.String(0), args.Error(1)
+	return &FetchResult{URL: url, HTML: args.String(0), StatusCode: 200}, args.Error(1)
 }
 
 func TestGetAllLinks(t *testing.T) {
 	mockFetcher := new(mockFetcher)
-	mockFetcher.On("FetchHTML", "https://example.com").Return(`<html><body><a href="http://example.com">Example</a><a href="http://test.com">Test</a></body></html>`, nil)
+	mockFetcher.On("Fetch", "https://example.com").Return(`<html><body><a href="http://example.com">Example</a><a href="http://test.com">Test</a></body></html>`, nil)
 
 	wp := NewWebPage(mockFetcher)
 
@@ -42,7 +42,7 @@ func TestGetAllLinks(t *testing.T) {
 
 func TestGetAllLinks_EmptyHTML(t *testing.T) {
 	mockFetcher := new(mockFetcher)
-	mockFetcher.On("FetchHTML", "https://example.com").Return("", nil)
+	mockFetcher.On("Fetch", "https://example.com").Return("", nil)
 
 	wp := NewWebPage(mockFetcher)
 	wp.Load("https://example.com")
@@ -56,7 +56,7 @@ func TestGetAllLinks_EmptyHTML(t *testing.T) {
 
 func TestGetAllLinks_ParsingError(t *testing.T) {
 	mockFetcher := new(mockFetcher)
-	mockFetcher.On("FetchHTML", "https://example.com").Return("", fmt.Errorf("parsing error"))
+	mockFetcher.On("Fetch", "https://example.com").Return("", fmt.Errorf("parsing error"))
 
 	wp := NewWebPage(mockFetcher)
 	wp.Load("https://example.com")
@@ -70,7 +70,7 @@ func TestGetAllLinks_ParsingError(t *testing.T) {
 
 func TestGetAllLinks_MissingLinks(t *testing.T) {
 	mockFetcher := new(mockFetcher)
-	mockFetcher.On("FetchHTML", "https://example.com").Return(`<html><body></body></html>`, nil)
+	mockFetcher.On("Fetch", "https://example.com").Return(`<html><body></body></html>`, nil)
 
 	wp := NewWebPage(mockFetcher)
 	wp.Load("https://example.com")
@@ -84,7 +84,7 @@ func TestGetAllLinks_MissingLinks(t *testing.T) {
 
 func TestGetAllLinks_RelativeLinks(t *testing.T) {
 	mockFetcher := new(mockFetcher)
-	mockFetcher.On("FetchHTML", "https://example.com/base").Return(`<html><body><a href="./about">About</a></body></html>`, nil)
+	mockFetcher.On("Fetch", "https://example.com/base").Return(`<html><body><a href="./about">About</a></body></html>`, nil)
 
 	wp := NewWebPage(mockFetcher)
 	wp.Load("https://example.com/base")
@@ -101,7 +101,7 @@ func TestGetAllLinks_RelativeLinks(t *testing.T) {
 func TestGetAllLinks_FragmentLinks(t *testing.T) {
 	// Setup: Mock with HTML containing fragment links (optional test)
 	mockFetcher := new(mockFetcher)
-	mockFetcher.On("FetchHTML", "https://example.com").Return(`<html><body><a href="http://example.com/about#intro">Intro</a><a href="https://test.com/contact">Contact</a></body></html>`, nil)
+	mockFetcher.On("Fetch", "https://example.com").Return(`<html><body><a href="http://example.com/about#intro">Intro</a><a href="https://test.com/contact">Contact</a></body></html>`, nil)
 
 	wp := NewWebPage(mockFetcher)
 	wp.Load("https://example.com")
@@ -116,3 +116,21 @@ func TestGetAllLinks_FragmentLinks(t *testing.T) {
 		t.Errorf("Expected links to be %v, got %v instead", expectedLinks, links)
 	}
 }
+
+func TestLoad_KeepsLastResult(t *testing.T) {
+	mockFetcher := new(mockFetcher)
+	mockFetcher.On("Fetch", "https://example.com").Return(`<html></html>`, nil).Once()
+	mockFetcher.On("Fetch", "https://example.com/broken").Return("", fmt.Errorf("connection refused")).Once()
+
+	wp := NewWebPage(mockFetcher)
+
+	wp.Load("https://example.com")
+	if result := wp.LastResult(); result == nil || result.URL != "https://example.com" || result.StatusCode != 200 {
+		t.Errorf("Expected last result of https://example.com, got %v instead", result)
+	}
+
+	wp.Load("https://example.com/broken")
+	if result := wp.LastResult(); result == nil || result.Err == nil {
+		t.Errorf("Expected last result with an error after a failed load, got %v instead", result)
+	}
+}
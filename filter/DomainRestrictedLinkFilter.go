@@ -1,8 +1,10 @@
-package main
+package filter
 
 import (
 	"net/url"
 	"strings"
+
+	"goCrawler/urlutil"
 )
 
 // DomainRestrictedLinkFilter implements the LinkFilter interface to filter links based on domain restrictions.
@@ -12,7 +14,7 @@ type DomainRestrictedLinkFilter struct {
 
 // NewDomainRestrictedLinkFilter creates a new DomainRestrictedLinkFilter with the specified domain.
 func NewDomainRestrictedLinkFilter(domain string) *DomainRestrictedLinkFilter {
-	return &DomainRestrictedLinkFilter{domain: NormalizeDomain(domain)}
+	return &DomainRestrictedLinkFilter{domain: urlutil.NormalizeDomain(domain)}
 }
 
 // FilterLink checks if the link leads outside the specified domain or to a fragment identifier.
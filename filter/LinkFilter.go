@@ -1,4 +1,5 @@
-package main
+// Package filter decides which of the links found on a page a crawl follows.
+package filter
 
 // LinkFilter is an interface that requires any implementing type to have a FilterLink method.
 type LinkFilter interface {
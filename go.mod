@@ -2,14 +2,19 @@ module goCrawler
 
 go 1.21.1
 
-require github.com/stretchr/testify v1.9.0
+require (
+	github.com/PuerkitoBio/goquery v1.9.1
+	github.com/andybalholm/cascadia v1.3.2
+	github.com/klauspost/compress v1.17.11
+	github.com/pmezard/go-difflib v1.0.0
+	github.com/stretchr/testify v1.9.0
+	go.etcd.io/bbolt v1.3.10
+	golang.org/x/net v0.21.0
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
-	github.com/PuerkitoBio/goquery v1.9.1 // indirect
-	github.com/andybalholm/cascadia v1.3.2 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
-	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/stretchr/objx v0.5.2 // indirect
-	golang.org/x/net v0.21.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
+	golang.org/x/sys v0.17.0 // indirect
 )
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+
+	crawlerdb "goCrawler/db"
 )
 
 type IDatabase interface {
@@ -13,10 +15,14 @@ type IDatabase interface {
 	Exists(key string) (bool, error)
 	ListKeys() ([]string, error)
 	Count() (int, error)
+	Scan(prefix string, cursor string, limit int) (crawlerdb.ScanResult, error)
+	ScanRange(start string, end string, cursor string, limit int) (crawlerdb.ScanResult, error)
 }
 
 type InMemoryDatabase struct {
 	data map[string][]byte
+	// keys indexes data's keys for scans
+	keys crawlerdb.SortedKeys
 	mu   sync.RWMutex
 }
 
@@ -30,7 +36,7 @@ func (db *InMemoryDatabase) Store(key string, value []byte) error {
 	db.mu.Lock()
 	defer db.mu.Unlock()
 	fmt.Println("Storing key: ", key, " value: ", value)
-	db.data[key] = value
+	db.set(key, value)
 	return nil
 }
 
@@ -51,7 +57,7 @@ func (db *InMemoryDatabase) Delete(key string) error {
 	if _, exists := db.data[key]; !exists {
 		return errors.New("key not found")
 	}
-	delete(db.data, key)
+	db.remove(key)
 	return nil
 }
 
@@ -79,3 +85,32 @@ func (db *InMemoryDatabase) Count() (int, error) {
 	defer db.mu.RUnlock()
 	return len(db.data), nil
 }
+
+// Scan takes the write lock, as it merges the keys stored since the last scan into the index.
+func (db *InMemoryDatabase) Scan(prefix string, cursor string, limit int) (crawlerdb.ScanResult, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.keys.Scan(prefix, cursor, limit), nil
+}
+
+func (db *InMemoryDatabase) ScanRange(start string, end string, cursor string, limit int) (crawlerdb.ScanResult, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.keys.ScanRange(start, end, cursor, limit), nil
+}
+
+// set and remove change a key and the index of keys; the caller holds the lock.
+func (db *InMemoryDatabase) set(key string, value []byte) {
+	if _, exists := db.data[key]; !exists {
+		db.keys.Add(key)
+	}
+	db.data[key] = value
+}
+
+func (db *InMemoryDatabase) remove(key string) {
+	if _, exists := db.data[key]; !exists {
+		return
+	}
+	delete(db.data, key)
+	db.keys.Remove(key)
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"goCrawler/db"
+	"goCrawler/db/protocol"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newContractServer runs the real jkdb handlers for a db.RemoteDatabase to talk to.
+func newContractServer(t *testing.T) (*httptest.Server, *InMemoryDatabase) {
+	var ready atomic.Bool
+	ready.Store(true)
+	database := NewInMemoryDatabase()
+	server := httptest.NewServer(newServerMux(database, &ready))
+	t.Cleanup(server.Close)
+	return server, database
+}
+
+func TestRemoteDatabase_Contract(t *testing.T) {
+	keys := []string{
+		"https://example.com/",
+		"https://example.com/search?q=go&page=2",
+		"https://example.com/a b/#section",
+		"https://example.com/100%/ünïcode",
+		"crawlrun:2024-01-01T00:00:00Z",
+	}
+
+	for _, key := range keys {
+		t.Run(key, func(t *testing.T) {
+			server, serverDatabase := newContractServer(t)
+			sut := db.NewRemoteDatabase(server.URL)
+
+			require.NoError(t, sut.Store(key, []byte("value of "+key)))
+			stored, err := serverDatabase.Read(key)
+			require.NoError(t, err, "the server stores the exact key")
+			assert.Equal(t, "value of "+key, string(stored))
+
+			value, err := sut.Read(key)
+			require.NoError(t, err)
+			assert.Equal(t, "value of "+key, string(value))
+			exists, err := sut.Exists(key)
+			require.NoError(t, err)
+			assert.True(t, exists)
+			listed, err := sut.ListKeys()
+			require.NoError(t, err)
+			assert.Equal(t, []string{key}, listed)
+			count, err := sut.Count()
+			require.NoError(t, err)
+			assert.Equal(t, 1, count)
+
+			require.NoError(t, sut.Delete(key))
+			exists, err = sut.Exists(key)
+			require.NoError(t, err)
+			assert.False(t, exists)
+			_, err = sut.Read(key)
+			assert.Error(t, err)
+			assert.Error(t, sut.Delete(key))
+		})
+	}
+}
+
+func TestRemoteDatabase_ListKeys(t *testing.T) {
+	server, serverDatabase := newContractServer(t)
+	serverDatabase.Store("b", []byte("2"))
+	serverDatabase.Store("a", []byte("1"))
+
+	keys, err := db.NewRemoteDatabase(server.URL).ListKeys()
+
+	require.NoError(t, err)
+	sort.Strings(keys)
+	assert.Equal(t, []string{"a", "b"}, keys)
+}
+
+func TestRemoteDatabase_Scan(t *testing.T) {
+	server, serverDatabase := newContractServer(t)
+	for _, key := range []string{"https://a.com/?x=1&y=2", "https://a.com/b", "https://b.com/", "crawlrun:1"} {
+		serverDatabase.Store(key, nil)
+	}
+	sut := db.NewRemoteDatabase(server.URL)
+
+	first, err := sut.Scan("https://a.com/", "", 1)
+	require.NoError(t, err)
+	assert.Equal(t, db.ScanResult{Keys: []string{"https://a.com/?x=1&y=2"}, NextCursor: "https://a.com/?x=1&y=2"}, first)
+	second, err := sut.Scan("https://a.com/", first.NextCursor, 1)
+	require.NoError(t, err)
+	assert.Equal(t, db.ScanResult{Keys: []string{"https://a.com/b"}}, second)
+
+	keys := make([]string, 0)
+	require.NoError(t, db.ForEachKey(sut, "", func(key string) error {
+		keys = append(keys, key)
+		return nil
+	}))
+	assert.Equal(t, []string{"crawlrun:1", "https://a.com/?x=1&y=2", "https://a.com/b", "https://b.com/"}, keys)
+}
+
+func TestRemoteDatabase_ScanRange(t *testing.T) {
+	server, serverDatabase := newContractServer(t)
+	for _, key := range []string{"https://a.com/", "https://b.com/?x=1&y=2", "https://b.com/z", "https://c.com/"} {
+		serverDatabase.Store(key, nil)
+	}
+	sut := db.NewRemoteDatabase(server.URL)
+
+	first, err := sut.ScanRange("https://b", "https://c", "", 1)
+	require.NoError(t, err)
+	assert.Equal(t, db.ScanResult{Keys: []string{"https://b.com/?x=1&y=2"}, NextCursor: "https://b.com/?x=1&y=2"}, first)
+	second, err := sut.ScanRange("https://b", "https://c", first.NextCursor, 1)
+	require.NoError(t, err)
+	assert.Equal(t, db.ScanResult{Keys: []string{"https://b.com/z"}}, second)
+	unbounded, err := sut.ScanRange("https://b.com/z", "", "", 0)
+	require.NoError(t, err)
+	assert.Equal(t, db.ScanResult{Keys: []string{"https://b.com/z", "https://c.com/"}}, unbounded)
+}
+
+func TestServer_ProtocolVersion(t *testing.T) {
+	server, _ := newContractServer(t)
+
+	tests := []struct {
+		name     string
+		version  string
+		expected int
+	}{
+		{"Current version", protocol.Version, http.StatusOK},
+		{"Client without version header", "", http.StatusOK},
+		{"Unsupported version", "99", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, server.URL+protocol.StorePath, strings.NewReader(`{"key":"a","value":"MQ=="}`))
+			require.NoError(t, err)
+			if tt.version != "" {
+				req.Header.Set(protocol.VersionHeader, tt.version)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+
+			assert.Equal(t, tt.expected, resp.StatusCode)
+			assert.Equal(t, protocol.Version, resp.Header.Get(protocol.VersionHeader))
+		})
+	}
+}
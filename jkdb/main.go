@@ -1,10 +1,78 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
 func main() {
-	fmt.Println("Hello, World!")
-	RunServer()
+	addr := flag.String("addr", envOrDefault("JKDB_ADDR", ":8080"), "Address to listen on (env JKDB_ADDR)")
+	dataDir := flag.String("dataDir", envOrDefault("JKDB_DATA_DIR", ""),
+		"Directory keeping the data across restarts; without one, data is kept in memory only (env JKDB_DATA_DIR)")
+	fsync := flag.String("fsync", envOrDefault("JKDB_FSYNC", string(FsyncAlways)),
+		"When the write-ahead log is flushed to disk: always, interval (every second) or never (env JKDB_FSYNC)")
+	snapshotInterval := flag.Duration("snapshotInterval", durationEnvOrDefault("JKDB_SNAPSHOT_INTERVAL", 5*time.Minute),
+		"How often a snapshot is written and the write-ahead log started over, 0 disables periodic snapshots (env JKDB_SNAPSHOT_INTERVAL)")
+	drainDelay := flag.Duration("drainDelay", durationEnvOrDefault("JKDB_DRAIN_DELAY", 5*time.Second),
+		"How long /readyz reports not ready before the server stops accepting connections on shutdown (env JKDB_DRAIN_DELAY)")
+	shutdownTimeout := flag.Duration("shutdownTimeout", durationEnvOrDefault("JKDB_SHUTDOWN_TIMEOUT", 30*time.Second),
+		"How long in-flight requests may take to finish on shutdown (env JKDB_SHUTDOWN_TIMEOUT)")
+	flag.Parse()
+
+	fsyncPolicy, err := ParseFsyncPolicy(*fsync)
+	if err != nil {
+		log.Println(err)
+		os.Exit(2)
+	}
+
+	var db IDatabase = NewInMemoryDatabase()
+	if *dataDir != "" {
+		persistentDb, err := OpenPersistentDatabase(*dataDir, fsyncPolicy, *snapshotInterval)
+		if err != nil {
+			log.Fatalf("Opening %s failed: %v", *dataDir, err)
+		}
+		db = persistentDb
+	} else {
+		log.Println("WARNING: No -dataDir given, all data is lost when the server stops")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	serverErr := RunServer(ctx, *addr, db, *drainDelay, *shutdownTimeout)
+
+	// The database is closed only after the last request finished, so nothing stored is lost
+	if persistentDb, ok := db.(*PersistentDatabase); ok {
+		if err := persistentDb.Close(); err != nil {
+			log.Printf("ERROR: Closing %s failed: %v", *dataDir, err)
+		}
+	}
+	if serverErr != nil {
+		log.Fatal(serverErr)
+	}
+}
+
+func envOrDefault(name string, fallback string) string {
+	if value, found := os.LookupEnv(name); found {
+		return value
+	}
+	return fallback
+}
+
+func durationEnvOrDefault(name string, fallback time.Duration) time.Duration {
+	value, found := os.LookupEnv(name)
+	if !found {
+		return fallback
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid %s %q: %v\n", name, value, err)
+		os.Exit(2)
+	}
+	return duration
 }
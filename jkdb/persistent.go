@@ -0,0 +1,309 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FsyncPolicy selects when the write-ahead log is flushed to disk. With FsyncAlways a stored key survives
+// a power loss as soon as Store returns; FsyncInterval may lose the last second, FsyncNever whatever the
+// operating system hadn't written yet. A crash of jkdb alone loses nothing with any policy.
+type FsyncPolicy string
+
+const (
+	FsyncAlways   FsyncPolicy = "always"
+	FsyncInterval FsyncPolicy = "interval"
+	FsyncNever    FsyncPolicy = "never"
+
+	walFilename      = "wal"
+	snapshotFilename = "snapshot"
+	fsyncInterval    = time.Second
+)
+
+func ParseFsyncPolicy(policy string) (FsyncPolicy, error) {
+	switch FsyncPolicy(policy) {
+	case FsyncAlways, FsyncInterval, FsyncNever:
+		return FsyncPolicy(policy), nil
+	}
+	return "", fmt.Errorf("unknown fsync policy %q, expected %q, %q or %q", policy, FsyncAlways, FsyncInterval, FsyncNever)
+}
+
+// walFile is the open write-ahead log, an *os.File opened for appending.
+type walFile interface {
+	io.WriteCloser
+	Sync() error
+	Truncate(size int64) error
+}
+
+// PersistentDatabase is an InMemoryDatabase whose changes are appended to a write-ahead log in directory
+// before they are applied. A snapshot of all keys is written periodically, after which the log starts over.
+// On startup the snapshot is loaded and the log replayed on top of it.
+type PersistentDatabase struct {
+	*InMemoryDatabase
+	// mu orders log appends and snapshots; reads only take the lock of the InMemoryDatabase
+	mu        sync.Mutex
+	directory string
+	wal       walFile
+	// walSize is where the next record starts, the log is cut back to it when appending fails
+	walSize  int64
+	fsync    FsyncPolicy
+	unsynced bool
+	// failed is set once a failed append couldn't be undone; every later write fails with it
+	failed  error
+	stop    chan struct{}
+	stopped sync.WaitGroup
+}
+
+// OpenPersistentDatabase loads the data in directory, creating it if needed. With a positive snapshotInterval
+// a snapshot is written that often.
+func OpenPersistentDatabase(directory string, fsync FsyncPolicy, snapshotInterval time.Duration) (*PersistentDatabase, error) {
+	if err := os.MkdirAll(directory, 0o755); err != nil {
+		return nil, err
+	}
+	db := &PersistentDatabase{
+		InMemoryDatabase: NewInMemoryDatabase(),
+		directory:        directory,
+		fsync:            fsync,
+		stop:             make(chan struct{}),
+	}
+
+	if err := db.loadSnapshot(); err != nil {
+		return nil, err
+	}
+	if err := db.replayWal(); err != nil {
+		return nil, err
+	}
+	for key := range db.data {
+		db.keys.Add(key)
+	}
+	wal, err := os.OpenFile(filepath.Join(directory, walFilename), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := wal.Stat()
+	if err != nil {
+		wal.Close()
+		return nil, err
+	}
+	db.wal = wal
+	db.walSize = info.Size()
+
+	db.stopped.Add(1)
+	go db.background(snapshotInterval)
+	return db, nil
+}
+
+func (db *PersistentDatabase) Store(key string, value []byte) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if err := db.append(walRecord{op: opStore, key: key, value: value}); err != nil {
+		return err
+	}
+	return db.InMemoryDatabase.Store(key, value)
+}
+
+func (db *PersistentDatabase) Delete(key string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if exists, _ := db.InMemoryDatabase.Exists(key); !exists {
+		return errors.New("key not found")
+	}
+	if err := db.append(walRecord{op: opDelete, key: key}); err != nil {
+		return err
+	}
+	return db.InMemoryDatabase.Delete(key)
+}
+
+// append writes record to the write-ahead log. A partially written record is cut off again, since replaying
+// stops at the first torn record and would drop everything appended after it.
+func (db *PersistentDatabase) append(record walRecord) error {
+	if db.failed != nil {
+		return db.failed
+	}
+	n, err := db.wal.Write(record.encode())
+	if err != nil {
+		if truncateErr := db.wal.Truncate(db.walSize); truncateErr != nil {
+			db.failed = fmt.Errorf("write-ahead log unusable after a failed write, cutting off the torn record: %w", truncateErr)
+			log.Printf("ERROR: %v", db.failed)
+		}
+		return fmt.Errorf("writing the write-ahead log: %w", err)
+	}
+	db.walSize += int64(n)
+	if db.fsync == FsyncAlways {
+		return db.wal.Sync()
+	}
+	db.unsynced = true
+	return nil
+}
+
+// Snapshot writes all keys to a new snapshot file and empties the write-ahead log. Writes wait until it is done.
+func (db *PersistentDatabase) Snapshot() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	temp, err := os.CreateTemp(db.directory, snapshotFilename+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+
+	db.InMemoryDatabase.mu.RLock()
+	for key, value := range db.data {
+		if _, err = temp.Write(walRecord{op: opStore, key: key, value: value}.encode()); err != nil {
+			break
+		}
+	}
+	db.InMemoryDatabase.mu.RUnlock()
+	if err == nil {
+		err = temp.Sync()
+	}
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("writing snapshot: %w", err)
+	}
+	if err := os.Rename(temp.Name(), filepath.Join(db.directory, snapshotFilename)); err != nil {
+		return err
+	}
+	syncDirectory(db.directory)
+
+	// Replaying the log over the new snapshot would be harmless, so a crash before this point loses nothing
+	if err := db.wal.Truncate(0); err != nil {
+		return err
+	}
+	db.walSize = 0
+	db.unsynced = false
+	return db.wal.Sync()
+}
+
+// Close writes a final snapshot and closes the write-ahead log.
+func (db *PersistentDatabase) Close() error {
+	close(db.stop)
+	db.stopped.Wait()
+	err := db.Snapshot()
+	if closeErr := db.wal.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func (db *PersistentDatabase) background(snapshotInterval time.Duration) {
+	defer db.stopped.Done()
+
+	var snapshots <-chan time.Time
+	if snapshotInterval > 0 {
+		ticker := time.NewTicker(snapshotInterval)
+		defer ticker.Stop()
+		snapshots = ticker.C
+	}
+	var syncs <-chan time.Time
+	if db.fsync == FsyncInterval {
+		ticker := time.NewTicker(fsyncInterval)
+		defer ticker.Stop()
+		syncs = ticker.C
+	}
+
+	for {
+		select {
+		case <-db.stop:
+			return
+		case <-syncs:
+			db.mu.Lock()
+			if db.unsynced {
+				if err := db.wal.Sync(); err != nil {
+					log.Printf("ERROR: Syncing the write-ahead log failed: %v", err)
+				}
+				db.unsynced = false
+			}
+			db.mu.Unlock()
+		case <-snapshots:
+			if err := db.Snapshot(); err != nil {
+				log.Printf("ERROR: Writing a snapshot failed: %v", err)
+			}
+		}
+	}
+}
+
+func (db *PersistentDatabase) loadSnapshot() error {
+	file, err := os.Open(filepath.Join(db.directory, snapshotFilename))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := newWalReader(file)
+	for {
+		record, err := reader.next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			// Snapshots are renamed into place once complete, so this is corruption rather than a crash
+			return fmt.Errorf("snapshot corrupt at offset %d: %w", reader.offset, err)
+		}
+		db.apply(record)
+	}
+}
+
+// replayWal applies the write-ahead log. A torn record at its end, left by a crash during a write,
+// is cut off; everything before it was stored.
+func (db *PersistentDatabase) replayWal() error {
+	path := filepath.Join(db.directory, walFilename)
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := newWalReader(file)
+	replayed := 0
+	for {
+		record, err := reader.next()
+		if err == io.EOF {
+			break
+		}
+		if err == errTornRecord {
+			log.Printf("WARNING: Cutting off a torn record at offset %d of %s", reader.offset, path)
+			if err := os.Truncate(path, reader.offset); err != nil {
+				return err
+			}
+			break
+		}
+		if err != nil {
+			return err
+		}
+		db.apply(record)
+		replayed++
+	}
+	log.Printf("Replayed %d changes from %s", replayed, path)
+	return nil
+}
+
+// apply changes only the data while loading; the index of keys is built once everything is loaded.
+func (db *PersistentDatabase) apply(record walRecord) {
+	if record.op == opDelete {
+		delete(db.data, record.key)
+	} else {
+		db.data[record.key] = record.value
+	}
+}
+
+func syncDirectory(directory string) {
+	if dir, err := os.Open(directory); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+}
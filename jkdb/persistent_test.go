@@ -0,0 +1,165 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openDatabase(t *testing.T, directory string) *PersistentDatabase {
+	db, err := OpenPersistentDatabase(directory, FsyncAlways, 0)
+	require.NoError(t, err)
+	return db
+}
+
+// crash stops db without the final snapshot of Close, like a killed process.
+func crash(db *PersistentDatabase) {
+	close(db.stop)
+	db.stopped.Wait()
+	db.wal.Close()
+}
+
+func assertKeys(t *testing.T, db IDatabase, expected map[string]string) {
+	count, err := db.Count()
+	require.NoError(t, err)
+	assert.Equal(t, len(expected), count)
+	for key, value := range expected {
+		stored, err := db.Read(key)
+		require.NoError(t, err, key)
+		assert.Equal(t, value, string(stored), key)
+	}
+}
+
+func TestPersistentDatabase_ReplaysWalAfterCrash(t *testing.T) {
+	directory := t.TempDir()
+	db := openDatabase(t, directory)
+	require.NoError(t, db.Store("https://example.com", []byte("v1")))
+	require.NoError(t, db.Store("https://example.com", []byte("v2")))
+	require.NoError(t, db.Store("https://example.org", []byte("v1")))
+	require.NoError(t, db.Delete("https://example.org"))
+	assert.Error(t, db.Delete("https://example.org"))
+	crash(db)
+
+	reopened := openDatabase(t, directory)
+	assertKeys(t, reopened, map[string]string{"https://example.com": "v2"})
+	result, err := reopened.Scan("https://", "", 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://example.com"}, result.Keys, "replaying keeps the scan index")
+}
+
+func TestPersistentDatabase_CutsOffTornRecord(t *testing.T) {
+	directory := t.TempDir()
+	db := openDatabase(t, directory)
+	require.NoError(t, db.Store("a", []byte("1")))
+	require.NoError(t, db.Store("b", []byte("2")))
+	crash(db)
+
+	walPath := filepath.Join(directory, walFilename)
+	info, err := os.Stat(walPath)
+	require.NoError(t, err)
+	require.NoError(t, os.Truncate(walPath, info.Size()-2))
+
+	reopened := openDatabase(t, directory)
+	assertKeys(t, reopened, map[string]string{"a": "1"})
+	require.NoError(t, reopened.Store("c", []byte("3")))
+	require.NoError(t, reopened.Close())
+
+	assertKeys(t, openDatabase(t, directory), map[string]string{"a": "1", "c": "3"})
+}
+
+// failingWal writes only the first few bytes of each record while failWrites is set, like a full disk.
+type failingWal struct {
+	walFile
+	failWrites    bool
+	failTruncates bool
+}
+
+func (w *failingWal) Write(data []byte) (int, error) {
+	if w.failWrites {
+		n, _ := w.walFile.Write(data[:3])
+		return n, syscall.ENOSPC
+	}
+	return w.walFile.Write(data)
+}
+
+func (w *failingWal) Truncate(size int64) error {
+	if w.failTruncates {
+		return syscall.EIO
+	}
+	return w.walFile.Truncate(size)
+}
+
+func TestPersistentDatabase_FailedAppendKeepsLaterWrites(t *testing.T) {
+	directory := t.TempDir()
+	db := openDatabase(t, directory)
+	require.NoError(t, db.Store("a", []byte("1")))
+	wal := &failingWal{walFile: db.wal, failWrites: true}
+	db.wal = wal
+
+	assert.ErrorIs(t, db.Store("b", []byte("2")), syscall.ENOSPC)
+	wal.failWrites = false
+	require.NoError(t, db.Store("c", []byte("3")))
+	assertKeys(t, db, map[string]string{"a": "1", "c": "3"})
+	crash(db)
+
+	assertKeys(t, openDatabase(t, directory), map[string]string{"a": "1", "c": "3"})
+}
+
+func TestPersistentDatabase_FailsForGoodWhenTornRecordStays(t *testing.T) {
+	directory := t.TempDir()
+	db := openDatabase(t, directory)
+	require.NoError(t, db.Store("a", []byte("1")))
+	wal := &failingWal{walFile: db.wal, failWrites: true, failTruncates: true}
+	db.wal = wal
+
+	assert.ErrorIs(t, db.Store("b", []byte("2")), syscall.ENOSPC)
+	wal.failWrites = false
+	wal.failTruncates = false
+	assert.ErrorIs(t, db.Store("c", []byte("3")), syscall.EIO)
+	assert.ErrorIs(t, db.Delete("a"), syscall.EIO)
+	assertKeys(t, db, map[string]string{"a": "1"})
+	crash(db)
+
+	assertKeys(t, openDatabase(t, directory), map[string]string{"a": "1"})
+}
+
+func TestPersistentDatabase_SnapshotEmptiesWal(t *testing.T) {
+	directory := t.TempDir()
+	db := openDatabase(t, directory)
+	require.NoError(t, db.Store("a", []byte("1")))
+	require.NoError(t, db.Store("b", []byte("2")))
+
+	require.NoError(t, db.Snapshot())
+
+	info, err := os.Stat(filepath.Join(directory, walFilename))
+	require.NoError(t, err)
+	assert.Zero(t, info.Size())
+	require.NoError(t, db.Delete("a"))
+	require.NoError(t, db.Store("b", []byte("3")))
+	crash(db)
+
+	assertKeys(t, openDatabase(t, directory), map[string]string{"b": "3"})
+}
+
+func TestPersistentDatabase_RejectsCorruptSnapshot(t *testing.T) {
+	directory := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(directory, snapshotFilename), []byte{opStore, 1, 'a', 1, '1', 0, 0, 0, 0}, 0o644))
+
+	_, err := OpenPersistentDatabase(directory, FsyncAlways, 0)
+
+	assert.ErrorContains(t, err, "snapshot corrupt")
+}
+
+func TestParseFsyncPolicy(t *testing.T) {
+	for _, policy := range []string{"always", "interval", "never"} {
+		parsed, err := ParseFsyncPolicy(policy)
+		require.NoError(t, err)
+		assert.Equal(t, FsyncPolicy(policy), parsed)
+	}
+	_, err := ParseFsyncPolicy("sometimes")
+	assert.Error(t, err)
+}
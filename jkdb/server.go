@@ -1,30 +1,30 @@
 package main
 
 import (
-	"encoding/base64"
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	crawlerdb "goCrawler/db"
+	"goCrawler/db/protocol"
 )
 
 func storeHandler(db IDatabase) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var req struct {
-			Key   string `json:"key"`
-			Value string `json:"value"`
-		}
+		var req protocol.StoreRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			http.Error(w, "Invalid request", http.StatusBadRequest)
 			return
 		}
-		value, err := base64.StdEncoding.DecodeString(req.Value)
-		if err != nil {
-			http.Error(w, "Invalid base64 data", http.StatusBadRequest)
-			return
-		}
-		fmt.Println("Server::storeHandler: Storing key: ", req.Key, " value: ", value)
-		if err := db.Store(req.Key, value); err != nil {
+		fmt.Println("Server::storeHandler: Storing key: ", req.Key, " value: ", req.Value)
+		if err := db.Store(req.Key, req.Value); err != nil {
 			http.Error(w, "Error storing value", http.StatusInternalServerError)
 			return
 		}
@@ -34,26 +34,23 @@ func storeHandler(db IDatabase) http.HandlerFunc {
 
 func readHandler(db IDatabase) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		key := r.URL.Query().Get("key")
+		key := r.URL.Query().Get(protocol.KeyParameter)
 		value, err := db.Read(key)
 		if err != nil {
 			http.Error(w, "Key not found", http.StatusNotFound)
 			return
 		}
-		response := struct {
-			Key   string `json:"key"`
-			Value string `json:"value"`
-		}{
-			Key:   key,
-			Value: base64.StdEncoding.EncodeToString(value),
-		}
-		json.NewEncoder(w).Encode(response)
+		json.NewEncoder(w).Encode(protocol.ReadResponse{Key: key, Value: value})
 	}
 }
 
 func deleteHandler(db IDatabase) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		key := r.URL.Query().Get("key")
+		key := r.URL.Query().Get(protocol.KeyParameter)
+		if exists, err := db.Exists(key); err == nil && !exists {
+			http.Error(w, "Key not found", http.StatusNotFound)
+			return
+		}
 		if err := db.Delete(key); err != nil {
 			http.Error(w, "Error deleting key", http.StatusInternalServerError)
 			return
@@ -64,19 +61,14 @@ func deleteHandler(db IDatabase) http.HandlerFunc {
 
 func existsHandler(db IDatabase) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		key := r.URL.Query().Get("key")
+		key := r.URL.Query().Get(protocol.KeyParameter)
 		fmt.Println("Server::existsHandler: Checking existence of key: ", key)
 		exists, err := db.Exists(key)
 		if err != nil {
 			http.Error(w, "Error checking existence", http.StatusInternalServerError)
 			return
 		}
-		response := struct {
-			Exists bool `json:"exists"`
-		}{
-			Exists: exists,
-		}
-		json.NewEncoder(w).Encode(response)
+		json.NewEncoder(w).Encode(protocol.ExistsResponse{Exists: exists})
 	}
 }
 
@@ -87,12 +79,7 @@ func listKeysHandler(db IDatabase) http.HandlerFunc {
 			http.Error(w, "Error listing keys", http.StatusInternalServerError)
 			return
 		}
-		response := struct {
-			Keys []string `json:"keys"`
-		}{
-			Keys: keys,
-		}
-		json.NewEncoder(w).Encode(response)
+		json.NewEncoder(w).Encode(protocol.KeysResponse{Keys: keys})
 	}
 }
 
@@ -103,24 +90,150 @@ func countHandler(db IDatabase) http.HandlerFunc {
 			http.Error(w, "Error counting keys", http.StatusInternalServerError)
 			return
 		}
-		response := struct {
-			Count int `json:"count"`
-		}{
-			Count: count,
+		json.NewEncoder(w).Encode(protocol.CountResponse{Count: count})
+	}
+}
+
+func scanHandler(db IDatabase) http.HandlerFunc {
+	return pageHandler(func(query url.Values, limit int) (crawlerdb.ScanResult, error) {
+		return db.Scan(query.Get(protocol.PrefixParameter), query.Get(protocol.CursorParameter), limit)
+	})
+}
+
+func rangeHandler(db IDatabase) http.HandlerFunc {
+	return pageHandler(func(query url.Values, limit int) (crawlerdb.ScanResult, error) {
+		return db.ScanRange(query.Get(protocol.StartParameter), query.Get(protocol.EndParameter), query.Get(protocol.CursorParameter), limit)
+	})
+}
+
+// pageHandler answers with one page of keys from scan, for the limit requested but at most DefaultScanLimit.
+func pageHandler(scan func(query url.Values, limit int) (crawlerdb.ScanResult, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		limit := 0
+		if rawLimit := query.Get(protocol.LimitParameter); rawLimit != "" {
+			var err error
+			if limit, err = strconv.Atoi(rawLimit); err != nil {
+				http.Error(w, "Invalid limit", http.StatusBadRequest)
+				return
+			}
+		}
+		if limit <= 0 || limit > crawlerdb.DefaultScanLimit {
+			limit = crawlerdb.DefaultScanLimit
+		}
+
+		result, err := scan(query, limit)
+		if err != nil {
+			http.Error(w, "Error scanning keys", http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(protocol.ScanResponse{Keys: result.Keys, NextCursor: result.NextCursor})
+	}
+}
+
+// checkProtocolVersion answers every request with the protocol version and rejects requests for another one.
+func checkProtocolVersion(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(protocol.VersionHeader, protocol.Version)
+		if version := r.Header.Get(protocol.VersionHeader); !protocol.SupportedVersion(version) {
+			http.Error(w, fmt.Sprintf("Unsupported protocol version %q, expected %q", version, protocol.Version), http.StatusBadRequest)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func healthHandler(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("ok\n"))
+}
+
+// readyHandler reports whether requests should be sent to this server; it stops being ready when shutting down.
+func readyHandler(ready *atomic.Bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !ready.Load() {
+			http.Error(w, "shutting down", http.StatusServiceUnavailable)
+			return
 		}
-		json.NewEncoder(w).Encode(response)
+		w.Write([]byte("ok\n"))
 	}
 }
 
-func RunServer() {
-	db := NewInMemoryDatabase()
-	http.HandleFunc("/db/store", storeHandler(db))
-	http.HandleFunc("/db/read", readHandler(db))
-	http.HandleFunc("/db/delete", deleteHandler(db))
-	http.HandleFunc("/db/exists", existsHandler(db))
-	http.HandleFunc("/db/keys", listKeysHandler(db))
-	http.HandleFunc("/db/count", countHandler(db))
-	log.Println("Server started on port 8080")
-	log.Fatal(http.ListenAndServe(":8080", nil))
-	http.ListenAndServe(":8080", nil)
+func newServerMux(db IDatabase, ready *atomic.Bool) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle(protocol.StorePath, checkProtocolVersion(storeHandler(db)))
+	mux.Handle(protocol.ReadPath, checkProtocolVersion(readHandler(db)))
+	mux.Handle(protocol.DeletePath, checkProtocolVersion(deleteHandler(db)))
+	mux.Handle(protocol.ExistsPath, checkProtocolVersion(existsHandler(db)))
+	mux.Handle(protocol.KeysPath, checkProtocolVersion(listKeysHandler(db)))
+	mux.Handle(protocol.CountPath, checkProtocolVersion(countHandler(db)))
+	mux.Handle(protocol.ScanPath, checkProtocolVersion(scanHandler(db)))
+	mux.Handle(protocol.RangePath, checkProtocolVersion(rangeHandler(db)))
+	mux.HandleFunc("/healthz", healthHandler)
+	mux.HandleFunc("/readyz", readyHandler(ready))
+	return mux
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// logRequests logs method, path, status and duration of every request.
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+		log.Printf("%s %s %d %s", r.Method, r.URL.Path, recorder.status, time.Since(start))
+	})
+}
+
+// RunServer serves db on addr until ctx is done. It then reports not ready on /readyz while still serving for
+// drainDelay, so load balancers stop sending requests, and waits up to shutdownTimeout for in-flight requests.
+func RunServer(ctx context.Context, addr string, db IDatabase, drainDelay time.Duration, shutdownTimeout time.Duration) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	log.Printf("Server listening on %s", listener.Addr())
+	return serve(ctx, listener, db, drainDelay, shutdownTimeout)
+}
+
+func serve(ctx context.Context, listener net.Listener, db IDatabase, drainDelay time.Duration, shutdownTimeout time.Duration) error {
+	var ready atomic.Bool
+	ready.Store(true)
+	server := &http.Server{Handler: logRequests(newServerMux(db, &ready))}
+
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(listener)
+	}()
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Printf("Shutting down, draining for %s", drainDelay)
+	ready.Store(false)
+	select {
+	case err := <-served:
+		return err
+	case <-time.After(drainDelay):
+	}
+
+	log.Println("Waiting for in-flight requests")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("shutdown: %w", err)
+	}
+	<-served
+	return nil
 }
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingDatabase holds every Store until release is closed.
+type blockingDatabase struct {
+	*InMemoryDatabase
+	storing chan struct{}
+	release chan struct{}
+}
+
+func (db *blockingDatabase) Store(key string, value []byte) error {
+	close(db.storing)
+	<-db.release
+	return db.InMemoryDatabase.Store(key, value)
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	var ready atomic.Bool
+	ready.Store(true)
+	mux := newServerMux(NewInMemoryDatabase(), &ready)
+
+	tests := []struct {
+		name     string
+		path     string
+		ready    bool
+		expected int
+	}{
+		{"Healthy", "/healthz", true, http.StatusOK},
+		{"Healthy while shutting down", "/healthz", false, http.StatusOK},
+		{"Ready", "/readyz", true, http.StatusOK},
+		{"Not ready while shutting down", "/readyz", false, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ready.Store(tt.ready)
+			recorder := httptest.NewRecorder()
+			mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			assert.Equal(t, tt.expected, recorder.Code)
+		})
+	}
+}
+
+func TestLogRequests(t *testing.T) {
+	var output bytes.Buffer
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(&output)
+	handler := logRequests(http.NotFoundHandler())
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/db/read?key=a", nil))
+
+	assert.Contains(t, output.String(), "GET /db/read 404")
+}
+
+func TestServe_DrainsInFlightRequestsOnShutdown(t *testing.T) {
+	db := &blockingDatabase{InMemoryDatabase: NewInMemoryDatabase(), storing: make(chan struct{}), release: make(chan struct{})}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- serve(ctx, listener, db, 0, 5*time.Second)
+	}()
+
+	responses := make(chan int, 1)
+	go func() {
+		resp, err := http.Post("http://"+listener.Addr().String()+"/db/store", "application/json",
+			strings.NewReader(`{"key":"a","value":"MQ=="}`))
+		if err != nil {
+			responses <- 0
+			return
+		}
+		resp.Body.Close()
+		responses <- resp.StatusCode
+	}()
+	<-db.storing
+	cancel()
+
+	select {
+	case <-served:
+		t.Fatal("server stopped before the in-flight request finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(db.release)
+
+	assert.Equal(t, http.StatusOK, <-responses)
+	assert.NoError(t, <-served)
+	value, err := db.Read("a")
+	require.NoError(t, err)
+	assert.Equal(t, "1", string(value))
+}
+
+func TestServe_ReportsNotReadyWhileDraining(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- serve(ctx, listener, NewInMemoryDatabase(), 200*time.Millisecond, 5*time.Second)
+	}()
+	readyz := func() int {
+		resp, err := http.Get("http://" + listener.Addr().String() + "/readyz")
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, http.StatusOK, readyz())
+	cancel()
+	require.Eventually(t, func() bool { return readyz() == http.StatusServiceUnavailable }, time.Second, 10*time.Millisecond)
+	resp, err := http.Get("http://" + listener.Addr().String() + "/db/count")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "requests are still served while draining")
+
+	assert.NoError(t, <-served)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+const (
+	opStore  byte = 1
+	opDelete byte = 2
+)
+
+// walRecord is a single change in the write-ahead log, or a single key in a snapshot.
+// On disk it is: op, uvarint key length, key, uvarint value length, value, CRC-32 of all of them.
+type walRecord struct {
+	op    byte
+	key   string
+	value []byte
+}
+
+var errTornRecord = errors.New("torn record")
+
+func (r walRecord) encode() []byte {
+	data := make([]byte, 0, 1+2*binary.MaxVarintLen64+len(r.key)+len(r.value)+4)
+	data = append(data, r.op)
+	data = binary.AppendUvarint(data, uint64(len(r.key)))
+	data = append(data, r.key...)
+	data = binary.AppendUvarint(data, uint64(len(r.value)))
+	data = append(data, r.value...)
+	return binary.BigEndian.AppendUint32(data, crc32.ChecksumIEEE(data))
+}
+
+// walReader reads records until the end of the log. A record cut short by a crash, or with a wrong
+// checksum, ends the log with errTornRecord; offset is then where the last intact record ended.
+type walReader struct {
+	reader *bufio.Reader
+	offset int64
+}
+
+func newWalReader(reader io.Reader) *walReader {
+	return &walReader{reader: bufio.NewReader(reader)}
+}
+
+func (w *walReader) next() (walRecord, error) {
+	var data []byte
+	readByte := func() (byte, error) {
+		b, err := w.reader.ReadByte()
+		if err == nil {
+			data = append(data, b)
+		}
+		return b, err
+	}
+	readBytes := func() ([]byte, error) {
+		length, err := binary.ReadUvarint(byteReaderFunc(readByte))
+		if err != nil {
+			return nil, err
+		}
+		if length > 1<<31 {
+			return nil, fmt.Errorf("length %d", length)
+		}
+		content := make([]byte, length)
+		if _, err := io.ReadFull(w.reader, content); err != nil {
+			return nil, err
+		}
+		data = append(data, content...)
+		return content, nil
+	}
+
+	op, err := readByte()
+	if err == io.EOF {
+		return walRecord{}, io.EOF
+	}
+	if err != nil {
+		return walRecord{}, err
+	}
+	key, err := readBytes()
+	if err != nil {
+		return walRecord{}, errTornRecord
+	}
+	value, err := readBytes()
+	if err != nil {
+		return walRecord{}, errTornRecord
+	}
+	var checksum [4]byte
+	if _, err := io.ReadFull(w.reader, checksum[:]); err != nil {
+		return walRecord{}, errTornRecord
+	}
+	if binary.BigEndian.Uint32(checksum[:]) != crc32.ChecksumIEEE(data) || (op != opStore && op != opDelete) {
+		return walRecord{}, errTornRecord
+	}
+
+	w.offset += int64(len(data) + len(checksum))
+	return walRecord{op: op, key: string(key), value: value}, nil
+}
+
+type byteReaderFunc func() (byte, error)
+
+func (f byteReaderFunc) ReadByte() (byte, error) {
+	return f()
+}
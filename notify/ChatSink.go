@@ -0,0 +1,77 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"text/template"
+	"time"
+
+	"goCrawler/urlutil"
+)
+
+// ChatOptions configures a ChatSink. Without Seeds the sink receives the digests of all seeds.
+type ChatOptions struct {
+	URL      string
+	Template string
+	Seeds    []string
+}
+
+// ChatSink posts digests to Slack or Mattermost style incoming webhooks as {"text": "..."}.
+type ChatSink struct {
+	options  ChatOptions
+	template *template.Template
+	client   *http.Client
+}
+
+func NewChatSink(options ChatOptions) (*ChatSink, error) {
+	tmpl, err := ParseTemplate("chat", options.Template, DefaultChatTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chat template: %w", err)
+	}
+	return &ChatSink{options: options, template: tmpl, client: &http.Client{Timeout: 30 * time.Second}}, nil
+}
+
+// Name is the URL without its path, which holds the webhook's token.
+func (c *ChatSink) Name() string {
+	return urlutil.RedactURL(c.options.URL, true)
+}
+
+func (c *ChatSink) Send(digest Digest) error {
+	if !c.wants(digest.Seed) {
+		return nil
+	}
+
+	text, err := render(c.template, digest)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.client.Post(c.options.URL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return redactUrlError(err, true)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("chat webhook responded with %s", resp.Status)
+	}
+	return nil
+}
+
+func (c *ChatSink) wants(seed string) bool {
+	if len(c.options.Seeds) == 0 {
+		return true
+	}
+	for _, wanted := range c.options.Seeds {
+		if wanted == seed {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatSink_PostsRenderedText(t *testing.T) {
+	var payload map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+	}))
+	defer server.Close()
+
+	sink, err := NewChatSink(ChatOptions{URL: server.URL})
+	require.NoError(t, err)
+	require.NoError(t, sink.Send(emailDigest))
+
+	assert.Equal(t, "*goCrawler* run 20240301T120000.000Z: 2 change(s) on https://example.com\n"+
+		"• new: <https://example.com/new>\n"+
+		"• changed: <https://example.com/pricing> v2 → v3 ("+emailDigest.Events[1].DiffSummary.String()+")\n", payload["text"])
+}
+
+func TestChatSink_Seeds(t *testing.T) {
+	tests := []struct {
+		name         string
+		seeds        []string
+		expectedPost bool
+	}{
+		{"All seeds", nil, true},
+		{"Matching seed", []string{"https://other.org", "https://example.com"}, true},
+		{"Other seeds only", []string{"https://other.org"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			posted := false
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { posted = true }))
+			defer server.Close()
+
+			sink, err := NewChatSink(ChatOptions{URL: server.URL, Seeds: tt.seeds})
+			require.NoError(t, err)
+			require.NoError(t, sink.Send(emailDigest))
+
+			assert.Equal(t, tt.expectedPost, posted)
+		})
+	}
+}
+
+func TestChatSink_FailsOnErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	sink, err := NewChatSink(ChatOptions{URL: server.URL, Template: "{{.Site}}"})
+	require.NoError(t, err)
+	assert.ErrorContains(t, sink.Send(emailDigest), "403")
+}
+
+func TestChatSink_HidesTokenOfUnreachableWebhook(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	unreachableUrl := server.URL + "/services/T000/B000/XXXX"
+	server.Close()
+
+	sink, err := NewChatSink(ChatOptions{URL: unreachableUrl, Template: "{{.Site}}"})
+	require.NoError(t, err)
+	sendErr := sink.Send(emailDigest)
+
+	require.Error(t, sendErr)
+	assert.NotContains(t, sendErr.Error(), "XXXX")
+	assert.Equal(t, server.URL+"/REDACTED", sink.Name())
+}
@@ -0,0 +1,67 @@
+// Package notify delivers change events recorded by the DifferenceTracker to external systems.
+package notify
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// DeadLetter is a notification that could not be delivered even after retrying.
+type DeadLetter struct {
+	FailedAt time.Time       `json:"failedAt"`
+	Sink     string          `json:"sink"`
+	Attempts int             `json:"attempts"`
+	Error    string          `json:"error"`
+	Payload  json.RawMessage `json:"payload"`
+}
+
+// DeadLetterLog appends undelivered notifications as JSON lines to a file, so they can be inspected or replayed.
+type DeadLetterLog struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewDeadLetterLog(path string) *DeadLetterLog {
+	return &DeadLetterLog{path: path}
+}
+
+func (d *DeadLetterLog) Append(deadLetter DeadLetter) error {
+	line, err := json.Marshal(deadLetter)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	file, err := os.OpenFile(d.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
+
+// ReadDeadLetters returns every dead letter recorded in the log at path.
+func ReadDeadLetters(path string) ([]DeadLetter, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	deadLetters := make([]DeadLetter, 0)
+	decoder := json.NewDecoder(file)
+	for decoder.More() {
+		var deadLetter DeadLetter
+		if err := decoder.Decode(&deadLetter); err != nil {
+			return nil, err
+		}
+		deadLetters = append(deadLetters, deadLetter)
+	}
+	return deadLetters, nil
+}
@@ -0,0 +1,150 @@
+package notify
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"goCrawler/diff"
+	"goCrawler/urlutil"
+)
+
+// Digest is every change one crawl run found below a seed. Seed is empty for changes
+// that can't be attributed to any seed of the run.
+type Digest struct {
+	Run    *diff.CrawlRun
+	Seed   string
+	Events []diff.ChangeEvent
+}
+
+// Site names what the digest is about, for use in templates.
+func (d Digest) Site() string {
+	if d.Seed == "" {
+		return "crawled sites"
+	}
+	return d.Seed
+}
+
+func (d Digest) NewPages() []diff.ChangeEvent {
+	return d.eventsOfType(diff.NewPageEvent)
+}
+
+func (d Digest) ChangedPages() []diff.ChangeEvent {
+	return d.eventsOfType(diff.NewVersionEvent)
+}
+
+func (d Digest) GonePages() []diff.ChangeEvent {
+	return d.eventsOfType(diff.PageGoneEvent)
+}
+
+func (d Digest) ReturnedPages() []diff.ChangeEvent {
+	return d.eventsOfType(diff.PageReturnedEvent)
+}
+
+func (d Digest) eventsOfType(eventType diff.ChangeEventType) []diff.ChangeEvent {
+	events := make([]diff.ChangeEvent, 0)
+	for _, event := range d.Events {
+		if event.Type == eventType {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// IDigestSink delivers a digest, e.g. by email. Sinks decide themselves whether a seed concerns them.
+type IDigestSink interface {
+	Name() string
+	Send(digest Digest) error
+}
+
+// DigestNotifier collects the change events of a crawl run and sends one digest per seed
+// to every sink when the run finishes, instead of a message per page.
+type DigestNotifier struct {
+	sinks      []IDigestSink
+	deadLetter *DeadLetterLog
+
+	mu     sync.Mutex
+	events []diff.ChangeEvent
+}
+
+// NewDigestNotifier sends digests to sinks. deadLetter may be nil, undelivered digests are then only logged.
+func NewDigestNotifier(sinks []IDigestSink, deadLetter *DeadLetterLog) *DigestNotifier {
+	return &DigestNotifier{sinks: sinks, deadLetter: deadLetter}
+}
+
+func (d *DigestNotifier) Notify(event diff.ChangeEvent) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.events = append(d.events, event)
+	return nil
+}
+
+// RunFinished sends the digests of run. Runs without changes send nothing.
+func (d *DigestNotifier) RunFinished(run *diff.CrawlRun) error {
+	d.mu.Lock()
+	events := d.events
+	d.events = nil
+	d.mu.Unlock()
+
+	for _, digest := range groupBySeed(run, events) {
+		for _, sink := range d.sinks {
+			if err := sink.Send(digest); err != nil {
+				log.Printf("ERROR: Sending digest to %s failed, run=%s seed=%s: %v", sink.Name(), run.ID, digest.Seed, err)
+				d.recordDeadLetter(sink, digest, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (d *DigestNotifier) recordDeadLetter(sink IDigestSink, digest Digest, sendErr error) {
+	if d.deadLetter == nil {
+		return
+	}
+
+	payload, err := json.Marshal(digest)
+	if err != nil {
+		log.Printf("ERROR: Serializing digest failed, run=%s: %v", digest.Run.ID, err)
+		return
+	}
+	deadLetter := DeadLetter{
+		FailedAt: time.Now().UTC(),
+		Sink:     sink.Name(),
+		Attempts: 1,
+		Error:    sendErr.Error(),
+		Payload:  payload,
+	}
+	if err := d.deadLetter.Append(deadLetter); err != nil {
+		log.Printf("ERROR: Writing dead letter failed, run=%s: %v", digest.Run.ID, err)
+	}
+}
+
+// groupBySeed splits events into one digest per seed, in the order the seeds first changed. Crawls stay on
+// the domain of their seed, so an event belongs to the first seed with the same domain.
+func groupBySeed(run *diff.CrawlRun, events []diff.ChangeEvent) []Digest {
+	digests := make([]Digest, 0)
+	digestIndex := make(map[string]int)
+	for _, event := range events {
+		seed := seedOf(run.Seeds, event.URL)
+		index, found := digestIndex[seed]
+		if !found {
+			index = len(digests)
+			digestIndex[seed] = index
+			digests = append(digests, Digest{Run: run, Seed: seed})
+		}
+		digests[index].Events = append(digests[index].Events, event)
+	}
+	return digests
+}
+
+func seedOf(seeds []string, url string) string {
+	domain := urlutil.NormalizeDomain(url)
+	for _, seed := range seeds {
+		if urlutil.NormalizeDomain(seed) == domain {
+			return seed
+		}
+	}
+	return ""
+}
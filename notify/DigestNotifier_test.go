@@ -0,0 +1,102 @@
+package notify
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"goCrawler/diff"
+)
+
+type recordingSink struct {
+	digests []Digest
+	err     error
+}
+
+func (r *recordingSink) Name() string {
+	return "recording"
+}
+
+func (r *recordingSink) Send(digest Digest) error {
+	r.digests = append(r.digests, digest)
+	return r.err
+}
+
+var digestRun = &diff.CrawlRun{ID: "20240301T120000.000Z", Seeds: []string{"https://example.com", "https://www.other.org"}}
+
+func TestDigestNotifier_SendsOneDigestPerSeed(t *testing.T) {
+	sink := &recordingSink{}
+	notifier := NewDigestNotifier([]IDigestSink{sink}, nil)
+
+	require.NoError(t, notifier.Notify(diff.ChangeEvent{Type: diff.NewPageEvent, URL: "https://example.com/a"}))
+	require.NoError(t, notifier.Notify(diff.ChangeEvent{Type: diff.NewVersionEvent, URL: "https://other.org/b"}))
+	require.NoError(t, notifier.Notify(diff.ChangeEvent{Type: diff.NewVersionEvent, URL: "https://www.example.com/c"}))
+	require.NoError(t, notifier.Notify(diff.ChangeEvent{Type: diff.NewPageEvent, URL: "https://elsewhere.net/"}))
+	require.NoError(t, notifier.RunFinished(digestRun))
+
+	require.Len(t, sink.digests, 3)
+	assert.Equal(t, "https://example.com", sink.digests[0].Seed)
+	assert.Len(t, sink.digests[0].Events, 2)
+	assert.Len(t, sink.digests[0].NewPages(), 1)
+	assert.Len(t, sink.digests[0].ChangedPages(), 1)
+	assert.Equal(t, "https://www.other.org", sink.digests[1].Seed)
+	assert.Equal(t, "", sink.digests[2].Seed)
+	assert.Equal(t, "crawled sites", sink.digests[2].Site())
+	assert.Same(t, digestRun, sink.digests[0].Run)
+}
+
+func TestDigestNotifier_StartsEachRunEmpty(t *testing.T) {
+	sink := &recordingSink{}
+	notifier := NewDigestNotifier([]IDigestSink{sink}, nil)
+
+	require.NoError(t, notifier.Notify(diff.ChangeEvent{Type: diff.NewPageEvent, URL: "https://example.com/a"}))
+	require.NoError(t, notifier.RunFinished(digestRun))
+	require.NoError(t, notifier.RunFinished(digestRun))
+
+	assert.Len(t, sink.digests, 1)
+}
+
+func TestDigestNotifier_DeadLettersFailedDigests(t *testing.T) {
+	deadLetterPath := filepath.Join(t.TempDir(), "deadletter.jsonl")
+	failingSink := &recordingSink{err: errors.New("connection refused")}
+	workingSink := &recordingSink{}
+	notifier := NewDigestNotifier([]IDigestSink{failingSink, workingSink}, NewDeadLetterLog(deadLetterPath))
+
+	require.NoError(t, notifier.Notify(diff.ChangeEvent{Type: diff.NewPageEvent, URL: "https://example.com/a"}))
+	require.NoError(t, notifier.RunFinished(digestRun))
+
+	assert.Len(t, workingSink.digests, 1)
+	deadLetters, err := ReadDeadLetters(deadLetterPath)
+	require.NoError(t, err)
+	require.Len(t, deadLetters, 1)
+	assert.Equal(t, "recording", deadLetters[0].Sink)
+	assert.Equal(t, "connection refused", deadLetters[0].Error)
+	assert.Contains(t, string(deadLetters[0].Payload), "https://example.com/a")
+}
+
+func TestDefaultTemplates_ListGoneAndReturnedPages(t *testing.T) {
+	digest := Digest{
+		Run:  digestRun,
+		Seed: "https://example.com",
+		Events: []diff.ChangeEvent{
+			{Type: diff.PageGoneEvent, URL: "https://example.com/old", Reason: diff.GoneNotFound, StatusCode: 404},
+			{Type: diff.PageReturnedEvent, URL: "https://example.com/back"},
+		},
+	}
+
+	body, err := ParseTemplate("body", "", DefaultEmailBodyTemplate)
+	require.NoError(t, err)
+	rendered, err := render(body, digest)
+	require.NoError(t, err)
+	assert.Contains(t, rendered, "Gone pages:\n  https://example.com/old (not_found, HTTP 404)\n")
+	assert.Contains(t, rendered, "Returned pages:\n  https://example.com/back\n")
+
+	chat, err := ParseTemplate("chat", "", DefaultChatTemplate)
+	require.NoError(t, err)
+	rendered, err = render(chat, digest)
+	require.NoError(t, err)
+	assert.Contains(t, rendered, "• gone: <https://example.com/old> (not_found)\n• returned: <https://example.com/back>\n")
+}
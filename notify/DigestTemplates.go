@@ -0,0 +1,48 @@
+package notify
+
+import (
+	"strings"
+	"text/template"
+)
+
+// Templates render digests with text/template; the data is a Digest.
+
+const DefaultEmailSubjectTemplate = `[goCrawler] {{len .Events}} change(s) on {{.Site}}`
+
+const DefaultEmailBodyTemplate = `Crawl run {{.Run.ID}} found {{len .Events}} change(s) on {{.Site}}.
+{{with .NewPages}}
+New pages:
+{{range .}}  {{.URL}}
+{{end}}{{end}}{{with .ChangedPages}}
+Changed pages:
+{{range .}}  {{.URL}} (v{{.OldVersion}} -> v{{.NewVersion}}){{with .DiffSummary}} {{.}}{{end}}
+{{end}}{{end}}{{with .GonePages}}
+Gone pages:
+{{range .}}  {{.URL}} ({{.Reason}}{{with .StatusCode}}, HTTP {{.}}{{end}})
+{{end}}{{end}}{{with .ReturnedPages}}
+Returned pages:
+{{range .}}  {{.URL}}
+{{end}}{{end}}`
+
+const DefaultChatTemplate = `*goCrawler* run {{.Run.ID}}: {{len .Events}} change(s) on {{.Site}}
+{{range .NewPages}}• new: <{{.URL}}>
+{{end}}{{range .ChangedPages}}• changed: <{{.URL}}> v{{.OldVersion}} → v{{.NewVersion}}{{with .DiffSummary}} ({{.}}){{end}}
+{{end}}{{range .GonePages}}• gone: <{{.URL}}> ({{.Reason}})
+{{end}}{{range .ReturnedPages}}• returned: <{{.URL}}>
+{{end}}`
+
+// ParseTemplate parses text, falling back to defaultText if text is empty.
+func ParseTemplate(name string, text string, defaultText string) (*template.Template, error) {
+	if text == "" {
+		text = defaultText
+	}
+	return template.New(name).Parse(text)
+}
+
+func render(tmpl *template.Template, digest Digest) (string, error) {
+	var rendered strings.Builder
+	if err := tmpl.Execute(&rendered, digest); err != nil {
+		return "", err
+	}
+	return rendered.String(), nil
+}
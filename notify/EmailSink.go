@@ -0,0 +1,99 @@
+package notify
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// EmailOptions configures an EmailSink. SeedRecipients maps seed URLs to their own recipients;
+// digests of seeds without an entry go to To.
+type EmailOptions struct {
+	Host            string
+	Port            int
+	Username        string
+	Password        string
+	From            string
+	To              []string
+	SeedRecipients  map[string][]string
+	SubjectTemplate string
+	BodyTemplate    string
+}
+
+// EmailSink sends digests as plain text emails over SMTP.
+type EmailSink struct {
+	options EmailOptions
+	subject *template.Template
+	body    *template.Template
+	now     func() time.Time
+}
+
+func NewEmailSink(options EmailOptions) (*EmailSink, error) {
+	subject, err := ParseTemplate("subject", options.SubjectTemplate, DefaultEmailSubjectTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid subject template: %w", err)
+	}
+	body, err := ParseTemplate("body", options.BodyTemplate, DefaultEmailBodyTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid body template: %w", err)
+	}
+
+	if options.Port == 0 {
+		options.Port = 25
+	}
+	return &EmailSink{options: options, subject: subject, body: body, now: time.Now}, nil
+}
+
+func (e *EmailSink) Name() string {
+	return "smtp://" + e.address()
+}
+
+func (e *EmailSink) Send(digest Digest) error {
+	recipients := e.recipients(digest.Seed)
+	if len(recipients) == 0 {
+		return nil
+	}
+
+	subject, err := render(e.subject, digest)
+	if err != nil {
+		return err
+	}
+	body, err := render(e.body, digest)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if e.options.Username != "" {
+		auth = smtp.PlainAuth("", e.options.Username, e.options.Password, e.options.Host)
+	}
+	return smtp.SendMail(e.address(), auth, e.options.From, recipients, e.message(recipients, subject, body))
+}
+
+func (e *EmailSink) recipients(seed string) []string {
+	if recipients, found := e.options.SeedRecipients[seed]; found {
+		return recipients
+	}
+	return e.options.To
+}
+
+func (e *EmailSink) address() string {
+	return net.JoinHostPort(e.options.Host, strconv.Itoa(e.options.Port))
+}
+
+func (e *EmailSink) message(recipients []string, subject string, body string) []byte {
+	var message strings.Builder
+	message.WriteString("From: " + e.options.From + "\r\n")
+	message.WriteString("To: " + strings.Join(recipients, ", ") + "\r\n")
+	message.WriteString("Subject: " + strings.Join(strings.Fields(subject), " ") + "\r\n")
+	message.WriteString("Date: " + e.now().Format(time.RFC1123Z) + "\r\n")
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	message.WriteString("\r\n")
+	message.WriteString(strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n"))
+	return []byte(message.String())
+}
@@ -0,0 +1,209 @@
+package notify
+
+import (
+	"bufio"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"goCrawler/diff"
+)
+
+type receivedMail struct {
+	from       string
+	recipients []string
+	data       string
+}
+
+// fakeSmtpServer speaks just enough SMTP for net/smtp.SendMail, without STARTTLS or AUTH.
+type fakeSmtpServer struct {
+	listener net.Listener
+	mu       sync.Mutex
+	mails    []receivedMail
+}
+
+func newFakeSmtpServer(t *testing.T) *fakeSmtpServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := &fakeSmtpServer{listener: listener}
+	go server.serve()
+	t.Cleanup(func() { listener.Close() })
+	return server
+}
+
+func (s *fakeSmtpServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *fakeSmtpServer) received() []receivedMail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]receivedMail{}, s.mails...)
+}
+
+func (s *fakeSmtpServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeSmtpServer) handle(conn net.Conn) {
+	defer conn.Close()
+	text := textproto.NewConn(conn)
+	text.PrintfLine("220 localhost fake SMTP")
+
+	var mail receivedMail
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			text.PrintfLine("250 localhost")
+		case strings.HasPrefix(command, "MAIL FROM:"):
+			mail = receivedMail{from: strings.Trim(line[len("MAIL FROM:"):], "<>")}
+			text.PrintfLine("250 OK")
+		case strings.HasPrefix(command, "RCPT TO:"):
+			mail.recipients = append(mail.recipients, strings.Trim(line[len("RCPT TO:"):], "<>"))
+			text.PrintfLine("250 OK")
+		case command == "DATA":
+			text.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+			data, err := text.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			mail.data = string(data)
+			s.mu.Lock()
+			s.mails = append(s.mails, mail)
+			s.mu.Unlock()
+			text.PrintfLine("250 OK")
+		case command == "QUIT":
+			text.PrintfLine("221 Bye")
+			return
+		default:
+			text.PrintfLine("250 OK")
+		}
+	}
+}
+
+var emailDigest = Digest{
+	Run:  &diff.CrawlRun{ID: "20240301T120000.000Z"},
+	Seed: "https://example.com",
+	Events: []diff.ChangeEvent{
+		{Type: diff.NewPageEvent, URL: "https://example.com/new", NewVersion: 1},
+		{Type: diff.NewVersionEvent, URL: "https://example.com/pricing", OldVersion: 2, NewVersion: 3,
+			DiffSummary: &diff.DiffSummary{LinesAdded: 2, LinesRemoved: 1}},
+	},
+}
+
+func newTestEmailSink(t *testing.T, server *fakeSmtpServer, options EmailOptions) *EmailSink {
+	options.Host = "127.0.0.1"
+	options.Port = server.port()
+	options.From = "crawler@example.com"
+
+	sink, err := NewEmailSink(options)
+	require.NoError(t, err)
+	sink.now = func() time.Time { return time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC) }
+	return sink
+}
+
+func TestEmailSink_SendsDigest(t *testing.T) {
+	server := newFakeSmtpServer(t)
+	sink := newTestEmailSink(t, server, EmailOptions{To: []string{"team@example.com"}})
+
+	require.NoError(t, sink.Send(emailDigest))
+
+	mails := server.received()
+	require.Len(t, mails, 1)
+	assert.Equal(t, "crawler@example.com", mails[0].from)
+	assert.Equal(t, []string{"team@example.com"}, mails[0].recipients)
+
+	message, err := textproto.NewReader(bufio.NewReader(strings.NewReader(mails[0].data))).ReadMIMEHeader()
+	require.NoError(t, err)
+	assert.Equal(t, "[goCrawler] 2 change(s) on https://example.com", message.Get("Subject"))
+	assert.Equal(t, "Fri, 01 Mar 2024 12:00:00 +0000", message.Get("Date"))
+	assert.Contains(t, mails[0].data, "New pages:\n  https://example.com/new\n")
+	assert.Contains(t, mails[0].data, "https://example.com/pricing (v2 -> v3) "+emailDigest.Events[1].DiffSummary.String())
+}
+
+func TestEmailSink_Recipients(t *testing.T) {
+	tests := []struct {
+		name     string
+		seed     string
+		expected []string
+	}{
+		{"Seed with own recipients", "https://example.com", []string{"marketing@example.com", "ceo@example.com"}},
+		{"Seed without own recipients", "https://other.org", []string{"team@example.com"}},
+		{"Unattributed changes", "", []string{"team@example.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeSmtpServer(t)
+			sink := newTestEmailSink(t, server, EmailOptions{
+				To:             []string{"team@example.com"},
+				SeedRecipients: map[string][]string{"https://example.com": {"marketing@example.com", "ceo@example.com"}},
+			})
+
+			digest := emailDigest
+			digest.Seed = tt.seed
+			require.NoError(t, sink.Send(digest))
+
+			mails := server.received()
+			require.Len(t, mails, 1)
+			assert.Equal(t, tt.expected, mails[0].recipients)
+		})
+	}
+}
+
+func TestEmailSink_SkipsSeedsWithoutRecipients(t *testing.T) {
+	server := newFakeSmtpServer(t)
+	sink := newTestEmailSink(t, server, EmailOptions{SeedRecipients: map[string][]string{"https://example.com": {"marketing@example.com"}}})
+
+	digest := emailDigest
+	digest.Seed = "https://other.org"
+	require.NoError(t, sink.Send(digest))
+
+	assert.Empty(t, server.received())
+}
+
+func TestEmailSink_CustomTemplates(t *testing.T) {
+	server := newFakeSmtpServer(t)
+	sink := newTestEmailSink(t, server, EmailOptions{
+		To:              []string{"team@example.com"},
+		SubjectTemplate: "{{len .ChangedPages}} pages changed",
+		BodyTemplate:    "{{range .Events}}{{.URL}}\n{{end}}",
+	})
+
+	require.NoError(t, sink.Send(emailDigest))
+
+	mails := server.received()
+	require.Len(t, mails, 1)
+	assert.Contains(t, mails[0].data, "Subject: 1 pages changed\n")
+	assert.True(t, strings.HasSuffix(mails[0].data, "\n\nhttps://example.com/new\nhttps://example.com/pricing\n"), mails[0].data)
+}
+
+func TestNewEmailSink_RejectsInvalidTemplate(t *testing.T) {
+	_, err := NewEmailSink(EmailOptions{Host: "localhost", BodyTemplate: "{{.Run"})
+	assert.ErrorContains(t, err, "invalid body template")
+}
+
+func TestEmailSink_Name(t *testing.T) {
+	sink, err := NewEmailSink(EmailOptions{Host: "smtp.example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, "smtp://smtp.example.com:"+strconv.Itoa(25), sink.Name())
+}
@@ -0,0 +1,207 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"goCrawler/diff"
+	"goCrawler/urlutil"
+)
+
+// webhookQueueSize is how many events may wait for delivery; further ones are dead-lettered right away.
+const webhookQueueSize = 256
+
+var errQueueFull = errors.New("webhook delivery queue is full")
+
+const (
+	SignatureHeader = "X-GoCrawler-Signature"
+	EventHeader     = "X-GoCrawler-Event"
+	DeliveryHeader  = "X-GoCrawler-Delivery"
+)
+
+// WebhookEndpoint is a URL change events are POSTed to as JSON.
+// With a Secret, every request carries an HMAC-SHA256 of the body in the X-GoCrawler-Signature header.
+type WebhookEndpoint struct {
+	URL        string
+	Secret     string
+	MaxRetries int
+	RetryDelay time.Duration
+	Timeout    time.Duration
+}
+
+type webhookDelivery struct {
+	id      string
+	event   diff.ChangeEvent
+	payload []byte
+}
+
+// WebhookNotifier delivers change events to webhook endpoints in the background, so slow or failing
+// receivers don't hold up the crawl: when the queue is full, events are dead-lettered instead of waiting.
+// Close must be called to flush pending deliveries.
+type WebhookNotifier struct {
+	endpoints  []WebhookEndpoint
+	clients    []*http.Client
+	deadLetter *DeadLetterLog
+	queue      chan webhookDelivery
+	wg         sync.WaitGroup
+	sleep      func(time.Duration)
+}
+
+// NewWebhookNotifier starts delivering to endpoints. deadLetter may be nil, undelivered events are then only logged.
+func NewWebhookNotifier(endpoints []WebhookEndpoint, deadLetter *DeadLetterLog) *WebhookNotifier {
+	// Endpoints without a timeout share one client, the others get their own so each attempt reuses connections
+	client := &http.Client{}
+	clients := make([]*http.Client, len(endpoints))
+	for i, endpoint := range endpoints {
+		clients[i] = client
+		if endpoint.Timeout > 0 {
+			clients[i] = &http.Client{Timeout: endpoint.Timeout}
+		}
+	}
+
+	notifier := &WebhookNotifier{
+		endpoints:  endpoints,
+		clients:    clients,
+		deadLetter: deadLetter,
+		queue:      make(chan webhookDelivery, webhookQueueSize),
+		sleep:      time.Sleep,
+	}
+
+	notifier.wg.Add(1)
+	go notifier.deliverLoop()
+	return notifier
+}
+
+func (w *WebhookNotifier) Notify(event diff.ChangeEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	delivery := webhookDelivery{id: newDeliveryID(), event: event, payload: payload}
+	select {
+	case w.queue <- delivery:
+	default:
+		log.Printf("ERROR: Webhook delivery queue is full, dead-lettering event, url=%s", event.URL)
+		for _, endpoint := range w.endpoints {
+			w.writeDeadLetter(endpoint, 0, errQueueFull, delivery)
+		}
+	}
+	return nil
+}
+
+// Close waits until every queued event was delivered or dead-lettered.
+func (w *WebhookNotifier) Close() {
+	close(w.queue)
+	w.wg.Wait()
+}
+
+func (w *WebhookNotifier) deliverLoop() {
+	defer w.wg.Done()
+
+	for delivery := range w.queue {
+		for i, endpoint := range w.endpoints {
+			attempts, err := w.deliver(endpoint, w.clients[i], delivery)
+			if err == nil {
+				continue
+			}
+
+			log.Printf("ERROR: Giving up webhook delivery to %s after %d attempts, url=%s: %v", urlutil.RedactURL(endpoint.URL, false), attempts, delivery.event.URL, err)
+			w.writeDeadLetter(endpoint, attempts, err, delivery)
+		}
+	}
+}
+
+func (w *WebhookNotifier) writeDeadLetter(endpoint WebhookEndpoint, attempts int, err error, delivery webhookDelivery) {
+	if w.deadLetter == nil {
+		return
+	}
+	deadLetter := DeadLetter{
+		FailedAt: time.Now().UTC(),
+		Sink:     urlutil.RedactURL(endpoint.URL, false),
+		Attempts: attempts,
+		Error:    err.Error(),
+		Payload:  delivery.payload,
+	}
+	if err := w.deadLetter.Append(deadLetter); err != nil {
+		log.Printf("ERROR: Writing dead letter failed, url=%s: %v", delivery.event.URL, err)
+	}
+}
+
+// deliver POSTs the payload, retrying with exponential backoff on network errors, 429 and 5xx responses.
+func (w *WebhookNotifier) deliver(endpoint WebhookEndpoint, client *http.Client, delivery webhookDelivery) (int, error) {
+	delay := endpoint.RetryDelay
+	attempts := 0
+	for {
+		attempts++
+		retryable, err := w.post(endpoint, client, delivery)
+		if err == nil || !retryable || attempts > endpoint.MaxRetries {
+			return attempts, err
+		}
+
+		w.sleep(delay)
+		delay *= 2
+	}
+}
+
+func (w *WebhookNotifier) post(endpoint WebhookEndpoint, client *http.Client, delivery webhookDelivery) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, endpoint.URL, bytes.NewReader(delivery.payload))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, string(delivery.event.Type))
+	req.Header.Set(DeliveryHeader, delivery.id)
+	if endpoint.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(endpoint.Secret, delivery.payload))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, redactUrlError(err, false)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+
+	retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retryable, fmt.Errorf("webhook responded with %s", resp.Status)
+}
+
+// Sign returns the signature header value of payload, "sha256=" followed by the hex HMAC-SHA256 with secret.
+// Receivers should compute the same value from the raw request body and compare with hmac.Equal.
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// redactUrlError hides credentials in the URL of an HTTP client error, since errors end up in logs and dead letters.
+func redactUrlError(err error, redactPath bool) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		urlErr.URL = urlutil.RedactURL(urlErr.URL, redactPath)
+	}
+	return err
+}
+
+func newDeliveryID() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return fmt.Sprint(time.Now().UnixNano())
+	}
+	return hex.EncodeToString(id)
+}
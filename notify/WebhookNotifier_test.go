@@ -0,0 +1,201 @@
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"goCrawler/diff"
+)
+
+var changeEvent = diff.ChangeEvent{
+	Type:       diff.NewVersionEvent,
+	URL:        "https://example.com",
+	OldVersion: 1,
+	NewVersion: 2,
+	OldHash:    "d6165a2f6a47eba8aa611ca6891203a9",
+	NewHash:    "2f2180839c2f324971d4f0f98fbf46de",
+	DetectedAt: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+}
+
+func newTestNotifier(endpoints []WebhookEndpoint, deadLetter *DeadLetterLog) *WebhookNotifier {
+	notifier := NewWebhookNotifier(endpoints, deadLetter)
+	notifier.sleep = func(time.Duration) {}
+	return notifier
+}
+
+func TestWebhookNotifier_PostsSignedPayload(t *testing.T) {
+	var received *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	notifier := newTestNotifier([]WebhookEndpoint{{URL: server.URL, Secret: "s3cret"}}, nil)
+	require.NoError(t, notifier.Notify(changeEvent))
+	notifier.Close()
+
+	require.NotNil(t, received)
+	assert.Equal(t, http.MethodPost, received.Method)
+	assert.Equal(t, "application/json", received.Header.Get("Content-Type"))
+	assert.Equal(t, "new_version", received.Header.Get(EventHeader))
+	assert.NotEmpty(t, received.Header.Get(DeliveryHeader))
+	assert.Equal(t, Sign("s3cret", body), received.Header.Get(SignatureHeader))
+
+	var event diff.ChangeEvent
+	require.NoError(t, json.Unmarshal(body, &event))
+	assert.Equal(t, changeEvent, event)
+}
+
+func TestWebhookNotifier_OmitsSignatureWithoutSecret(t *testing.T) {
+	var signature atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signature.Store(r.Header.Get(SignatureHeader))
+	}))
+	defer server.Close()
+
+	notifier := newTestNotifier([]WebhookEndpoint{{URL: server.URL}}, nil)
+	require.NoError(t, notifier.Notify(changeEvent))
+	notifier.Close()
+
+	assert.Equal(t, "", signature.Load())
+}
+
+func TestWebhookNotifier_Retries(t *testing.T) {
+	tests := []struct {
+		name             string
+		statuses         []int
+		maxRetries       int
+		expectedAttempts int32
+		expectDeadLetter bool
+	}{
+		{"Succeeds at once", []int{200}, 3, 1, false},
+		{"Retries server errors", []int{500, 503, 204}, 3, 3, false},
+		{"Retries rate limiting", []int{429, 200}, 3, 2, false},
+		{"Gives up after max retries", []int{500, 500, 500}, 2, 3, true},
+		{"Does not retry client errors", []int{400, 200}, 3, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempt := atomic.AddInt32(&attempts, 1)
+				w.WriteHeader(tt.statuses[attempt-1])
+			}))
+			defer server.Close()
+
+			deadLetterPath := filepath.Join(t.TempDir(), "deadletter.jsonl")
+			notifier := newTestNotifier([]WebhookEndpoint{{URL: server.URL, MaxRetries: tt.maxRetries, RetryDelay: time.Second}},
+				NewDeadLetterLog(deadLetterPath))
+			require.NoError(t, notifier.Notify(changeEvent))
+			notifier.Close()
+
+			assert.Equal(t, tt.expectedAttempts, atomic.LoadInt32(&attempts))
+
+			deadLetters, err := ReadDeadLetters(deadLetterPath)
+			if !tt.expectDeadLetter {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, deadLetters, 1)
+			assert.Equal(t, server.URL, deadLetters[0].Sink)
+			assert.Equal(t, int(tt.expectedAttempts), deadLetters[0].Attempts)
+
+			var event diff.ChangeEvent
+			require.NoError(t, json.Unmarshal(deadLetters[0].Payload, &event))
+			assert.Equal(t, changeEvent, event)
+		})
+	}
+}
+
+func TestWebhookNotifier_BacksOffExponentially(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	var delays []time.Duration
+	notifier := NewWebhookNotifier([]WebhookEndpoint{{URL: server.URL, MaxRetries: 3, RetryDelay: time.Second}}, nil)
+	notifier.sleep = func(delay time.Duration) { delays = append(delays, delay) }
+	require.NoError(t, notifier.Notify(changeEvent))
+	notifier.Close()
+
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}, delays)
+}
+
+func TestWebhookNotifier_DeadLettersUnreachableEndpoint(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	unreachableUrl := server.URL
+	server.Close()
+
+	deadLetterPath := filepath.Join(t.TempDir(), "deadletter.jsonl")
+	notifier := newTestNotifier([]WebhookEndpoint{{URL: unreachableUrl, MaxRetries: 1}}, NewDeadLetterLog(deadLetterPath))
+	require.NoError(t, notifier.Notify(changeEvent))
+	require.NoError(t, notifier.Notify(changeEvent))
+	notifier.Close()
+
+	deadLetters, err := ReadDeadLetters(deadLetterPath)
+	require.NoError(t, err)
+	require.Len(t, deadLetters, 2)
+	assert.Equal(t, 2, deadLetters[0].Attempts)
+	assert.NotEmpty(t, deadLetters[0].Error)
+}
+
+func TestWebhookNotifier_DeadLettersWhenEndpointNeverResponds(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+
+	deadLetterPath := filepath.Join(t.TempDir(), "deadletter.jsonl")
+	notifier := newTestNotifier([]WebhookEndpoint{{URL: server.URL}}, NewDeadLetterLog(deadLetterPath))
+
+	notified := make(chan struct{})
+	go func() {
+		// One delivery in flight, a full queue, and some more
+		for i := 0; i < webhookQueueSize+10; i++ {
+			notifier.Notify(changeEvent)
+		}
+		close(notified)
+	}()
+	select {
+	case <-notified:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Notify blocked on the stalled endpoint")
+	}
+	close(release)
+	notifier.Close()
+
+	deadLetters, err := ReadDeadLetters(deadLetterPath)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, len(deadLetters), 9)
+	assert.Equal(t, errQueueFull.Error(), deadLetters[0].Error)
+	assert.Equal(t, 0, deadLetters[0].Attempts)
+}
+
+func TestNewWebhookNotifier_BuildsClientsOnce(t *testing.T) {
+	notifier := NewWebhookNotifier([]WebhookEndpoint{
+		{URL: "https://a.example.com"},
+		{URL: "https://b.example.com", Timeout: time.Second},
+		{URL: "https://c.example.com"},
+	}, nil)
+	defer notifier.Close()
+
+	require.Len(t, notifier.clients, 3)
+	assert.Same(t, notifier.clients[0], notifier.clients[2])
+	assert.NotSame(t, notifier.clients[0], notifier.clients[1])
+	assert.Equal(t, time.Second, notifier.clients[1].Timeout)
+}
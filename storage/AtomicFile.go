@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const tempFilePattern = ".tmp-*"
+
+// atomicFileWriter writes to a temporary file next to path and renames it into place on Close, so neither
+// readers nor a crash ever see a partially written file. The content is synced before the rename and the
+// directory after it, so the rename survives a power failure; after a failed Write, Close discards it and
+// keeps whatever file was at path before.
+type atomicFileWriter struct {
+	path   string
+	temp   *os.File
+	err    error
+	closed bool
+}
+
+func newAtomicFileWriter(path string) (*atomicFileWriter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(path), tempFilePattern)
+	if err != nil {
+		return nil, err
+	}
+	return &atomicFileWriter{path: path, temp: temp}, nil
+}
+
+func (w *atomicFileWriter) Write(data []byte) (int, error) {
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	n, err := w.temp.Write(data)
+	w.err = err
+	return n, err
+}
+
+func (w *atomicFileWriter) Close() error {
+	if w.closed {
+		return os.ErrClosed
+	}
+	w.closed = true
+
+	if w.err == nil {
+		w.err = w.temp.Sync()
+	}
+	if err := w.temp.Close(); w.err == nil {
+		w.err = err
+	}
+	if w.err == nil {
+		w.err = os.Rename(w.temp.Name(), w.path)
+	}
+	if w.err != nil {
+		os.Remove(w.temp.Name())
+		return w.err
+	}
+	w.err = syncDirectory(filepath.Dir(w.path))
+	return w.err
+}
+
+// syncDirectory flushes the entries of directory, e.g. a file renamed into it, to disk.
+func syncDirectory(directory string) error {
+	dir, err := os.Open(directory)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
+
+// isTempFile reports whether name is a temporary file of an atomicFileWriter.
+func isTempFile(name string) bool {
+	return strings.HasPrefix(name, strings.TrimSuffix(tempFilePattern, "*"))
+}
+
+// RemoveStaleTempFiles removes the temporary files below root that writers interrupted by a crash left behind
+// and returns how many it removed. Only files not modified for maxAge are removed, so writers still busy in
+// another process keep theirs; files that can't be removed are left for the next time, they are never listed.
+func RemoveStaleTempFiles(root string, maxAge time.Duration) (int, error) {
+	removed := 0
+	cutoff := time.Now().Add(-maxAge)
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root && os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if entry.IsDir() || !isTempFile(entry.Name()) {
+			return nil
+		}
+		if info, err := entry.Info(); err == nil && info.ModTime().Before(cutoff) && os.Remove(path) == nil {
+			removed++
+		}
+		return nil
+	})
+	return removed, err
+}
+
+// writeFileAtomically writes content to path through an atomicFileWriter.
+func writeFileAtomically(path string, content []byte) error {
+	writer, err := newAtomicFileWriter(path)
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(content); err != nil {
+		writer.Close()
+		return err
+	}
+	return writer.Close()
+}
@@ -0,0 +1,14 @@
+package storage
+
+import "bytes"
+
+// bufferedWriter collects a whole file in memory for storages that need the complete content to store it,
+// e.g. to hash or compress it. Nothing is stored unless Close is called.
+type bufferedWriter struct {
+	bytes.Buffer
+	store func(content []byte) error
+}
+
+func (w *bufferedWriter) Close() error {
+	return w.store(w.Bytes())
+}
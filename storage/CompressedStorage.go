@@ -0,0 +1,348 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+type Compression string
+
+const (
+	CompressionNone Compression = "none"
+	CompressionGzip Compression = "gzip"
+	CompressionZstd Compression = "zstd"
+)
+
+// DefaultKeyframeInterval stores every 10th version in full, so reading a delta encoded version
+// never needs more than 9 predecessors.
+const DefaultKeyframeInterval = 10
+
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	zstdMagic  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	deltaMagic = []byte("GCDELTA1\n")
+)
+
+// versionFileRegexp matches the version files written by the DifferenceTracker, "<path>/vN.html".
+var versionFileRegexp = regexp.MustCompile(`^(.*/)?v(\d+)\.html$`)
+
+// CompressedStorage compresses files before passing them on to another storage. With deltas enabled,
+// version files are stored as the line differences to their predecessor whenever that is smaller.
+// Files are recognized by their magic bytes when read, so files written uncompressed, with another
+// compression or before delta encoding was enabled are all read the same way.
+type CompressedStorage struct {
+	backend          IStorage
+	compression      Compression
+	deltas           bool
+	keyframeInterval int
+	zstdEncoder      *zstd.Encoder
+	zstdDecoder      *zstd.Decoder
+}
+
+func NewCompressedStorage(backend IStorage, compression Compression) (*CompressedStorage, error) {
+	switch compression {
+	case CompressionNone, CompressionGzip, CompressionZstd:
+	default:
+		return nil, fmt.Errorf("unknown compression %q", compression)
+	}
+
+	encoder, err := zstd.NewWriter(nil)
+	if err != nil {
+		return nil, err
+	}
+	decoder, err := zstd.NewReader(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CompressedStorage{
+		backend:          backend,
+		compression:      compression,
+		keyframeInterval: DefaultKeyframeInterval,
+		zstdEncoder:      encoder,
+		zstdDecoder:      decoder,
+	}, nil
+}
+
+// SetDeltas enables delta encoding of version files against their predecessor. Every keyframeInterval-th
+// version is stored in full to bound how many files reading a version needs.
+func (c *CompressedStorage) SetDeltas(enabled bool, keyframeInterval int) {
+	c.deltas = enabled
+	if keyframeInterval > 0 {
+		c.keyframeInterval = keyframeInterval
+	}
+}
+
+// Store encodes content and writes it to the backend.
+func (c *CompressedStorage) Store(filename string, content []byte) error {
+	encoded, err := c.encode(filename, content)
+	if err != nil {
+		return err
+	}
+	return c.writeToBackend(filename, encoded)
+}
+
+func (c *CompressedStorage) writeToBackend(filename string, data []byte) error {
+	writer, err := c.backend.NewWriter(filename)
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(data); err != nil {
+		writer.Close()
+		return err
+	}
+	return writer.Close()
+}
+
+func (c *CompressedStorage) Read(filename string) ([]byte, error) {
+	data, err := c.backend.Read(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.HasPrefix(data, deltaMagic) {
+		return c.decompress(data)
+	}
+
+	deltaJson, err := c.decompress(data[len(deltaMagic):])
+	if err != nil {
+		return nil, err
+	}
+	var delta versionDelta
+	if err := json.Unmarshal(deltaJson, &delta); err != nil {
+		return nil, fmt.Errorf("invalid delta in %s: %w", filename, err)
+	}
+	base, err := c.Read(delta.Base)
+	if err != nil {
+		return nil, fmt.Errorf("reading base %s of %s: %w", delta.Base, filename, err)
+	}
+	return delta.apply(base)
+}
+
+// Delete removes filename from the backend. A version delta encoded against it is rebuilt in full first.
+func (c *CompressedStorage) Delete(filename string) error {
+	if dependent, ok := c.deltaDependent(filename); ok {
+		content, err := c.Read(dependent)
+		if err != nil {
+			return fmt.Errorf("reading %s, which is delta encoded against %s: %w", dependent, filename, err)
+		}
+		full, err := c.compress(content)
+		if err != nil {
+			return err
+		}
+		if err := c.writeToBackend(dependent, full); err != nil {
+			return err
+		}
+	}
+
+	return c.backend.Delete(filename)
+}
+
+// deltaDependent returns the successor of a version file if it is delta encoded against it.
+func (c *CompressedStorage) deltaDependent(filename string) (string, bool) {
+	match := versionFileRegexp.FindStringSubmatch(filename)
+	if match == nil {
+		return "", false
+	}
+	version, err := strconv.Atoi(match[2])
+	if err != nil {
+		return "", false
+	}
+
+	successor := fmt.Sprintf("%sv%d.html", match[1], version+1)
+	data, err := c.backend.Read(successor)
+	if err != nil || !bytes.HasPrefix(data, deltaMagic) {
+		return "", false
+	}
+	deltaJson, err := c.decompress(data[len(deltaMagic):])
+	if err != nil {
+		return "", false
+	}
+	var delta versionDelta
+	if err := json.Unmarshal(deltaJson, &delta); err != nil || delta.Base != filename {
+		return "", false
+	}
+	return successor, true
+}
+
+func (c *CompressedStorage) NewReader(filename string) (io.ReadCloser, error) {
+	content, err := c.Read(filename)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
+
+func (c *CompressedStorage) NewWriter(filename string) (io.WriteCloser, error) {
+	return &bufferedWriter{store: func(content []byte) error {
+		return c.Store(filename, content)
+	}}, nil
+}
+
+// Stat reports the size of the decoded content, so it has to read the file.
+func (c *CompressedStorage) Stat(filename string) (FileInfo, error) {
+	info, err := c.backend.Stat(filename)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	content, err := c.Read(filename)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	info.Size = int64(len(content))
+	return info, nil
+}
+
+func (c *CompressedStorage) List(prefix string) ([]string, error) {
+	return c.backend.List(prefix)
+}
+
+func (c *CompressedStorage) encode(filename string, content []byte) ([]byte, error) {
+	full, err := c.compress(content)
+	if err != nil {
+		return nil, err
+	}
+
+	baseFilename, ok := c.deltaBase(filename)
+	if !ok {
+		return full, nil
+	}
+	base, err := c.Read(baseFilename)
+	if err != nil {
+		// Without the predecessor, e.g. after pruning, the version becomes a keyframe
+		return full, nil
+	}
+
+	deltaJson, err := json.Marshal(computeDelta(baseFilename, base, content))
+	if err != nil {
+		return nil, err
+	}
+	compressedDelta, err := c.compress(deltaJson)
+	if err != nil {
+		return nil, err
+	}
+
+	encodedDelta := append(append([]byte{}, deltaMagic...), compressedDelta...)
+	if len(encodedDelta) >= len(full) {
+		return full, nil
+	}
+	return encodedDelta, nil
+}
+
+// deltaBase returns the predecessor a version file is delta encoded against; keyframes and other files have none.
+func (c *CompressedStorage) deltaBase(filename string) (string, bool) {
+	if !c.deltas {
+		return "", false
+	}
+
+	match := versionFileRegexp.FindStringSubmatch(filename)
+	if match == nil {
+		return "", false
+	}
+	version, err := strconv.Atoi(match[2])
+	if err != nil || version <= 1 || (version-1)%c.keyframeInterval == 0 {
+		return "", false
+	}
+	return fmt.Sprintf("%sv%d.html", match[1], version-1), true
+}
+
+func (c *CompressedStorage) compress(content []byte) ([]byte, error) {
+	switch c.compression {
+	case CompressionGzip:
+		var compressed bytes.Buffer
+		writer := gzip.NewWriter(&compressed)
+		if _, err := writer.Write(content); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+		return compressed.Bytes(), nil
+	case CompressionZstd:
+		return c.zstdEncoder.EncodeAll(content, nil), nil
+	default:
+		return content, nil
+	}
+}
+
+// decompress detects the compression by its magic bytes; anything else is returned as it is.
+func (c *CompressedStorage) decompress(data []byte) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(data, gzipMagic):
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		return io.ReadAll(reader)
+	case bytes.HasPrefix(data, zstdMagic):
+		return c.zstdDecoder.DecodeAll(data, nil)
+	default:
+		return data, nil
+	}
+}
+
+// versionDelta rebuilds a version from the lines of its Base version: each op either copies
+// the base lines [From, To) or inserts new text.
+type versionDelta struct {
+	Base string    `json:"base"`
+	Ops  []deltaOp `json:"ops"`
+}
+
+type deltaOp struct {
+	From   int    `json:"from,omitempty"`
+	To     int    `json:"to,omitempty"`
+	Insert string `json:"insert,omitempty"`
+}
+
+func computeDelta(baseFilename string, base []byte, content []byte) versionDelta {
+	baseLines, lines := splitLines(string(base)), splitLines(string(content))
+	delta := versionDelta{Base: baseFilename, Ops: make([]deltaOp, 0)}
+
+	matcher := difflib.NewMatcher(baseLines, lines)
+	for _, opcode := range matcher.GetOpCodes() {
+		switch opcode.Tag {
+		case 'e':
+			delta.Ops = append(delta.Ops, deltaOp{From: opcode.I1, To: opcode.I2})
+		case 'r', 'i':
+			delta.Ops = append(delta.Ops, deltaOp{Insert: strings.Join(lines[opcode.J1:opcode.J2], "")})
+		}
+	}
+	return delta
+}
+
+func (d versionDelta) apply(base []byte) ([]byte, error) {
+	baseLines := splitLines(string(base))
+
+	var content strings.Builder
+	for _, op := range d.Ops {
+		if op.Insert != "" {
+			content.WriteString(op.Insert)
+			continue
+		}
+		if op.From < 0 || op.To > len(baseLines) || op.From > op.To {
+			return nil, errors.New("delta doesn't match its base")
+		}
+		content.WriteString(strings.Join(baseLines[op.From:op.To], ""))
+	}
+	return []byte(content.String()), nil
+}
+
+// splitLines splits text after each newline, so joining the lines gives back the exact text.
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
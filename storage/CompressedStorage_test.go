@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func versionContent(version int) string {
+	var content strings.Builder
+	content.WriteString("<html>\n<body>\n")
+	for line := 0; line < 200; line++ {
+		fmt.Fprintf(&content, "<p>Paragraph %d of a long page that barely changes between versions.</p>\n", line)
+	}
+	fmt.Fprintf(&content, "<footer>Version %d</footer>\n</body>\n</html>\n", version)
+	return content.String()
+}
+
+func newCompressedStorage(t *testing.T, compression Compression) (*CompressedStorage, string) {
+	directory := t.TempDir()
+	sut, err := NewCompressedStorage(NewFileStorage(directory), compression)
+	require.NoError(t, err)
+	return sut, directory
+}
+
+func TestCompressedStorage_Compressions(t *testing.T) {
+	tests := []struct {
+		compression   Compression
+		expectedMagic []byte
+	}{
+		{CompressionNone, []byte("<html>")},
+		{CompressionGzip, gzipMagic},
+		{CompressionZstd, zstdMagic},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.compression), func(t *testing.T) {
+			sut, directory := newCompressedStorage(t, tt.compression)
+			writeFile(t, sut, "example.com/v1.html", versionContent(1))
+
+			raw, err := os.ReadFile(filepath.Join(directory, "example.com/v1.html"))
+			require.NoError(t, err)
+			assert.True(t, bytes.HasPrefix(raw, tt.expectedMagic))
+
+			content, err := sut.Read("example.com/v1.html")
+			require.NoError(t, err)
+			assert.Equal(t, versionContent(1), string(content))
+		})
+	}
+}
+
+func TestCompressedStorage_ReadsFilesWrittenWithOtherSettings(t *testing.T) {
+	directory := t.TempDir()
+	writeFile(t, NewFileStorage(directory), "example.com/v1.html", "uncompressed")
+	gzipStorage, err := NewCompressedStorage(NewFileStorage(directory), CompressionGzip)
+	require.NoError(t, err)
+	writeFile(t, gzipStorage, "example.com/v2.html", "gzipped")
+
+	sut, err := NewCompressedStorage(NewFileStorage(directory), CompressionZstd)
+	require.NoError(t, err)
+
+	for filename, expected := range map[string]string{"example.com/v1.html": "uncompressed", "example.com/v2.html": "gzipped"} {
+		content, err := sut.Read(filename)
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(content))
+	}
+}
+
+func TestCompressedStorage_DeltaEncodesVersions(t *testing.T) {
+	sut, directory := newCompressedStorage(t, CompressionZstd)
+	sut.SetDeltas(true, 3)
+
+	for version := 1; version <= 5; version++ {
+		writeFile(t, sut, fmt.Sprintf("example.com/v%d.html", version), versionContent(version))
+	}
+	writeFile(t, sut, "example.com/v2.diff.json", `{"not":"a version"}`)
+
+	for version, expectDelta := range map[int]bool{1: false, 2: true, 3: true, 4: false, 5: true} {
+		raw, err := os.ReadFile(filepath.Join(directory, fmt.Sprintf("example.com/v%d.html", version)))
+		require.NoError(t, err)
+		assert.Equal(t, expectDelta, bytes.HasPrefix(raw, deltaMagic), "version %d", version)
+
+		content, err := sut.Read(fmt.Sprintf("example.com/v%d.html", version))
+		require.NoError(t, err)
+		assert.Equal(t, versionContent(version), string(content), "version %d", version)
+	}
+
+	raw, err := os.ReadFile(filepath.Join(directory, "example.com/v2.diff.json"))
+	require.NoError(t, err)
+	assert.False(t, bytes.HasPrefix(raw, deltaMagic))
+}
+
+func TestCompressedStorage_StoresFullVersionIfDeltaIsNotSmaller(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous bool
+		content  string
+	}{
+		{"Rewritten page", true, "<html>\n<body>completely different</body>\n</html>\n"},
+		{"Missing predecessor", false, versionContent(2)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sut, directory := newCompressedStorage(t, CompressionNone)
+			sut.SetDeltas(true, DefaultKeyframeInterval)
+			if tt.previous {
+				writeFile(t, sut, "example.com/v1.html", versionContent(1))
+			}
+			writeFile(t, sut, "example.com/v2.html", tt.content)
+
+			raw, err := os.ReadFile(filepath.Join(directory, "example.com/v2.html"))
+			require.NoError(t, err)
+			assert.Equal(t, tt.content, string(raw))
+		})
+	}
+}
+
+func TestVersionDelta_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		base    string
+		content string
+	}{
+		{"Changed line", "a\nb\nc\n", "a\nB\nc\n"},
+		{"Added and removed lines", "a\nb\nc\nd\n", "x\na\nc\nd\ne\n"},
+		{"No trailing newline", "a\nb", "a\nb\nc"},
+		{"Empty content", "a\n", ""},
+		{"Empty base", "", "a\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delta := computeDelta("base", []byte(tt.base), []byte(tt.content))
+			content, err := delta.apply([]byte(tt.base))
+			require.NoError(t, err)
+			assert.Equal(t, tt.content, string(content))
+		})
+	}
+}
+
+func TestCompressedStorage_DeleteRebuildsDeltaEncodedSuccessor(t *testing.T) {
+	sut, directory := newCompressedStorage(t, CompressionGzip)
+	sut.SetDeltas(true, DefaultKeyframeInterval)
+	for version := 1; version <= 3; version++ {
+		writeFile(t, sut, fmt.Sprintf("example.com/v%d.html", version), versionContent(version))
+	}
+
+	require.NoError(t, sut.Delete("example.com/v2.html"))
+
+	_, err := os.Stat(filepath.Join(directory, "example.com/v2.html"))
+	assert.True(t, os.IsNotExist(err))
+	raw, err := os.ReadFile(filepath.Join(directory, "example.com/v3.html"))
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(raw, gzipMagic))
+
+	content, err := sut.Read("example.com/v3.html")
+	require.NoError(t, err)
+	assert.Equal(t, versionContent(3), string(content))
+
+	require.NoError(t, sut.Delete("example.com/v1.html"))
+	content, err = sut.Read("example.com/v3.html")
+	require.NoError(t, err)
+	assert.Equal(t, versionContent(3), string(content))
+}
@@ -0,0 +1,215 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	objectsDirectory = "objects"
+	refsDirectory    = "refs"
+)
+
+// ContentAddressedStorage keeps every distinct content once, as a blob named by its SHA-256 under
+// objects/, and every written filename as a small ref under refs/ pointing to its blob. Identical
+// versions of different URLs, like mirrored pages or shared boilerplate, therefore share one blob.
+// Each blob counts its refs and is removed when the last one is deleted.
+type ContentAddressedStorage struct {
+	directory string
+	// mu serializes ref and reference count updates; reads hold it shared, so a blob can't be removed
+	// between resolving a ref and opening the blob
+	mu sync.RWMutex
+}
+
+func NewContentAddressedStorage(directory string) *ContentAddressedStorage {
+	for _, subdirectory := range []string{objectsDirectory, refsDirectory} {
+		if err := os.MkdirAll(filepath.Join(directory, subdirectory), os.ModePerm); err != nil {
+			panic("Failed to create directory: " + err.Error())
+		}
+	}
+
+	return &ContentAddressedStorage{directory: directory}
+}
+
+// Store points filename at the blob of content, writing the blob only if no other file has the same content.
+// created tells whether a new blob was written.
+func (c *ContentAddressedStorage) Store(filename string, content []byte) (created bool, err error) {
+	if err := validateFilename(filename); err != nil {
+		return false, err
+	}
+
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	previousHash, err := c.readRef(filename)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return false, err
+	}
+	if previousHash == hash {
+		return false, nil
+	}
+
+	if _, err := os.Stat(c.blobPath(hash)); errors.Is(err, os.ErrNotExist) {
+		if err := writeFileAtomically(c.blobPath(hash), content); err != nil {
+			return false, err
+		}
+		created = true
+	}
+	if err := c.addReferences(hash, 1); err != nil {
+		return created, err
+	}
+	if err := writeFileAtomically(c.refPath(filename), []byte(hash)); err != nil {
+		return created, err
+	}
+
+	if previousHash != "" {
+		return created, c.addReferences(previousHash, -1)
+	}
+	return created, nil
+}
+
+// Delete removes filename, and its blob if no other file references it.
+func (c *ContentAddressedStorage) Delete(filename string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	hash, err := c.readRef(filename)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(c.refPath(filename)); err != nil {
+		return err
+	}
+	return c.addReferences(hash, -1)
+}
+
+func (c *ContentAddressedStorage) Read(filename string) ([]byte, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	hash, err := c.readRef(filename)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(c.blobPath(hash))
+}
+
+// NewReader opens the blob under the lock; a blob removed later stays readable through the open file.
+func (c *ContentAddressedStorage) NewReader(filename string) (io.ReadCloser, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	hash, err := c.readRef(filename)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(c.blobPath(hash))
+}
+
+func (c *ContentAddressedStorage) NewWriter(filename string) (io.WriteCloser, error) {
+	if err := validateFilename(filename); err != nil {
+		return nil, err
+	}
+	return &bufferedWriter{store: func(content []byte) error {
+		_, err := c.Store(filename, content)
+		return err
+	}}, nil
+}
+
+// Stat reports the size of the blob, and as ModTime when filename was last written, not when its blob was created.
+func (c *ContentAddressedStorage) Stat(filename string) (FileInfo, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	hash, err := c.readRef(filename)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	refInfo, err := os.Stat(c.refPath(filename))
+	if err != nil {
+		return FileInfo{}, err
+	}
+	blobInfo, err := os.Stat(c.blobPath(hash))
+	if err != nil {
+		return FileInfo{}, err
+	}
+	return FileInfo{Name: filename, Size: blobInfo.Size(), ModTime: refInfo.ModTime()}, nil
+}
+
+func (c *ContentAddressedStorage) List(prefix string) ([]string, error) {
+	return listFiles(filepath.Join(c.directory, refsDirectory), prefix)
+}
+
+func (c *ContentAddressedStorage) readRef(filename string) (string, error) {
+	hash, err := os.ReadFile(c.refPath(filename))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(hash)), nil
+}
+
+// referenceCount returns how many files point to the blob with hash.
+func (c *ContentAddressedStorage) referenceCount(hash string) (int, error) {
+	count, err := os.ReadFile(c.blobPath(hash) + ".refs")
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(count)))
+}
+
+// addReferences changes the reference count of a blob by delta and removes blobs no longer referenced.
+func (c *ContentAddressedStorage) addReferences(hash string, delta int) error {
+	count, err := c.referenceCount(hash)
+	if err != nil {
+		return fmt.Errorf("reading reference count of blob %s: %w", hash, err)
+	}
+
+	count += delta
+	if count > 0 {
+		return writeFileAtomically(c.blobPath(hash)+".refs", []byte(strconv.Itoa(count)))
+	}
+
+	if err := os.Remove(c.blobPath(hash)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := os.Remove(c.blobPath(hash) + ".refs"); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// blobPath spreads blobs over 256 directories by the first byte of their hash.
+func (c *ContentAddressedStorage) blobPath(hash string) string {
+	return filepath.Join(c.directory, objectsDirectory, hash[:2], hash)
+}
+
+func (c *ContentAddressedStorage) refPath(filename string) string {
+	return filepath.Join(c.directory, refsDirectory, filepath.FromSlash(filename))
+}
+
+// validateFilename rejects names that would escape the refs directory.
+func validateFilename(filename string) error {
+	if filename == "" || filepath.IsAbs(filename) {
+		return fmt.Errorf("invalid filename %q", filename)
+	}
+	for _, element := range strings.Split(filename, "/") {
+		if element == ".." {
+			return fmt.Errorf("invalid filename %q", filename)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func contentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func writeFile(t *testing.T, s IStorage, filename string, content string) {
+	writer, err := s.NewWriter(filename)
+	require.NoError(t, err)
+	_, err = writer.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+}
+
+func countBlobs(t *testing.T, directory string) int {
+	blobs := 0
+	err := filepath.WalkDir(filepath.Join(directory, objectsDirectory), func(path string, entry os.DirEntry, err error) error {
+		if err == nil && !entry.IsDir() && filepath.Ext(path) != ".refs" {
+			blobs++
+		}
+		return err
+	})
+	require.NoError(t, err)
+	return blobs
+}
+
+func TestContentAddressedStorage_SharesBlobsOfIdenticalContent(t *testing.T) {
+	directory := t.TempDir()
+	sut := NewContentAddressedStorage(directory)
+
+	writeFile(t, sut, "example.com/v1.html", "<html>boilerplate</html>")
+	writeFile(t, sut, "mirror.example.com/v1.html", "<html>boilerplate</html>")
+	writeFile(t, sut, "example.com/about/v1.html", "<html>about</html>")
+
+	assert.Equal(t, 2, countBlobs(t, directory))
+	count, err := sut.referenceCount(contentHash("<html>boilerplate</html>"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	content, err := sut.Read("mirror.example.com/v1.html")
+	require.NoError(t, err)
+	assert.Equal(t, "<html>boilerplate</html>", string(content))
+
+	info, err := sut.Stat("example.com/about/v1.html")
+	require.NoError(t, err)
+	assert.Equal(t, int64(len("<html>about</html>")), info.Size)
+}
+
+func TestContentAddressedStorage_WritesInSeveralParts(t *testing.T) {
+	sut := NewContentAddressedStorage(t.TempDir())
+
+	writer, err := sut.NewWriter("example.com/v1.html")
+	require.NoError(t, err)
+	_, err = writer.Write([]byte("<html>"))
+	require.NoError(t, err)
+	_, err = writer.Write([]byte("</html>"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	content, err := sut.Read("example.com/v1.html")
+	require.NoError(t, err)
+	assert.Equal(t, "<html></html>", string(content))
+}
+
+func TestContentAddressedStorage_DeleteRemovesUnreferencedBlobs(t *testing.T) {
+	directory := t.TempDir()
+	sut := NewContentAddressedStorage(directory)
+	writeFile(t, sut, "a.com/v1.html", "shared")
+	writeFile(t, sut, "b.com/v1.html", "shared")
+
+	require.NoError(t, sut.Delete("a.com/v1.html"))
+	assert.Equal(t, 1, countBlobs(t, directory))
+	_, err := sut.Read("a.com/v1.html")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	require.NoError(t, sut.Delete("b.com/v1.html"))
+	assert.Equal(t, 0, countBlobs(t, directory))
+	assert.ErrorIs(t, sut.Delete("b.com/v1.html"), os.ErrNotExist)
+}
+
+func TestContentAddressedStorage_Overwrite(t *testing.T) {
+	tests := []struct {
+		name          string
+		second        string
+		expectedBlobs int
+	}{
+		{"Same content keeps the reference", "first", 1},
+		{"Other content releases the old blob", "second", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			directory := t.TempDir()
+			sut := NewContentAddressedStorage(directory)
+
+			writeFile(t, sut, "a.com/v1.html", "first")
+			writeFile(t, sut, "a.com/v1.html", tt.second)
+
+			assert.Equal(t, tt.expectedBlobs, countBlobs(t, directory))
+			count, err := sut.referenceCount(contentHash(tt.second))
+			require.NoError(t, err)
+			assert.Equal(t, 1, count)
+
+			content, err := sut.Read("a.com/v1.html")
+			require.NoError(t, err)
+			assert.Equal(t, tt.second, string(content))
+		})
+	}
+}
+
+func TestContentAddressedStorage_ReadsWhileBlobsAreReleased(t *testing.T) {
+	sut := NewContentAddressedStorage(t.TempDir())
+	writeFile(t, sut, "a.com/v1.html", "content 0")
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(done)
+		// every overwrite releases the blob of the previous content
+		for i := 1; i <= 200; i++ {
+			writer, err := sut.NewWriter("a.com/v1.html")
+			if !assert.NoError(t, err) {
+				return
+			}
+			writer.Write([]byte(fmt.Sprintf("content %d", i)))
+			if !assert.NoError(t, writer.Close()) {
+				return
+			}
+		}
+	}()
+
+	for reader := 0; reader < 4; reader++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				content, err := sut.Read("a.com/v1.html")
+				if !assert.NoError(t, err) {
+					return
+				}
+				assert.True(t, strings.HasPrefix(string(content), "content "), string(content))
+
+				_, err = sut.Stat("a.com/v1.html")
+				if !assert.NoError(t, err) {
+					return
+				}
+				file, err := sut.NewReader("a.com/v1.html")
+				if !assert.NoError(t, err) {
+					return
+				}
+				_, err = io.ReadAll(file)
+				assert.NoError(t, err)
+				file.Close()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestContentAddressedStorage_RejectsEscapingFilenames(t *testing.T) {
+	sut := NewContentAddressedStorage(t.TempDir())
+
+	for _, filename := range []string{"", "/etc/passwd", "../outside.html", "a.com/../../outside.html"} {
+		_, err := sut.NewWriter(filename)
+		assert.Error(t, err, filename)
+	}
+}
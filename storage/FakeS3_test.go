@@ -0,0 +1,239 @@
+package storage
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	fakeS3AccessKeyID     = "AKIDEXAMPLE"
+	fakeS3SecretAccessKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+	fakeS3Bucket          = "pages"
+)
+
+type fakeS3Object struct {
+	data    []byte
+	modTime time.Time
+}
+
+// fakeS3 is an in-process server for the subset of the S3 API used by S3Storage, path style addressed,
+// with one bucket. It checks the signature of every request and lists objects in small pages.
+type fakeS3 struct {
+	mu        sync.Mutex
+	objects   map[string]fakeS3Object
+	uploads   map[string]map[int][]byte
+	uploadIds int
+	pageSize  int
+	// failPart makes the upload of this part number fail.
+	failPart int
+	requests []string
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, *httptest.Server) {
+	fake := &fakeS3{objects: map[string]fakeS3Object{}, uploads: map[string]map[int][]byte{}, pageSize: 2}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	return fake, server
+}
+
+func newFakeS3Storage(t *testing.T, prefix string, partSize int) (*S3Storage, *fakeS3) {
+	fake, server := newFakeS3(t)
+	s3, err := NewS3Storage(S3Options{
+		Endpoint:        server.URL,
+		Bucket:          fakeS3Bucket,
+		Prefix:          prefix,
+		AccessKeyID:     fakeS3AccessKeyID,
+		SecretAccessKey: fakeS3SecretAccessKey,
+		PartSize:        partSize,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s3, fake
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		f.fail(w, http.StatusBadRequest, "IncompleteBody")
+		return
+	}
+	if !f.validSignature(r, body) {
+		f.fail(w, http.StatusForbidden, "SignatureDoesNotMatch")
+		return
+	}
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != fakeS3Bucket {
+		f.fail(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	query := r.URL.Query()
+	f.requests = append(f.requests, r.Method+" "+key+" "+r.URL.RawQuery)
+
+	switch {
+	case key == "" && r.Method == http.MethodGet:
+		f.list(w, query.Get("prefix"), query.Get("continuation-token"))
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		f.uploadIds++
+		uploadId := strconv.Itoa(f.uploadIds)
+		f.uploads[uploadId] = map[int][]byte{}
+		writeXml(w, struct {
+			XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+			Key      string
+			UploadId string
+		}{Key: key, UploadId: uploadId})
+	case r.Method == http.MethodPut && query.Has("uploadId"):
+		parts, ok := f.uploads[query.Get("uploadId")]
+		partNumber, _ := strconv.Atoi(query.Get("partNumber"))
+		if !ok {
+			f.fail(w, http.StatusNotFound, "NoSuchUpload")
+			return
+		}
+		if partNumber == f.failPart {
+			f.fail(w, http.StatusInternalServerError, "InternalError")
+			return
+		}
+		parts[partNumber] = body
+		w.Header().Set("ETag", etag(body))
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		f.complete(w, key, query.Get("uploadId"), body)
+	case r.Method == http.MethodDelete && query.Has("uploadId"):
+		delete(f.uploads, query.Get("uploadId"))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut:
+		f.objects[key] = fakeS3Object{data: body, modTime: time.Now()}
+		w.Header().Set("ETag", etag(body))
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		object, ok := f.objects[key]
+		if !ok {
+			f.fail(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(object.data)))
+		w.Header().Set("Last-Modified", object.modTime.UTC().Format(http.TimeFormat))
+		if r.Method == http.MethodGet {
+			w.Write(object.data)
+		}
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		f.fail(w, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+// validSignature signs a copy of the request as received and compares the result, so the canonical form
+// of what went over the wire has to match the one the client signed.
+func (f *fakeS3) validSignature(r *http.Request, body []byte) bool {
+	if r.Header.Get("X-Amz-Content-Sha256") != sha256Hex(body) {
+		return false
+	}
+	date, err := time.Parse("20060102T150405Z", r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return false
+	}
+	signed := r.Clone(r.Context())
+	signV4(signed, fakeS3AccessKeyID, fakeS3SecretAccessKey, DefaultS3Region, "s3", date)
+	return signed.Header.Get("Authorization") == r.Header.Get("Authorization")
+}
+
+func (f *fakeS3) list(w http.ResponseWriter, prefix string, continuationToken string) {
+	keys := make([]string, 0)
+	for key := range f.objects {
+		if strings.HasPrefix(key, prefix) && key > continuationToken {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	type content struct {
+		Key  string
+		Size int
+	}
+	result := struct {
+		XMLName               xml.Name `xml:"ListBucketResult"`
+		Contents              []content
+		IsTruncated           bool
+		NextContinuationToken string `xml:",omitempty"`
+	}{}
+	if len(keys) > f.pageSize {
+		keys = keys[:f.pageSize]
+		result.IsTruncated = true
+		result.NextContinuationToken = keys[len(keys)-1]
+	}
+	for _, key := range keys {
+		result.Contents = append(result.Contents, content{Key: key, Size: len(f.objects[key].data)})
+	}
+	writeXml(w, result)
+}
+
+func (f *fakeS3) complete(w http.ResponseWriter, key string, uploadId string, body []byte) {
+	parts, ok := f.uploads[uploadId]
+	if !ok {
+		f.fail(w, http.StatusNotFound, "NoSuchUpload")
+		return
+	}
+	var request struct {
+		Parts []completedPart `xml:"Part"`
+	}
+	if err := xml.Unmarshal(body, &request); err != nil || len(request.Parts) == 0 {
+		f.fail(w, http.StatusBadRequest, "MalformedXML")
+		return
+	}
+
+	var data []byte
+	for i, part := range request.Parts {
+		partData, ok := parts[part.PartNumber]
+		if part.PartNumber != i+1 || !ok || part.ETag != etag(partData) {
+			// S3 reports some failures of a completion with status 200 and an error document.
+			writeXml(w, struct {
+				XMLName xml.Name `xml:"Error"`
+				Code    string
+				Message string
+			}{Code: "InvalidPart", Message: fmt.Sprintf("part %d", part.PartNumber)})
+			return
+		}
+		data = append(data, partData...)
+	}
+	delete(f.uploads, uploadId)
+	f.objects[key] = fakeS3Object{data: data, modTime: time.Now()}
+	writeXml(w, struct {
+		XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+		Key     string
+	}{Key: key})
+}
+
+func (f *fakeS3) fail(w http.ResponseWriter, status int, code string) {
+	w.WriteHeader(status)
+	writeXml(w, struct {
+		XMLName xml.Name `xml:"Error"`
+		Code    string
+		Message string
+	}{Code: code, Message: code})
+}
+
+func writeXml(w http.ResponseWriter, document interface{}) {
+	w.Header().Set("Content-Type", "application/xml")
+	data, _ := xml.Marshal(document)
+	w.Write(append([]byte(xml.Header), data...))
+}
+
+func etag(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
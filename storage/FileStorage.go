@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FileStorage keeps every file as is below directory. Files are written atomically, see atomicFileWriter.
+type FileStorage struct {
+	directory string
+}
+
+func NewFileStorage(directory string) *FileStorage {
+	if _, err := os.Stat(directory); os.IsNotExist(err) {
+		os.Mkdir(directory, os.ModePerm)
+	}
+
+	if _, err := os.Stat(directory); os.IsNotExist(err) {
+		panic("Failed to create directory")
+	}
+
+	return &FileStorage{directory: directory}
+}
+
+func (d *FileStorage) NewWriter(filename string) (io.WriteCloser, error) {
+	return newAtomicFileWriter(d.path(filename))
+}
+
+func (d *FileStorage) NewReader(filename string) (io.ReadCloser, error) {
+	return os.Open(d.path(filename))
+}
+
+func (d *FileStorage) Read(filename string) ([]byte, error) {
+	return os.ReadFile(d.path(filename))
+}
+
+func (d *FileStorage) Stat(filename string) (FileInfo, error) {
+	info, err := os.Stat(d.path(filename))
+	if err != nil {
+		return FileInfo{}, err
+	}
+	if info.IsDir() {
+		return FileInfo{}, &fs.PathError{Op: "stat", Path: filename, Err: fs.ErrNotExist}
+	}
+	return FileInfo{Name: filename, Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
+func (d *FileStorage) List(prefix string) ([]string, error) {
+	return listFiles(d.directory, prefix)
+}
+
+func (d *FileStorage) Delete(filename string) error {
+	return os.Remove(d.path(filename))
+}
+
+func (d *FileStorage) path(filename string) string {
+	return filepath.Join(d.directory, filepath.FromSlash(filename))
+}
+
+// listFiles returns the slash separated names, relative to root, of the files below root starting with prefix.
+// Only the directory named by the prefix is walked, and temporary files of atomic writes are skipped.
+func listFiles(root string, prefix string) ([]string, error) {
+	start := root
+	if slash := strings.LastIndex(prefix, "/"); slash >= 0 {
+		start = filepath.Join(root, filepath.FromSlash(prefix[:slash]))
+	}
+
+	names := make([]string, 0)
+	err := filepath.WalkDir(start, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path == start && os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if entry.IsDir() || isTempFile(entry.Name()) {
+			return nil
+		}
+
+		relative, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if name := filepath.ToSlash(relative); strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+		return nil
+	})
+	sort.Strings(names)
+	return names, err
+}
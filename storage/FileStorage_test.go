@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStorage_ConcurrentWritersAreIndependent(t *testing.T) {
+	sut := NewFileStorage(t.TempDir())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			writer, err := sut.NewWriter(fmt.Sprintf("example.com/page%d/v1.html", i))
+			if !assert.NoError(t, err) {
+				return
+			}
+			for part := 0; part < 10; part++ {
+				_, err := fmt.Fprintf(writer, "page %d part %d\n", i, part)
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, writer.Close())
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < 20; i++ {
+		content, err := sut.Read(fmt.Sprintf("example.com/page%d/v1.html", i))
+		require.NoError(t, err)
+		var expected string
+		for part := 0; part < 10; part++ {
+			expected += fmt.Sprintf("page %d part %d\n", i, part)
+		}
+		assert.Equal(t, expected, string(content))
+	}
+}
+
+func TestFileStorage_ContentAppearsOnlyWhenClosed(t *testing.T) {
+	directory := t.TempDir()
+	sut := NewFileStorage(directory)
+
+	writer, err := sut.NewWriter("example.com/v1.html")
+	require.NoError(t, err)
+	_, err = writer.Write([]byte("<html>"))
+	require.NoError(t, err)
+
+	_, err = sut.Stat("example.com/v1.html")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	names, err := sut.List("")
+	require.NoError(t, err)
+	assert.Empty(t, names)
+
+	require.NoError(t, writer.Close())
+	content, err := sut.Read("example.com/v1.html")
+	require.NoError(t, err)
+	assert.Equal(t, "<html>", string(content))
+	assert.ErrorIs(t, writer.Close(), os.ErrClosed)
+}
+
+func TestFileStorage_FailedWriteKeepsPreviousContent(t *testing.T) {
+	directory := t.TempDir()
+	sut := NewFileStorage(directory)
+	writeFile(t, sut, "example.com/v1.html", "complete")
+
+	writer, err := sut.NewWriter("example.com/v1.html")
+	require.NoError(t, err)
+	_, err = writer.Write([]byte("trunc"))
+	require.NoError(t, err)
+	// Simulate a failing disk
+	require.NoError(t, writer.(*atomicFileWriter).temp.Close())
+	_, err = writer.Write([]byte("ated"))
+	require.Error(t, err)
+	assert.Error(t, writer.Close())
+
+	content, err := sut.Read("example.com/v1.html")
+	require.NoError(t, err)
+	assert.Equal(t, "complete", string(content))
+
+	entries, err := os.ReadDir(filepath.Join(directory, "example.com"))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "the temporary file is removed")
+}
+
+func TestRemoveStaleTempFiles_KeepsThoseOfRunningWrites(t *testing.T) {
+	directory := t.TempDir()
+	sut := NewFileStorage(directory)
+	writeFile(t, sut, "example.com/v1.html", "complete")
+	interrupted, err := sut.NewWriter("example.com/v2.html")
+	require.NoError(t, err)
+	// Simulate a crash long ago: the writer is never closed
+	require.NoError(t, interrupted.(*atomicFileWriter).temp.Close())
+	longAgo := time.Now().Add(-48 * time.Hour)
+	require.NoError(t, os.Chtimes(interrupted.(*atomicFileWriter).temp.Name(), longAgo, longAgo))
+	running, err := sut.NewWriter("example.com/v3.html")
+	require.NoError(t, err)
+
+	removed, err := RemoveStaleTempFiles(directory, 24*time.Hour)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	_, err = running.Write([]byte("running"))
+	require.NoError(t, err)
+	require.NoError(t, running.Close())
+	names, err := sut.List("")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"example.com/v1.html", "example.com/v3.html"}, names)
+	entries, err := os.ReadDir(filepath.Join(directory, "example.com"))
+	require.NoError(t, err)
+	assert.Len(t, entries, 2, "no temporary file is left")
+}
+
+func TestRemoveStaleTempFiles_MissingDirectory(t *testing.T) {
+	removed, err := RemoveStaleTempFiles(filepath.Join(t.TempDir(), "missing"), time.Hour)
+	require.NoError(t, err)
+	assert.Zero(t, removed)
+}
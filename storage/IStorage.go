@@ -0,0 +1,27 @@
+// Package storage keeps the content of crawled page versions.
+package storage
+
+import (
+	"io"
+	"time"
+)
+
+// IStorage keeps files by slash separated relative names like "example.com/v1.html".
+// Missing files are reported with errors matching fs.ErrNotExist. Implementations are safe for concurrent use.
+type IStorage interface {
+	// NewWriter streams a new content of filename. Every writer is independent; the content is stored
+	// when the writer is closed, and replaces the previous one only if all writes succeeded.
+	NewWriter(filename string) (io.WriteCloser, error)
+	NewReader(filename string) (io.ReadCloser, error)
+	Read(filename string) ([]byte, error)
+	Stat(filename string) (FileInfo, error)
+	// List returns the sorted names of all files starting with prefix; an empty prefix lists everything.
+	List(prefix string) ([]string, error)
+	Delete(filename string) error
+}
+
+type FileInfo struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+}
@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"io"
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func storageImplementations(t *testing.T) map[string]func() IStorage {
+	return map[string]func() IStorage{
+		"FileStorage":             func() IStorage { return NewFileStorage(t.TempDir()) },
+		"ContentAddressedStorage": func() IStorage { return NewContentAddressedStorage(t.TempDir()) },
+		"CompressedStorage": func() IStorage {
+			compressed, err := NewCompressedStorage(NewFileStorage(t.TempDir()), CompressionGzip)
+			require.NoError(t, err)
+			return compressed
+		},
+		"S3Storage": func() IStorage {
+			s3, _ := newFakeS3Storage(t, "crawl", 0)
+			return s3
+		},
+	}
+}
+
+func TestIStorage_Contract(t *testing.T) {
+	for name, newStorage := range storageImplementations(t) {
+		t.Run(name, func(t *testing.T) {
+			sut := newStorage()
+
+			writer, err := sut.NewWriter("example.com/v1.html")
+			require.NoError(t, err)
+			_, err = io.WriteString(writer, "<html>")
+			require.NoError(t, err)
+			_, err = io.WriteString(writer, "</html>")
+			require.NoError(t, err)
+			require.NoError(t, writer.Close())
+			writeFile(t, sut, "example.com/about/v1.html", "about")
+			writeFile(t, sut, "example.org/v1.html", "other")
+
+			content, err := sut.Read("example.com/v1.html")
+			require.NoError(t, err)
+			assert.Equal(t, "<html></html>", string(content))
+
+			reader, err := sut.NewReader("example.com/about/v1.html")
+			require.NoError(t, err)
+			content, err = io.ReadAll(reader)
+			require.NoError(t, err)
+			require.NoError(t, reader.Close())
+			assert.Equal(t, "about", string(content))
+
+			info, err := sut.Stat("example.com/v1.html")
+			require.NoError(t, err)
+			assert.Equal(t, "example.com/v1.html", info.Name)
+			assert.Equal(t, int64(len("<html></html>")), info.Size)
+			assert.False(t, info.ModTime.IsZero())
+
+			all, err := sut.List("")
+			require.NoError(t, err)
+			assert.Equal(t, []string{"example.com/about/v1.html", "example.com/v1.html", "example.org/v1.html"}, all)
+			domain, err := sut.List("example.com/")
+			require.NoError(t, err)
+			assert.Equal(t, []string{"example.com/about/v1.html", "example.com/v1.html"}, domain)
+			partial, err := sut.List("example.o")
+			require.NoError(t, err)
+			assert.Equal(t, []string{"example.org/v1.html"}, partial)
+			none, err := sut.List("missing.com/")
+			require.NoError(t, err)
+			assert.Empty(t, none)
+
+			require.NoError(t, sut.Delete("example.com/v1.html"))
+			_, err = sut.Stat("example.com/v1.html")
+			assert.ErrorIs(t, err, fs.ErrNotExist)
+			_, err = sut.Read("example.com/v1.html")
+			assert.ErrorIs(t, err, fs.ErrNotExist)
+			_, err = sut.NewReader("example.com/v1.html")
+			assert.ErrorIs(t, err, fs.ErrNotExist)
+			assert.ErrorIs(t, sut.Delete("example.com/v1.html"), fs.ErrNotExist)
+		})
+	}
+}
+
+func TestFileStorage_StatOfDirectoryIsNotExist(t *testing.T) {
+	sut := NewFileStorage(t.TempDir())
+	writeFile(t, sut, "example.com/v1.html", "content")
+
+	_, err := sut.Stat("example.com")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// MigrationStats sums up a storage migration. StoredBytes is what the migrated files take up
+// in the target, so Bytes - StoredBytes is the space saved by deduplication.
+type MigrationStats struct {
+	Files       int
+	Bytes       int64
+	Blobs       int
+	StoredBytes int64
+}
+
+// MigrateToContentAddressed copies every file of the FileStorage layout in sourceDirectory into target,
+// keeping the filenames, so page versions stored in the database keep working after switching storages.
+// The source may also be the directory of target; its objects and refs are skipped. With deleteOriginals,
+// each file is deleted once it is migrated, and so are the directories left empty. Migrating again
+// only adds files missing in target.
+func MigrateToContentAddressed(sourceDirectory string, target *ContentAddressedStorage, deleteOriginals bool) (MigrationStats, error) {
+	var stats MigrationStats
+	var directories []string
+
+	err := filepath.WalkDir(sourceDirectory, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relativePath, err := filepath.Rel(sourceDirectory, path)
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if relativePath == objectsDirectory || relativePath == refsDirectory {
+				return filepath.SkipDir
+			}
+			if relativePath != "." {
+				directories = append(directories, path)
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		created, err := target.Store(filepath.ToSlash(relativePath), content)
+		if err != nil {
+			return err
+		}
+
+		stats.Files++
+		stats.Bytes += int64(len(content))
+		if created {
+			stats.Blobs++
+			stats.StoredBytes += int64(len(content))
+		}
+
+		if deleteOriginals {
+			return os.Remove(path)
+		}
+		return nil
+	})
+
+	if err == nil && deleteOriginals {
+		// Deepest directories come last; removing fails harmlessly for those that aren't empty
+		for i := len(directories) - 1; i >= 0; i-- {
+			os.Remove(directories[i])
+		}
+	}
+	return stats, err
+}
@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newFileLayout(t *testing.T) string {
+	directory := t.TempDir()
+	fileStorage := NewFileStorage(directory)
+	writeFile(t, fileStorage, "example.com/v1.html", "<html>home</html>")
+	writeFile(t, fileStorage, "example.com/v2.html", "<html>home v2</html>")
+	writeFile(t, fileStorage, "www.example.com/v1.html", "<html>home</html>")
+	return directory
+}
+
+func TestMigrateToContentAddressed(t *testing.T) {
+	source := newFileLayout(t)
+	target := NewContentAddressedStorage(t.TempDir())
+
+	stats, err := MigrateToContentAddressed(source, target, false)
+	require.NoError(t, err)
+
+	assert.Equal(t, MigrationStats{Files: 3, Bytes: 54, Blobs: 2, StoredBytes: 37}, stats)
+	for _, filename := range []string{"example.com/v1.html", "example.com/v2.html", "www.example.com/v1.html"} {
+		expected, err := os.ReadFile(filepath.Join(source, filename))
+		require.NoError(t, err)
+		migrated, err := target.Read(filename)
+		require.NoError(t, err)
+		assert.Equal(t, expected, migrated)
+	}
+
+	stats, err = MigrateToContentAddressed(source, target, false)
+	require.NoError(t, err)
+	assert.Equal(t, 0, stats.Blobs, "migrating again must not store anything twice")
+}
+
+func TestMigrateToContentAddressed_InPlace(t *testing.T) {
+	directory := newFileLayout(t)
+	target := NewContentAddressedStorage(directory)
+
+	stats, err := MigrateToContentAddressed(directory, target, true)
+	require.NoError(t, err)
+	assert.Equal(t, 3, stats.Files)
+
+	entries, err := os.ReadDir(directory)
+	require.NoError(t, err)
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	assert.ElementsMatch(t, []string{objectsDirectory, refsDirectory}, names, "originals and their directories are deleted")
+
+	content, err := target.Read("www.example.com/v1.html")
+	require.NoError(t, err)
+	assert.Equal(t, "<html>home</html>", string(content))
+}
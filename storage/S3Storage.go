@@ -0,0 +1,382 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultS3Region   = "us-east-1"
+	DefaultS3PartSize = 8 << 20
+	// MinS3PartSize is the smallest part S3 accepts in a multipart upload, except for the last one.
+	MinS3PartSize = 5 << 20
+)
+
+// S3Options configure an S3Storage. Empty credentials are taken from the AWS_ACCESS_KEY_ID and
+// AWS_SECRET_ACCESS_KEY environment variables.
+type S3Options struct {
+	// Endpoint is the base URL of the service, e.g. "https://s3.eu-central-1.amazonaws.com" or "http://localhost:9000".
+	Endpoint        string
+	Region          string
+	Bucket          string
+	Prefix          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// PartSize is the size of the parts of multipart uploads, at least MinS3PartSize; smaller files are uploaded
+	// with a single request.
+	PartSize int
+}
+
+// S3Storage keeps files as objects of an S3 compatible object storage (AWS S3, MinIO, ...), addressed path style.
+// A file "example.com/v1.html" is the object "<prefix>/example.com/v1.html", so the layout matches FileStorage.
+type S3Storage struct {
+	endpoint *url.URL
+	options  S3Options
+	client   *http.Client
+	now      func() time.Time
+}
+
+// Validate checks the options NewS3Storage requires. Each error starts with the name of the option it is about,
+// like "bucket: required".
+func (options S3Options) Validate() []error {
+	var errs []error
+	if endpoint, err := url.Parse(options.Endpoint); err != nil || endpoint.Host == "" || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+		errs = append(errs, fmt.Errorf("endpoint: must be an absolute http(s) URL, got %q", options.Endpoint))
+	}
+	if options.Bucket == "" {
+		errs = append(errs, errors.New("bucket: required"))
+	}
+	if options.PartSize != 0 && options.PartSize < MinS3PartSize {
+		errs = append(errs, fmt.Errorf("partSize: must be at least %d bytes, got %d", MinS3PartSize, options.PartSize))
+	}
+	return errs
+}
+
+func NewS3Storage(options S3Options) (*S3Storage, error) {
+	if errs := options.Validate(); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid S3 options: %w", errors.Join(errs...))
+	}
+	endpoint, _ := url.Parse(options.Endpoint)
+	if options.Region == "" {
+		options.Region = DefaultS3Region
+	}
+	if options.PartSize == 0 {
+		options.PartSize = DefaultS3PartSize
+	}
+	if options.AccessKeyID == "" {
+		options.AccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+	}
+	if options.SecretAccessKey == "" {
+		options.SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+	}
+	options.Prefix = strings.Trim(options.Prefix, "/")
+
+	return &S3Storage{
+		endpoint: endpoint,
+		options:  options,
+		client:   &http.Client{Timeout: 5 * time.Minute},
+		now:      time.Now,
+	}, nil
+}
+
+func (s *S3Storage) NewWriter(filename string) (io.WriteCloser, error) {
+	if err := validateFilename(filename); err != nil {
+		return nil, err
+	}
+	return &s3Writer{storage: s, filename: filename}, nil
+}
+
+func (s *S3Storage) NewReader(filename string) (io.ReadCloser, error) {
+	resp, err := s.do(http.MethodGet, s.key(filename), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkResponse(resp, "open", filename); err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *S3Storage) Read(filename string) ([]byte, error) {
+	reader, err := s.NewReader(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+func (s *S3Storage) Stat(filename string) (FileInfo, error) {
+	resp, err := s.do(http.MethodHead, s.key(filename), nil, nil)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	if err := checkResponse(resp, "stat", filename); err != nil {
+		return FileInfo{}, err
+	}
+	resp.Body.Close()
+
+	modTime, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	return FileInfo{Name: filename, Size: resp.ContentLength, ModTime: modTime}, nil
+}
+
+func (s *S3Storage) List(prefix string) ([]string, error) {
+	names := make([]string, 0)
+	continuationToken := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {s.key(prefix)}}
+		if continuationToken != "" {
+			query.Set("continuation-token", continuationToken)
+		}
+		resp, err := s.do(http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+		var result struct {
+			Contents []struct {
+				Key string
+			}
+			IsTruncated           bool
+			NextContinuationToken string
+		}
+		if err := decodeResponse(resp, "list", prefix, &result); err != nil {
+			return nil, err
+		}
+
+		for _, object := range result.Contents {
+			names = append(names, strings.TrimPrefix(object.Key, s.key("")))
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return names, nil
+		}
+		continuationToken = result.NextContinuationToken
+	}
+}
+
+// Delete removes filename. S3 does not report deleting a missing object, so it is checked for first.
+func (s *S3Storage) Delete(filename string) error {
+	if _, err := s.Stat(filename); err != nil {
+		return err
+	}
+	resp, err := s.do(http.MethodDelete, s.key(filename), nil, nil)
+	if err != nil {
+		return err
+	}
+	if err := checkResponse(resp, "delete", filename); err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (s *S3Storage) key(filename string) string {
+	if s.options.Prefix == "" {
+		return filename
+	}
+	return s.options.Prefix + "/" + filename
+}
+
+// do sends a signed request for the object key, or for the bucket if key is empty.
+func (s *S3Storage) do(method string, key string, query url.Values, body []byte) (*http.Response, error) {
+	target := *s.endpoint
+	target.Path = strings.TrimSuffix(target.Path, "/") + "/" + s.options.Bucket
+	if key != "" {
+		target.Path += "/" + key
+	}
+	target.RawPath = uriEncode(target.Path, false)
+	target.RawQuery = strings.ReplaceAll(query.Encode(), "+", "%20")
+
+	req, err := http.NewRequest(method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(body))
+	req.Header.Set("X-Amz-Content-Sha256", sha256Hex(body))
+	signV4(req, s.options.AccessKeyID, s.options.SecretAccessKey, s.options.Region, "s3", s.now())
+	return s.client.Do(req)
+}
+
+// s3Error is the error document S3 answers failed requests with.
+type s3Error struct {
+	Code    string
+	Message string
+}
+
+// checkResponse turns an unsuccessful response into an error and closes its body.
+// Missing objects are reported as fs.ErrNotExist.
+func checkResponse(resp *http.Response, op string, filename string) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return &fs.PathError{Op: op, Path: filename, Err: fs.ErrNotExist}
+	}
+
+	var s3Err s3Error
+	if body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10)); err == nil {
+		xml.Unmarshal(body, &s3Err)
+	}
+	if s3Err.Code == "" {
+		s3Err.Code = resp.Status
+	}
+	return fmt.Errorf("%s %s: S3 error %s: %s", op, filename, s3Err.Code, s3Err.Message)
+}
+
+// decodeResponse decodes the XML document of a successful response into result.
+// Some operations answer with status 200 and an error document, which is reported as an error too.
+func decodeResponse(resp *http.Response, op string, filename string, result interface{}) error {
+	if err := checkResponse(resp, op, filename); err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var s3Err s3Error
+	if xml.Unmarshal(body, &s3Err) == nil && s3Err.Code != "" {
+		return fmt.Errorf("%s %s: S3 error %s: %s", op, filename, s3Err.Code, s3Err.Message)
+	}
+	return xml.Unmarshal(body, result)
+}
+
+type completedPart struct {
+	PartNumber int
+	ETag       string
+}
+
+// s3Writer buffers a file and uploads it with a single PUT on Close. Once the content exceeds the part size
+// it switches to a multipart upload, which is completed on Close and aborted if any part failed.
+type s3Writer struct {
+	storage  *S3Storage
+	filename string
+	buffer   bytes.Buffer
+	uploadId string
+	parts    []completedPart
+	err      error
+	closed   bool
+}
+
+func (w *s3Writer) Write(data []byte) (int, error) {
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+	if w.err != nil {
+		return 0, w.err
+	}
+	w.buffer.Write(data)
+
+	partSize := w.storage.options.PartSize
+	for w.buffer.Len() > partSize {
+		if w.err = w.uploadPart(w.buffer.Next(partSize)); w.err != nil {
+			return 0, w.err
+		}
+	}
+	return len(data), nil
+}
+
+func (w *s3Writer) Close() error {
+	if w.closed {
+		return os.ErrClosed
+	}
+	w.closed = true
+
+	if w.uploadId == "" {
+		if w.err != nil {
+			return w.err
+		}
+		return w.put()
+	}
+
+	if w.err == nil {
+		w.err = w.uploadPart(w.buffer.Bytes())
+	}
+	if w.err == nil {
+		w.err = w.complete()
+	}
+	if w.err != nil {
+		w.abort()
+	}
+	return w.err
+}
+
+func (w *s3Writer) key() string {
+	return w.storage.key(w.filename)
+}
+
+func (w *s3Writer) put() error {
+	resp, err := w.storage.do(http.MethodPut, w.key(), nil, w.buffer.Bytes())
+	if err != nil {
+		return err
+	}
+	if err := checkResponse(resp, "write", w.filename); err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (w *s3Writer) uploadPart(part []byte) error {
+	if w.uploadId == "" {
+		resp, err := w.storage.do(http.MethodPost, w.key(), url.Values{"uploads": {""}}, nil)
+		if err != nil {
+			return err
+		}
+		var result struct {
+			UploadId string
+		}
+		if err := decodeResponse(resp, "write", w.filename, &result); err != nil {
+			return err
+		}
+		w.uploadId = result.UploadId
+	}
+
+	partNumber := len(w.parts) + 1
+	query := url.Values{"partNumber": {strconv.Itoa(partNumber)}, "uploadId": {w.uploadId}}
+	resp, err := w.storage.do(http.MethodPut, w.key(), query, part)
+	if err != nil {
+		return err
+	}
+	if err := checkResponse(resp, "write", w.filename); err != nil {
+		return err
+	}
+	resp.Body.Close()
+	w.parts = append(w.parts, completedPart{PartNumber: partNumber, ETag: resp.Header.Get("ETag")})
+	return nil
+}
+
+func (w *s3Writer) complete() error {
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+		Parts   []completedPart `xml:"Part"`
+	}{Parts: w.parts})
+	if err != nil {
+		return err
+	}
+
+	resp, err := w.storage.do(http.MethodPost, w.key(), url.Values{"uploadId": {w.uploadId}}, body)
+	if err != nil {
+		return err
+	}
+	var result struct {
+		Key string
+	}
+	return decodeResponse(resp, "write", w.filename, &result)
+}
+
+func (w *s3Writer) abort() {
+	resp, err := w.storage.do(http.MethodDelete, w.key(), url.Values{"uploadId": {w.uploadId}}, nil)
+	if err == nil {
+		resp.Body.Close()
+	}
+}
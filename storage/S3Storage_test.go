@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"io/fs"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignV4_MatchesAwsTestSuite(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	require.NoError(t, err)
+
+	signV4(req, fakeS3AccessKeyID, fakeS3SecretAccessKey, "us-east-1", "service", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	assert.Equal(t, "20150830T123600Z", req.Header.Get("X-Amz-Date"))
+	assert.Equal(t, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, "+
+		"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		req.Header.Get("Authorization"))
+}
+
+func TestNewS3Storage_ValidatesOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options S3Options
+	}{
+		{"Missing endpoint", S3Options{Bucket: "pages"}},
+		{"Endpoint without scheme", S3Options{Endpoint: "localhost:9000", Bucket: "pages"}},
+		{"Missing bucket", S3Options{Endpoint: "http://localhost:9000"}},
+		{"Part size below the S3 minimum", S3Options{Endpoint: "http://localhost:9000", Bucket: "pages", PartSize: MinS3PartSize - 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewS3Storage(tt.options)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestNewS3Storage_TakesCredentialsFromEnvironment(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "from-env")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret-from-env")
+
+	sut, err := NewS3Storage(S3Options{Endpoint: "http://localhost:9000", Bucket: "pages"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "from-env", sut.options.AccessKeyID)
+	assert.Equal(t, "secret-from-env", sut.options.SecretAccessKey)
+	assert.Equal(t, DefaultS3Region, sut.options.Region)
+	assert.Equal(t, DefaultS3PartSize, sut.options.PartSize)
+}
+
+func TestS3Storage_StoresObjectsBelowPrefix(t *testing.T) {
+	sut, fake := newFakeS3Storage(t, "/crawl/", 0)
+
+	writeFile(t, sut, "example.com/a%20b/_q^x=1/v1.html", "content")
+
+	assert.Contains(t, fake.objects, "crawl/example.com/a%20b/_q^x=1/v1.html")
+	names, err := sut.List("example.com/")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"example.com/a%20b/_q^x=1/v1.html"}, names)
+	content, err := sut.Read("example.com/a%20b/_q^x=1/v1.html")
+	require.NoError(t, err)
+	assert.Equal(t, "content", string(content))
+}
+
+func TestS3Storage_UploadsLargeFilesInParts(t *testing.T) {
+	sut, fake := newFakeS3Storage(t, "", MinS3PartSize)
+	body := strings.Repeat("0123456789", MinS3PartSize/5)
+
+	writer, err := sut.NewWriter("example.com/v1.html")
+	require.NoError(t, err)
+	for _, chunk := range []string{"<html>", body, "</html>"} {
+		_, err = writer.Write([]byte(chunk))
+		require.NoError(t, err)
+	}
+	_, err = sut.Stat("example.com/v1.html")
+	assert.ErrorIs(t, err, fs.ErrNotExist, "nothing is visible before Close")
+	require.NoError(t, writer.Close())
+
+	content, err := sut.Read("example.com/v1.html")
+	require.NoError(t, err)
+	assert.Equal(t, len("<html>"+body+"</html>"), len(content))
+	assert.True(t, string(content) == "<html>"+body+"</html>", "content matches")
+	assert.Empty(t, fake.uploads)
+
+	parts := 0
+	for _, request := range fake.requests {
+		if strings.HasPrefix(request, "PUT example.com/v1.html partNumber=") {
+			parts++
+		}
+	}
+	assert.Equal(t, 3, parts)
+}
+
+func TestS3Storage_AbortsFailedMultipartUpload(t *testing.T) {
+	sut, fake := newFakeS3Storage(t, "", MinS3PartSize)
+	writeFile(t, sut, "example.com/v1.html", "old")
+	fake.failPart = 2
+
+	writer, err := sut.NewWriter("example.com/v1.html")
+	require.NoError(t, err)
+	_, err = writer.Write(make([]byte, 2*MinS3PartSize+1))
+	assert.Error(t, err)
+	assert.Error(t, writer.Close())
+
+	content, err := sut.Read("example.com/v1.html")
+	require.NoError(t, err)
+	assert.Equal(t, "old", string(content))
+	assert.Empty(t, fake.uploads, "the upload is aborted")
+}
+
+func TestS3Storage_ReportsServiceErrors(t *testing.T) {
+	sut, _ := newFakeS3Storage(t, "", 0)
+	sut.options.SecretAccessKey = "wrong"
+
+	_, err := sut.Read("example.com/v1.html")
+
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, fs.ErrNotExist)
+	assert.Contains(t, err.Error(), "SignatureDoesNotMatch")
+}
@@ -0,0 +1,201 @@
+package urlutil
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const (
+	// maxComponentLength keeps encoded names well below the 255 bytes most filesystems allow.
+	maxComponentLength = 120
+	// maxPathLength bounds the whole directory of a URL, whose components are then replaced by one hash.
+	maxPathLength = 1024
+	// hashMarker is never produced by the escaping, so hashed names can't collide with escaped ones.
+	hashMarker = "~"
+)
+
+// ErrHashedFilePath is returned when decoding a path shortened by hashing; the original URL has to be
+// looked up in the index stored next to the versions.
+var ErrHashedFilePath = errors.New("file path was shortened by hashing and can't be decoded")
+
+// EncodeFilePath maps a URL to the directory its versions are stored in, e.g. "example.com/blog/^post/_qid=1"
+// for https://example.com/blog/Post?id=1. The mapping is reversible, see DecodeFilePath, and gives distinct
+// URLs distinct directories, even on case-insensitive filesystems:
+//   - the host is the first component, URLs with other schemes than https are below "_<scheme>/"
+//   - every path segment is a component, an empty one (e.g. a trailing slash) is "_"
+//   - the query, even an empty one, is a last component prefixed with "_q"
+//   - bytes other than lowercase letters, digits, '-', '.' and '_' are escaped as %XX, upper case
+//     letters as '^' followed by the letter in lower case; so is a trailing '.', a leading '_' and
+//     the 'v' of a leading "v<digit>", which keeps components apart from the files stored in a
+//     directory, like "v1.html", and from the "_" names above
+//   - components longer than maxComponentLength, and paths longer than maxPathLength, are shortened
+//     by hashing and can't be decoded anymore
+//
+// Fragments and user info are left out; URLs without a scheme are taken as https.
+func EncodeFilePath(rawUrl string) string {
+	parsedUrl, err := url.Parse(rawUrl)
+	if err == nil && parsedUrl.Scheme == "" && parsedUrl.Host == "" {
+		parsedUrl, err = url.Parse("https://" + rawUrl)
+	}
+	if err != nil || parsedUrl.Host == "" {
+		return hashMarker + hashOf(rawUrl)
+	}
+
+	components := make([]string, 0)
+	if scheme := strings.ToLower(parsedUrl.Scheme); scheme != "https" {
+		components = append(components, "_"+escapeComponent(scheme))
+	}
+	host := escapeComponent(parsedUrl.Host)
+	components = append(components, host)
+
+	if escapedPath := parsedUrl.EscapedPath(); escapedPath != "" {
+		for _, segment := range strings.Split(strings.TrimPrefix(escapedPath, "/"), "/") {
+			components = append(components, escapeComponent(segment))
+		}
+	}
+	if parsedUrl.RawQuery != "" || parsedUrl.ForceQuery {
+		components = append(components, "_q"+escape(parsedUrl.RawQuery))
+	}
+
+	for i, component := range components {
+		if len(component) > maxComponentLength {
+			components[i] = component[:maxComponentLength-len(hashMarker)-32] + hashMarker + hashOf(component)[:32]
+		}
+	}
+
+	filePath := strings.Join(components, "/")
+	if len(filePath) > maxPathLength {
+		return components[0] + "/" + hashMarker + hashOf(filePath)
+	}
+	return filePath
+}
+
+// DecodeFilePath returns the URL whose directory EncodeFilePath returned.
+func DecodeFilePath(filePath string) (string, error) {
+	components := strings.Split(filePath, "/")
+	for _, component := range components {
+		if strings.Contains(component, hashMarker) {
+			return "", ErrHashedFilePath
+		}
+	}
+
+	scheme := "https"
+	if strings.HasPrefix(components[0], "_") {
+		decoded, err := unescape(components[0][1:])
+		if err != nil {
+			return "", err
+		}
+		scheme = decoded
+		components = components[1:]
+	}
+	if len(components) == 0 || components[0] == "" {
+		return "", fmt.Errorf("invalid file path %q: no host", filePath)
+	}
+
+	host, err := unescape(components[0])
+	if err != nil {
+		return "", err
+	}
+	decodedUrl := scheme + "://" + host
+
+	segments := components[1:]
+	query := ""
+	hasQuery := false
+	if last := len(segments) - 1; last >= 0 && strings.HasPrefix(segments[last], "_q") {
+		if query, err = unescape(segments[last][2:]); err != nil {
+			return "", err
+		}
+		hasQuery = true
+		segments = segments[:last]
+	}
+
+	for _, segment := range segments {
+		if segment == "_" {
+			decodedUrl += "/"
+			continue
+		}
+		if segment == "" || strings.HasPrefix(segment, "_") {
+			return "", fmt.Errorf("invalid file path %q: invalid component %q", filePath, segment)
+		}
+		decoded, err := unescape(segment)
+		if err != nil {
+			return "", err
+		}
+		decodedUrl += "/" + decoded
+	}
+
+	if hasQuery {
+		decodedUrl += "?" + query
+	}
+	return decodedUrl, nil
+}
+
+func escapeComponent(component string) string {
+	if component == "" {
+		return "_"
+	}
+
+	escaped := escape(component)
+	if escaped[0] == '_' {
+		escaped = "%5F" + escaped[1:]
+	} else if len(escaped) > 1 && escaped[0] == 'v' && escaped[1] >= '0' && escaped[1] <= '9' {
+		escaped = "%76" + escaped[1:]
+	}
+	if strings.HasSuffix(escaped, ".") {
+		escaped = strings.TrimSuffix(escaped, ".") + "%2E"
+	}
+	return escaped
+}
+
+func escape(text string) string {
+	var escaped strings.Builder
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '-', c == '.', c == '_':
+			escaped.WriteByte(c)
+		case c >= 'A' && c <= 'Z':
+			escaped.WriteByte('^')
+			escaped.WriteByte(c - 'A' + 'a')
+		default:
+			fmt.Fprintf(&escaped, "%%%02X", c)
+		}
+	}
+	return escaped.String()
+}
+
+func unescape(escaped string) (string, error) {
+	var text strings.Builder
+	for i := 0; i < len(escaped); i++ {
+		switch escaped[i] {
+		case '^':
+			if i+1 >= len(escaped) || escaped[i+1] < 'a' || escaped[i+1] > 'z' {
+				return "", fmt.Errorf("invalid escape in %q", escaped)
+			}
+			text.WriteByte(escaped[i+1] - 'a' + 'A')
+			i++
+		case '%':
+			if i+2 >= len(escaped) {
+				return "", fmt.Errorf("invalid escape in %q", escaped)
+			}
+			decoded, err := hex.DecodeString(escaped[i+1 : i+3])
+			if err != nil {
+				return "", fmt.Errorf("invalid escape in %q", escaped)
+			}
+			text.Write(decoded)
+			i += 2
+		default:
+			text.WriteByte(escaped[i])
+		}
+	}
+	return text.String(), nil
+}
+
+func hashOf(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
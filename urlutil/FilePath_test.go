@@ -0,0 +1,123 @@
+package urlutil
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestEncodeFilePath(t *testing.T) {
+	tests := []struct {
+		name     string
+		link     string
+		expected string
+	}{
+		{"Host only", "https://www.google.com", "www.google.com"},
+		{"Path", "https://example.com/blog/post", "example.com/blog/post"},
+		{"Trailing slash", "https://example.com/blog/", "example.com/blog/_"},
+		{"Root", "https://example.com/", "example.com/_"},
+		{"Other scheme", "http://example.com/page", "_http/example.com/page"},
+		{"Port", "https://example.com:8443/page", "example.com%3A8443/page"},
+		{"Query", "https://example.com/search?q=Go&page=2", "example.com/search/_qq%3D^go%26page%3D2"},
+		{"Empty query", "https://example.com/search?", "example.com/search/_q"},
+		{"Upper case", "https://example.com/About", "example.com/^about"},
+		{"Unicode", "https://example.com/café", "example.com/caf%25^c3%25^a9"},
+		{"Dot segments", "https://example.com/a/../../etc", "example.com/a/.%2E/.%2E/etc"},
+		{"Version file name", "https://example.com/v1.html", "example.com/%761.html"},
+		{"Leading underscore", "https://example.com/_q", "example.com/%5Fq"},
+		{"Reserved characters", "https://example.com/a:b*c", "example.com/a%3Ab%2Ac"},
+		{"No scheme", "example.com/page", "example.com/page"},
+		{"Fragment", "https://example.com/page#top", "example.com/page"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			result := EncodeFilePath(tc.link)
+			if result != tc.expected {
+				t.Errorf("EncodeFilePath(%q) = %v; want %v", tc.link, result, tc.expected)
+			}
+		})
+	}
+}
+
+func TestDecodeFilePath_RoundTrip(t *testing.T) {
+	links := []string{
+		"https://www.google.com",
+		"https://example.com/",
+		"https://example.com/blog/",
+		"https://example.com/blog//post",
+		"http://example.com/page",
+		"https://example.com:8443/page",
+		"https://example.com/search?q=Go&page=2",
+		"https://example.com/search?",
+		"https://example.com/About/Us",
+		"https://example.com/caf%C3%A9",
+		"https://example.com/a%2Fb",
+		"https://example.com/a/../etc",
+		"https://example.com/v1.html",
+		"https://example.com/_q",
+		"https://example.com/~user",
+	}
+
+	for _, link := range links {
+		t.Run(link, func(t *testing.T) {
+			decoded, err := DecodeFilePath(EncodeFilePath(link))
+			if err != nil {
+				t.Fatalf("DecodeFilePath(EncodeFilePath(%q)) failed: %v", link, err)
+			}
+			if decoded != link {
+				t.Errorf("DecodeFilePath(EncodeFilePath(%q)) = %v", link, decoded)
+			}
+		})
+	}
+}
+
+func TestEncodeFilePath_IsCollisionFreeOnCaseInsensitiveFilesystems(t *testing.T) {
+	links := []string{
+		"https://example.com/a",
+		"https://example.com/a/",
+		"https://example.com/A",
+		"https://example.com/a?",
+		"https://example.com/a?x=1",
+		"https://example.com/a/_qx%3D1",
+		"http://example.com/a",
+		"https://example.com/a/v1.html",
+		"https://example.com/a/V1.html",
+		"https://example.com/a%2Fb",
+		"https://example.com/a/b",
+	}
+
+	seen := make(map[string]string)
+	for _, link := range links {
+		filePath := strings.ToLower(EncodeFilePath(link))
+		if other, exists := seen[filePath]; exists {
+			t.Errorf("%q and %q both map to %q", link, other, filePath)
+		}
+		seen[filePath] = link
+	}
+}
+
+func TestEncodeFilePath_HashesLongNames(t *testing.T) {
+	longSegment := "https://example.com/" + strings.Repeat("a", 300)
+	otherLongSegment := "https://example.com/" + strings.Repeat("a", 299) + "b"
+	longPath := "https://example.com" + strings.Repeat("/segment", 200)
+
+	for _, link := range []string{longSegment, otherLongSegment, longPath} {
+		filePath := EncodeFilePath(link)
+		for _, component := range strings.Split(filePath, "/") {
+			if len(component) > maxComponentLength {
+				t.Errorf("EncodeFilePath(%q) has a component of %d bytes", link, len(component))
+			}
+		}
+		if len(filePath) > maxPathLength {
+			t.Errorf("EncodeFilePath(%q) is %d bytes long", link, len(filePath))
+		}
+		if _, err := DecodeFilePath(filePath); !errors.Is(err, ErrHashedFilePath) {
+			t.Errorf("DecodeFilePath(%q) = %v; want ErrHashedFilePath", filePath, err)
+		}
+	}
+
+	if EncodeFilePath(longSegment) == EncodeFilePath(otherLongSegment) {
+		t.Errorf("long segments differing in the last byte collide")
+	}
+}
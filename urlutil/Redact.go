@@ -0,0 +1,32 @@
+package urlutil
+
+import "net/url"
+
+const redacted = "REDACTED"
+
+// RedactURL hides the credentials a URL may carry, so it can be logged or stored: the password of the
+// user info and every query value, and with redactPath the whole path, like the token in the path of
+// a Slack or Mattermost incoming webhook.
+func RedactURL(rawUrl string, redactPath bool) string {
+	parsedUrl, err := url.Parse(rawUrl)
+	if err != nil {
+		return redacted
+	}
+
+	if _, hasPassword := parsedUrl.User.Password(); hasPassword {
+		parsedUrl.User = url.UserPassword(parsedUrl.User.Username(), redacted)
+	}
+	if parsedUrl.RawQuery != "" {
+		query := parsedUrl.Query()
+		for key := range query {
+			query[key] = []string{redacted}
+		}
+		parsedUrl.RawQuery = query.Encode()
+	}
+	if redactPath && parsedUrl.Path != "" && parsedUrl.Path != "/" {
+		parsedUrl.Path = "/" + redacted
+		parsedUrl.RawPath = ""
+	}
+	parsedUrl.Fragment = ""
+	return parsedUrl.String()
+}
@@ -1,4 +1,5 @@
-package main
+// Package urlutil normalizes URLs and links found while crawling.
+package urlutil
 
 import (
 	"fmt"
@@ -0,0 +1,122 @@
+package warc
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"goCrawler/crawler"
+	"goCrawler/fetch"
+)
+
+// ImportStats counts the records of an import; Skipped are responses that aren't HTML pages.
+type ImportStats struct {
+	Files   int
+	Records int
+	Pages   int
+	Skipped int
+}
+
+// Import replays the HTML responses of WARC files, in the given order, as fetched pages to handler,
+// e.g. a DifferenceTracker to build the version history of pages archived elsewhere. The pages keep the
+// time they were archived. It stops at the first error of handler.
+func Import(paths []string, handler crawler.IPageHandler) (ImportStats, error) {
+	var stats ImportStats
+	for _, path := range paths {
+		if err := importFile(path, handler, &stats); err != nil {
+			return stats, fmt.Errorf("importing %s: %w", path, err)
+		}
+		stats.Files++
+	}
+	return stats, nil
+}
+
+func importFile(path string, handler crawler.IPageHandler, stats *ImportStats) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader, err := NewReader(file)
+	if err != nil {
+		return err
+	}
+	for {
+		record, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		stats.Records++
+
+		if record.Type() != RecordTypeResponse || !strings.HasPrefix(record.Header.Get("Content-Type"), "application/http") {
+			continue
+		}
+		page, err := pageFromResponse(record)
+		if err != nil || page == nil {
+			stats.Skipped++
+			continue
+		}
+		if err := handler.HandlePage(page); err != nil {
+			return err
+		}
+		stats.Pages++
+	}
+}
+
+// pageFromResponse parses the HTTP response in record; it returns nil for responses that aren't HTML.
+func pageFromResponse(record *Record) (*fetch.FetchResult, error) {
+	response, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(record.Block)), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	contentType := response.Header.Get("Content-Type")
+	if mediaType, _, err := mime.ParseMediaType(contentType); contentType != "" && (err != nil || (mediaType != "text/html" && mediaType != "application/xhtml+xml")) {
+		return nil, nil
+	}
+
+	var body io.Reader = response.Body
+	switch strings.ToLower(response.Header.Get("Content-Encoding")) {
+	case "", "identity":
+	case "gzip", "x-gzip":
+		gzipReader, err := gzip.NewReader(response.Body)
+		if err != nil {
+			return nil, err
+		}
+		body = gzipReader
+	default:
+		return nil, fmt.Errorf("unsupported Content-Encoding %q", response.Header.Get("Content-Encoding"))
+	}
+	html, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchedAt, err := time.Parse(time.RFC3339, record.Header.Get("WARC-Date"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid WARC-Date %q", record.Header.Get("WARC-Date"))
+	}
+
+	return &fetch.FetchResult{
+		URL:           strings.Trim(record.Header.Get("WARC-Target-URI"), "<>"),
+		HTML:          string(html),
+		StatusCode:    response.StatusCode,
+		ContentType:   contentType,
+		ContentLength: int64(len(html)),
+		FetchedAt:     fetchedAt,
+		Protocol:      response.Proto,
+		Header:        response.Header,
+	}, nil
+}
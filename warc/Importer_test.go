@@ -0,0 +1,80 @@
+package warc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"goCrawler/fetch"
+)
+
+type recordingHandler struct {
+	pages []*fetch.FetchResult
+}
+
+func (r *recordingHandler) HandlePage(page *fetch.FetchResult) error {
+	r.pages = append(r.pages, page)
+	return nil
+}
+
+func TestImport_ReplaysWrittenPages(t *testing.T) {
+	directory := t.TempDir()
+	writer, err := NewWarcWriter(directory, "test", 0)
+	require.NoError(t, err)
+	require.NoError(t, writer.HandlePage(examplePage("https://example.com/", "<html>v1</html>")))
+	notFound := examplePage("https://example.com/missing", "not found")
+	notFound.StatusCode = http.StatusNotFound
+	require.NoError(t, writer.HandlePage(notFound))
+	image := examplePage("https://example.com/logo.png", "PNG")
+	image.Header = http.Header{"Content-Type": {"image/png"}}
+	require.NoError(t, writer.HandlePage(image))
+	require.NoError(t, writer.Close())
+
+	files, err := filepath.Glob(filepath.Join(directory, "*.warc.gz"))
+	require.NoError(t, err)
+	handler := &recordingHandler{}
+	stats, err := Import(files, handler)
+	require.NoError(t, err)
+
+	assert.Equal(t, ImportStats{Files: 1, Records: 7, Pages: 2, Skipped: 1}, stats)
+	require.Len(t, handler.pages, 2)
+	assert.Equal(t, "https://example.com/", handler.pages[0].URL)
+	assert.Equal(t, "<html>v1</html>", handler.pages[0].HTML)
+	assert.Equal(t, http.StatusOK, handler.pages[0].StatusCode)
+	assert.Equal(t, fetchedAt, handler.pages[0].FetchedAt)
+	assert.Equal(t, http.StatusNotFound, handler.pages[1].StatusCode)
+}
+
+func TestImport_DecodesCompressedPayloads(t *testing.T) {
+	var payload bytes.Buffer
+	gzipWriter := gzip.NewWriter(&payload)
+	gzipWriter.Write([]byte("<html>compressed</html>"))
+	require.NoError(t, gzipWriter.Close())
+
+	block := append([]byte("HTTP/1.1 200 OK\r\nContent-Type: text/html\r\nContent-Encoding: gzip\r\nContent-Length: "+
+		strconv.Itoa(payload.Len())+"\r\n\r\n"), payload.Bytes()...)
+	record := newRecord(RecordTypeResponse, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), "application/http; msgtype=response", block)
+	record.Header.Set("WARC-Target-URI", "<https://example.org/>")
+
+	path := filepath.Join(t.TempDir(), "other.warc.gz")
+	var file bytes.Buffer
+	require.NoError(t, record.writeTo(&file))
+	require.NoError(t, os.WriteFile(path, file.Bytes(), 0o644))
+
+	handler := &recordingHandler{}
+	_, err := Import([]string{path}, handler)
+	require.NoError(t, err)
+
+	require.Len(t, handler.pages, 1)
+	assert.Equal(t, "https://example.org/", handler.pages[0].URL)
+	assert.Equal(t, "<html>compressed</html>", handler.pages[0].HTML)
+	assert.Equal(t, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), handler.pages[0].FetchedAt)
+}
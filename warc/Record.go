@@ -0,0 +1,166 @@
+// Package warc writes crawled pages as WARC 1.1 files and replays existing WARC files.
+package warc
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	warcVersion = "WARC/1.1"
+
+	RecordTypeWarcinfo = "warcinfo"
+	RecordTypeRequest  = "request"
+	RecordTypeResponse = "response"
+)
+
+// Record is a single WARC record. Header keys are canonicalized like textproto.MIMEHeader,
+// so look them up with Header.Get("WARC-Type").
+type Record struct {
+	Header textproto.MIMEHeader
+	Block  []byte
+}
+
+func (r *Record) Type() string {
+	return r.Header.Get("WARC-Type")
+}
+
+// newRecord creates a record with the headers every record needs.
+func newRecord(recordType string, date time.Time, contentType string, block []byte) *Record {
+	header := textproto.MIMEHeader{}
+	header.Set("WARC-Type", recordType)
+	header.Set("WARC-Record-ID", newRecordID())
+	header.Set("WARC-Date", date.UTC().Format(time.RFC3339))
+	header.Set("WARC-Block-Digest", digest(block))
+	header.Set("Content-Type", contentType)
+	header.Set("Content-Length", strconv.Itoa(len(block)))
+	return &Record{Header: header, Block: block}
+}
+
+// headerOrder lists the headers written first, in this order; the others follow sorted.
+var headerOrder = []string{"WARC-Type", "WARC-Record-ID", "WARC-Date", "WARC-Target-URI", "WARC-Filename", "WARC-Concurrent-To"}
+
+// writeTo writes the record as its own gzip member, so readers can seek to any record.
+func (r *Record) writeTo(out io.Writer) error {
+	var record bytes.Buffer
+	record.WriteString(warcVersion + "\r\n")
+	written := make(map[string]bool)
+	for _, key := range headerOrder {
+		if value := r.Header.Get(key); value != "" {
+			fmt.Fprintf(&record, "%s: %s\r\n", key, value)
+			written[textproto.CanonicalMIMEHeaderKey(key)] = true
+		}
+	}
+	for _, key := range sortedKeys(r.Header) {
+		if !written[key] {
+			fmt.Fprintf(&record, "%s: %s\r\n", warcHeaderName(key), r.Header.Get(key))
+		}
+	}
+	record.WriteString("\r\n")
+	record.Write(r.Block)
+	record.WriteString("\r\n\r\n")
+
+	gzipWriter := gzip.NewWriter(out)
+	if _, err := gzipWriter.Write(record.Bytes()); err != nil {
+		return err
+	}
+	return gzipWriter.Close()
+}
+
+// warcHeaderName undoes the canonicalization of "Warc-" prefixes, which WARC spells "WARC-".
+func warcHeaderName(key string) string {
+	if strings.HasPrefix(key, "Warc-") {
+		return "WARC-" + strings.TrimPrefix(key, "Warc-")
+	}
+	return key
+}
+
+// Reader reads the records of a WARC file, compressed with gzip or not.
+type Reader struct {
+	reader *bufio.Reader
+}
+
+func NewReader(in io.Reader) (*Reader, error) {
+	buffered := bufio.NewReader(in)
+	magic, err := buffered.Peek(2)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gzipReader, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, err
+		}
+		return &Reader{reader: bufio.NewReader(gzipReader)}, nil
+	}
+	return &Reader{reader: buffered}, nil
+}
+
+// Next returns the next record, or io.EOF after the last one.
+func (r *Reader) Next() (*Record, error) {
+	version, err := r.reader.ReadString('\n')
+	for err == nil && strings.TrimSpace(version) == "" {
+		version, err = r.reader.ReadString('\n')
+	}
+	if err != nil {
+		if errors.Is(err, io.EOF) && strings.TrimSpace(version) == "" {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	if !strings.HasPrefix(version, "WARC/") {
+		return nil, fmt.Errorf("invalid WARC record: expected version line, got %q", strings.TrimSpace(version))
+	}
+
+	header, err := textproto.NewReader(r.reader).ReadMIMEHeader()
+	if err != nil {
+		return nil, fmt.Errorf("invalid WARC record header: %w", err)
+	}
+	length, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid WARC record: Content-Length %q", header.Get("Content-Length"))
+	}
+
+	block := make([]byte, length)
+	if _, err := io.ReadFull(r.reader, block); err != nil {
+		return nil, fmt.Errorf("truncated WARC record: %w", err)
+	}
+	return &Record{Header: header, Block: block}, nil
+}
+
+func newRecordID() string {
+	var uuid [16]byte
+	if _, err := rand.Read(uuid[:]); err != nil {
+		panic("reading random bytes failed: " + err.Error())
+	}
+	uuid[6] = uuid[6]&0x0f | 0x40
+	uuid[8] = uuid[8]&0x3f | 0x80
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:])
+}
+
+// digest is the SHA-1 digest in the base32 form WARC tools expect.
+func digest(data []byte) string {
+	sum := sha1.Sum(data)
+	return "sha1:" + base32.StdEncoding.EncodeToString(sum[:])
+}
+
+func sortedKeys(header map[string][]string) []string {
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,197 @@
+package warc
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"goCrawler/fetch"
+)
+
+// DefaultMaxFileSize is the size after which a new WARC file is started, the usual 1 GB.
+const DefaultMaxFileSize = 1 << 30
+
+// WarcWriter is a content handler archiving every fetched page as a request and a response record.
+// Records are appended to <directory>/<prefix>-<timestamp>-<serial>.warc.gz, each file starting with a
+// warcinfo record; once a file reaches maxFileSize the next page starts a new one. Files being written
+// end in ".open" and are renamed when they are finished, so complete files can be picked up right away.
+// It is safe for concurrent use.
+type WarcWriter struct {
+	directory   string
+	prefix      string
+	maxFileSize int64
+	now         func() time.Time
+
+	mu       sync.Mutex
+	file     *os.File
+	filename string
+	size     int64
+	serial   int
+}
+
+func NewWarcWriter(directory string, prefix string, maxFileSize int64) (*WarcWriter, error) {
+	if err := os.MkdirAll(directory, os.ModePerm); err != nil {
+		return nil, err
+	}
+	if prefix == "" {
+		prefix = "goCrawler"
+	}
+	if maxFileSize <= 0 {
+		maxFileSize = DefaultMaxFileSize
+	}
+
+	return &WarcWriter{directory: directory, prefix: prefix, maxFileSize: maxFileSize, now: time.Now}, nil
+}
+
+func (w *WarcWriter) HandleContent(url string, html string) error {
+	return w.HandlePage(&fetch.FetchResult{URL: url, HTML: html})
+}
+
+// HandlePage archives page; pages that couldn't be downloaded at all are skipped, there is no response to keep.
+func (w *WarcWriter) HandlePage(page *fetch.FetchResult) error {
+	if page.Err != nil {
+		return nil
+	}
+
+	fetchedAt := page.FetchedAt
+	if fetchedAt.IsZero() {
+		fetchedAt = w.now()
+	}
+
+	requestBlock, err := requestBlock(page)
+	if err != nil {
+		return err
+	}
+	response := newRecord(RecordTypeResponse, fetchedAt, "application/http;msgtype=response", responseBlock(page))
+	response.Header.Set("WARC-Target-URI", page.URL)
+	response.Header.Set("WARC-Payload-Digest", digest([]byte(page.HTML)))
+	request := newRecord(RecordTypeRequest, fetchedAt, "application/http;msgtype=request", requestBlock)
+	request.Header.Set("WARC-Target-URI", page.URL)
+	request.Header.Set("WARC-Concurrent-To", response.Header.Get("WARC-Record-ID"))
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		if err := w.startFile(); err != nil {
+			return err
+		}
+	}
+	for _, record := range []*Record{request, response} {
+		if err := w.write(record); err != nil {
+			return err
+		}
+	}
+	if w.size >= w.maxFileSize {
+		return w.finishFile()
+	}
+	return nil
+}
+
+// Close finishes the current file.
+func (w *WarcWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+	return w.finishFile()
+}
+
+func (w *WarcWriter) startFile() error {
+	now := w.now()
+	w.filename = fmt.Sprintf("%s-%s-%05d.warc.gz", w.prefix, now.UTC().Format("20060102150405"), w.serial)
+	w.serial++
+
+	file, err := os.Create(filepath.Join(w.directory, w.filename+".open"))
+	if err != nil {
+		return err
+	}
+	w.file = file
+	w.size = 0
+
+	info := newRecord(RecordTypeWarcinfo, now, "application/warc-fields",
+		[]byte("software: goCrawler\r\nformat: WARC File Format 1.1\r\n"))
+	info.Header.Set("WARC-Filename", w.filename)
+	return w.write(info)
+}
+
+func (w *WarcWriter) write(record *Record) error {
+	var buffer bytes.Buffer
+	if err := record.writeTo(&buffer); err != nil {
+		return err
+	}
+	n, err := w.file.Write(buffer.Bytes())
+	w.size += int64(n)
+	return err
+}
+
+func (w *WarcWriter) finishFile() error {
+	file := w.file
+	w.file = nil
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), filepath.Join(w.directory, w.filename))
+}
+
+// requestBlock reconstructs the GET request of page; only the headers the fetcher set are known.
+func requestBlock(page *fetch.FetchResult) ([]byte, error) {
+	target, err := url.Parse(page.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	var block bytes.Buffer
+	fmt.Fprintf(&block, "GET %s %s\r\nHost: %s\r\n", target.RequestURI(), httpVersion(page), target.Host)
+	if err := page.RequestHeader.Write(&block); err != nil {
+		return nil, err
+	}
+	block.WriteString("\r\n")
+	return block.Bytes(), nil
+}
+
+// responseBlock reconstructs the HTTP response of page. The body is stored as received by the crawler,
+// so encodings removed while reading it are dropped from the headers and Content-Length is its size.
+func responseBlock(page *fetch.FetchResult) []byte {
+	statusCode := page.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+
+	header := page.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	if header.Get("Content-Type") == "" && page.ContentType != "" {
+		header.Set("Content-Type", page.ContentType)
+	}
+	header.Set("Content-Length", fmt.Sprint(len(page.HTML)))
+
+	var block bytes.Buffer
+	fmt.Fprintf(&block, "%s %d %s\r\n", httpVersion(page), statusCode, http.StatusText(statusCode))
+	header.WriteSubset(&block, map[string]bool{"Transfer-Encoding": true, "Content-Encoding": true})
+	block.WriteString("\r\n")
+	block.WriteString(page.HTML)
+	return block.Bytes()
+}
+
+// httpVersion is the protocol the page was fetched with; HTTP/2 exchanges are written in HTTP/1.1 form.
+func httpVersion(page *fetch.FetchResult) string {
+	if strings.HasPrefix(page.Protocol, "HTTP/1.") {
+		return page.Protocol
+	}
+	return "HTTP/1.1"
+}
@@ -0,0 +1,112 @@
+package warc
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"goCrawler/fetch"
+)
+
+var fetchedAt = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func examplePage(url string, html string) *fetch.FetchResult {
+	return &fetch.FetchResult{
+		URL:           url,
+		HTML:          html,
+		StatusCode:    http.StatusOK,
+		ContentType:   "text/html; charset=utf-8",
+		FetchedAt:     fetchedAt,
+		Protocol:      "HTTP/1.1",
+		RequestHeader: http.Header{"User-Agent": {"goCrawler"}},
+		Header:        http.Header{"Content-Type": {"text/html; charset=utf-8"}, "Content-Encoding": {"gzip"}, "Etag": {`"abc"`}},
+	}
+}
+
+func readRecords(t *testing.T, path string) []*Record {
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	reader, err := NewReader(file)
+	require.NoError(t, err)
+	records := make([]*Record, 0)
+	for {
+		record, err := reader.Next()
+		if err != nil {
+			assert.Equal(t, "EOF", err.Error())
+			return records
+		}
+		records = append(records, record)
+	}
+}
+
+func TestWarcWriter_WritesRequestAndResponseRecords(t *testing.T) {
+	directory := t.TempDir()
+	sut, err := NewWarcWriter(directory, "test", 0)
+	require.NoError(t, err)
+	sut.now = func() time.Time { return fetchedAt }
+
+	require.NoError(t, sut.HandlePage(examplePage("https://example.com/page?id=1", "<html>page</html>")))
+	require.NoError(t, sut.HandlePage(&fetch.FetchResult{URL: "https://example.com/broken", Err: os.ErrDeadlineExceeded}))
+	require.NoError(t, sut.Close())
+
+	files, err := filepath.Glob(filepath.Join(directory, "*"))
+	require.NoError(t, err)
+	require.Equal(t, []string{filepath.Join(directory, "test-20240301120000-00000.warc.gz")}, files)
+
+	records := readRecords(t, files[0])
+	require.Len(t, records, 3)
+	assert.Equal(t, RecordTypeWarcinfo, records[0].Type())
+	assert.Equal(t, "test-20240301120000-00000.warc.gz", records[0].Header.Get("WARC-Filename"))
+
+	request, response := records[1], records[2]
+	assert.Equal(t, RecordTypeRequest, request.Type())
+	assert.Equal(t, "https://example.com/page?id=1", request.Header.Get("WARC-Target-URI"))
+	assert.Equal(t, response.Header.Get("WARC-Record-ID"), request.Header.Get("WARC-Concurrent-To"))
+	assert.Equal(t, "GET /page?id=1 HTTP/1.1\r\nHost: example.com\r\nUser-Agent: goCrawler\r\n\r\n", string(request.Block))
+
+	assert.Equal(t, RecordTypeResponse, response.Type())
+	assert.Equal(t, "2024-03-01T12:00:00Z", response.Header.Get("WARC-Date"))
+	assert.Equal(t, "application/http;msgtype=response", response.Header.Get("Content-Type"))
+	assert.Equal(t, digest([]byte("<html>page</html>")), response.Header.Get("WARC-Payload-Digest"))
+	assert.Equal(t, digest(response.Block), response.Header.Get("WARC-Block-Digest"))
+	assert.Equal(t, "HTTP/1.1 200 OK\r\nContent-Length: 17\r\nContent-Type: text/html; charset=utf-8\r\nEtag: \"abc\"\r\n\r\n<html>page</html>", string(response.Block))
+}
+
+func TestWarcWriter_RotatesFiles(t *testing.T) {
+	directory := t.TempDir()
+	sut, err := NewWarcWriter(directory, "test", 1)
+	require.NoError(t, err)
+
+	require.NoError(t, sut.HandlePage(examplePage("https://example.com/1", "<html>1</html>")))
+	require.NoError(t, sut.HandlePage(examplePage("https://example.com/2", "<html>2</html>")))
+	require.NoError(t, sut.Close())
+
+	files, err := filepath.Glob(filepath.Join(directory, "*.warc.gz"))
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	for _, file := range files {
+		assert.Len(t, readRecords(t, file), 3)
+	}
+}
+
+func TestWarcWriter_KeepsUnfinishedFileOpen(t *testing.T) {
+	directory := t.TempDir()
+	sut, err := NewWarcWriter(directory, "test", 0)
+	require.NoError(t, err)
+
+	require.NoError(t, sut.HandleContent("https://example.com", "<html></html>"))
+
+	files, err := filepath.Glob(filepath.Join(directory, "*"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.True(t, strings.HasSuffix(files[0], ".warc.gz.open"))
+	require.NoError(t, sut.Close())
+}